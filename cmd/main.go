@@ -4,20 +4,26 @@ import (
 	"fmt"
 	"github.com/hpcsc/aws-profile/internal/config"
 	"os"
-	"runtime"
-	"strings"
 
 	"github.com/hpcsc/aws-profile/internal/aws"
+	"github.com/hpcsc/aws-profile/internal/cache"
 	"github.com/hpcsc/aws-profile/internal/handlers"
+	"github.com/hpcsc/aws-profile/internal/history"
 	"github.com/hpcsc/aws-profile/internal/io"
 	"github.com/hpcsc/aws-profile/internal/log"
+	"github.com/hpcsc/aws-profile/internal/mfa"
+	"github.com/hpcsc/aws-profile/internal/process"
+	"github.com/hpcsc/aws-profile/internal/server"
+	"github.com/hpcsc/aws-profile/internal/shell"
 	"github.com/hpcsc/aws-profile/internal/tui"
 	"github.com/hpcsc/aws-profile/internal/utils"
 	"gopkg.in/alecthomas/kingpin.v2"
 )
 
 func createHandlerMap(app *kingpin.Application, logger log.Logger, config *config.Config) map[string]handlers.Handler {
-	isWindows := runtime.GOOS == "windows"
+	credentialsCache := cache.NewDefaultStore()
+	profileHistory := history.NewDefaultStore()
+	selectProfile := tui.NewProfileSelector(profileHistory.Recent)
 
 	getHandler := handlers.NewGetHandler(
 		app,
@@ -26,29 +32,53 @@ func createHandlerMap(app *kingpin.Application, logger log.Logger, config *confi
 		io.ReadCachedCallerIdentity,
 		io.WriteCachedCallerIdentity,
 	)
-	setHandler := handlers.NewSetHandler(app, config, tui.SelectProfileFromList, io.WriteToFile)
-	setRegionHandler := handlers.NewSetRegionHandler(app, config, tui.SelectValueFromList, io.WriteToFile)
+	setHandler := handlers.NewSetHandler(app, config, selectProfile, tui.IsInteractive, io.WriteToFile, io.LockFile, profileHistory.Record, aws.GetSessionToken)
+	setRegionHandler := handlers.NewSetRegionHandler(app, config, tui.SelectValueFromList, io.WriteToFile, io.LockFile)
 	getRegionHandler := handlers.NewGetRegionHandler(app)
-	exportHandler := handlers.NewExportHandler(
+	credentialsGetter := handlers.CredentialsGetter{
+		GetAWSCredentials:      aws.GetAWSCredentials,
+		ReadCachedCredentials:  credentialsCache.Read,
+		WriteCachedCredentials: credentialsCache.Write,
+		GetSessionToken:        aws.GetSessionToken,
+		WriteToFile:            io.WriteToFile,
+		LockFile:               io.LockFile,
+	}
+	exportHandler := handlers.NewExportHandler(app, config, shell.Detect, selectProfile, credentialsGetter, profileHistory.Record)
+	execHandler := handlers.NewExecHandler(app, config, selectProfile, credentialsGetter, process.Run, os.Environ)
+	serveHandler := handlers.NewServeHandler(
 		app,
 		config,
-		isWindows,
-		tui.SelectProfileFromList,
-		aws.GetAWSCredentials,
+		shell.Detect,
+		selectProfile,
+		credentialsGetter,
+		server.GenerateToken,
+		server.Serve,
+		os.Stdout,
 	)
-	unsetHandler := handlers.NewUnsetHandler(app, isWindows)
+	credentialProcessHandler := handlers.NewCredentialProcessHandler(app, config, credentialsGetter)
+	unsetHandler := handlers.NewUnsetHandler(app, shell.Detect)
+	ssoLoginHandler := handlers.NewSSOLoginHandler(app, config, selectProfile, aws.SSOLogin)
+	cacheListHandler, cacheClearHandler := handlers.NewCacheHandlers(app, credentialsCache.List, credentialsCache.Clear)
+	mfaSetTOTPSecretHandler := handlers.NewMFASetTOTPSecretHandler(app, mfa.NewDefaultSecretStore().Write, os.Stdin)
 	upgradeHandler := handlers.NewUpgradeHandler(app, logger)
 	versionHandler := handlers.NewVersionHandler(app)
 
 	return map[string]handlers.Handler{
-		getHandler.SubCommand.FullCommand():       getHandler,
-		setHandler.SubCommand.FullCommand():       setHandler,
-		unsetHandler.SubCommand.FullCommand():     unsetHandler,
-		exportHandler.SubCommand.FullCommand():    exportHandler,
-		setRegionHandler.SubCommand.FullCommand(): setRegionHandler,
-		getRegionHandler.SubCommand.FullCommand(): getRegionHandler,
-		upgradeHandler.SubCommand.FullCommand():   upgradeHandler,
-		versionHandler.SubCommand.FullCommand():   versionHandler,
+		getHandler.SubCommand.FullCommand():               getHandler,
+		setHandler.SubCommand.FullCommand():               setHandler,
+		unsetHandler.SubCommand.FullCommand():             unsetHandler,
+		exportHandler.SubCommand.FullCommand():            exportHandler,
+		execHandler.SubCommand.FullCommand():              execHandler,
+		serveHandler.SubCommand.FullCommand():             serveHandler,
+		credentialProcessHandler.SubCommand.FullCommand(): credentialProcessHandler,
+		setRegionHandler.SubCommand.FullCommand():         setRegionHandler,
+		getRegionHandler.SubCommand.FullCommand():         getRegionHandler,
+		ssoLoginHandler.SubCommand.FullCommand():          ssoLoginHandler,
+		cacheListHandler.SubCommand.FullCommand():         cacheListHandler,
+		cacheClearHandler.SubCommand.FullCommand():        cacheClearHandler,
+		mfaSetTOTPSecretHandler.SubCommand.FullCommand():  mfaSetTOTPSecretHandler,
+		upgradeHandler.SubCommand.FullCommand():           upgradeHandler,
+		versionHandler.SubCommand.FullCommand():           versionHandler,
 	}
 }
 
@@ -57,8 +87,8 @@ func main() {
 
 	config, err := config.Load()
 	if err != nil {
-		fmt.Println(err.Error())
-		os.Exit(1)
+		_, _ = fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(handlers.ExitCodeConfigInvalid)
 	}
 
 	app := kingpin.New("aws-profile", "simple tool to help switching among AWS profiles more easily")
@@ -67,10 +97,14 @@ func main() {
 
 	if len(os.Args) < 2 {
 		app.Usage([]string{})
-		os.Exit(1)
+		os.Exit(handlers.ExitCodeUsage)
 	}
 
-	parsedInput := kingpin.MustParse(app.Parse(os.Args[1:]))
+	parsedInput, err := app.Parse(os.Args[1:])
+	if err != nil {
+		app.Errorf("%s, try --help", err)
+		os.Exit(handlers.ExitCodeUsage)
+	}
 
 	if handler, ok := handlerMap[parsedInput]; ok {
 		globalArguments := handlers.GlobalArguments{
@@ -78,17 +112,23 @@ func main() {
 			ConfigFilePath:      utils.GetEnvVariableOrDefault("AWS_CONFIG_FILE", "~/.aws/config"),
 		}
 
-		success, message := handler.Handle(globalArguments)
-		if !strings.EqualFold(message, "") {
-			fmt.Println(message)
+		result, err := handler.Handle(globalArguments)
+		if err != nil {
+			// output of commands like export is evaluated by shells, errors must not go to stdout
+			if handlers.CategoryOf(err) != handlers.CategoryCancelled {
+				_, _ = fmt.Fprintln(os.Stderr, err.Error())
+			}
+
+			os.Exit(handlers.ExitCode(err))
 		}
 
-		if success {
-			os.Exit(0)
-		} else {
-			os.Exit(1)
+		if result.Output != "" {
+			fmt.Println(result.Output)
 		}
+
+		os.Exit(result.ExitCode)
 	} else {
 		app.Usage([]string{})
+		os.Exit(handlers.ExitCodeUsage)
 	}
 }
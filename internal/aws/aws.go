@@ -6,28 +6,146 @@ import (
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
 	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sso"
 	"github.com/aws/aws-sdk-go/service/sts"
 	"github.com/hpcsc/aws-profile/internal/awsconfig"
+	"github.com/hpcsc/aws-profile/internal/mfa"
+	"github.com/hpcsc/aws-profile/internal/utils"
 	"strings"
 	"time"
 )
 
-func GetAWSCredentials(profile *awsconfig.Profile, duration time.Duration) (credentials.Value, error) {
-	session := session.Must(session.NewSessionWithOptions(session.Options{
-		SharedConfigState: session.SharedConfigEnable,
-		Profile:           profile.SourceProfile,
-	}))
+// Credentials are credentials of a profile with the time they expire, zero expiration means they don't expire
+type Credentials struct {
+	credentials.Value
+	Expiration time.Time
+}
+
+// MFATokenProviderFn returns provider of MFA token for given profile with mfa_serial
+type MFATokenProviderFn func(awsconfig.Profile) mfa.TokenProvider
+
+// GetAWSCredentials gets credentials of the first profile in chain, then assumes each following role in order,
+// using credentials of previous hop as source for the next one.
+// A zero duration keeps duration_seconds of the selected profile, or the default duration when it is not set.
+// MFA token is prompted on terminal if no MFA token provider is given
+func GetAWSCredentials(chain []awsconfig.Profile, duration time.Duration, mfaTokenProvider MFATokenProviderFn) (Credentials, error) {
+	if len(chain) == 0 {
+		return Credentials{}, newConfigError("no profile given to get credentials")
+	}
+
+	// no request is sent to AWS when base session is created, its errors come from profile configuration
+	currentSession, err := newBaseSession(chain[0])
+	if err != nil {
+		return Credentials{}, &ConfigError{Err: err}
+	}
+
+	// profiles with credential_source or web identity assume their own role using credentials from that source
+	hops := chain[1:]
+	if chain[0].CredentialSource != "" || chain[0].IsWebIdentity() {
+		hops = chain
+	}
+
+	currentCredentials := currentSession.Config.Credentials
+	for i, hop := range hops {
+		hopDuration := stscreds.DefaultDuration
+		if hop.DurationSeconds > 0 {
+			hopDuration = time.Duration(hop.DurationSeconds) * time.Second
+		}
+
+		// duration given explicitly only overrides duration of the last hop
+		if i == len(hops)-1 && duration > 0 {
+			hopDuration = duration
+		}
+
+		roleSessionName := hop.RoleSessionName
+		if roleSessionName == "" {
+			roleSessionName = fmt.Sprintf("aws-profile-%d", time.Now().UnixNano())
+		}
+
+		if hop.IsWebIdentity() {
+			currentCredentials = newWebIdentityCredentials(currentSession, hop, roleSessionName, hopDuration)
+		} else {
+			currentCredentials = stscreds.NewCredentials(currentSession, hop.RoleArn, func(p *stscreds.AssumeRoleProvider) {
+				if hop.MFASerialNumber != "" {
+					p.SerialNumber = aws.String(hop.MFASerialNumber)
+					p.TokenProvider = mfa.Prompt(hop.MFASerialNumber)
+					if mfaTokenProvider != nil {
+						p.TokenProvider = mfaTokenProvider(hop)
+					}
+				}
+				if hop.ExternalId != "" {
+					p.ExternalID = aws.String(hop.ExternalId)
+				}
+				p.RoleSessionName = roleSessionName
+				p.Duration = hopDuration
+			})
+		}
+		currentSession = currentSession.Copy(&aws.Config{Credentials: currentCredentials})
+	}
 
-	credentials := stscreds.NewCredentials(session, profile.RoleArn, func(p *stscreds.AssumeRoleProvider) {
-		if profile.MFASerialNumber != "" {
-			p.SerialNumber = aws.String(profile.MFASerialNumber)
-			p.TokenProvider = stscreds.StdinTokenProvider
+	value, err := currentCredentials.Get()
+	if err != nil {
+		return Credentials{}, fmt.Errorf("failed to get credentials for %s: %w", chain[len(chain)-1].ProfileName, err)
+	}
+
+	// static credentials, e.g. keys of a credentials file profile, don't support expiry
+	expiration, _ := currentCredentials.ExpiresAt()
+
+	return Credentials{
+		Value:      value,
+		Expiration: expiration,
+	}, nil
+}
+
+func newBaseSession(base awsconfig.Profile) (*session.Session, error) {
+	if base.CredentialSource != "" {
+		return newCredentialSourceSession(base)
+	}
+
+	// AssumeRoleWithWebIdentity doesn't need credentials, web identity role is assumed as the first hop
+	if base.IsWebIdentity() {
+		webIdentityConfig := aws.NewConfig().WithCredentials(credentials.AnonymousCredentials)
+		if base.Region != "" {
+			webIdentityConfig = webIdentityConfig.WithRegion(base.Region)
+		}
+
+		return session.NewSession(webIdentityConfig)
+	}
+
+	if base.IsCredentialProcess() {
+		return newCredentialProcessSession(base)
+	}
+
+	if base.IsSSO() {
+		region := base.Region
+		if region == "" {
+			region = base.SSORegion
+		}
+
+		ssoSession, err := session.NewSession()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create sso session for %s: %w", base.ProfileName, err)
 		}
-		p.RoleSessionName = fmt.Sprintf("aws-profile-%d", time.Now().UnixNano())
-		p.Duration = duration
+
+		return session.NewSession(&aws.Config{
+			Region: aws.String(region),
+			Credentials: credentials.NewCredentials(&ssoRoleProvider{
+				profile:        &base,
+				client:         sso.New(ssoSession, ssoClientConfig(base.SSORegion, "AWS_ENDPOINT_URL_SSO")),
+				cacheDirectory: utils.ExpandHomeDirectory(ssoCacheDirectory),
+			}),
+		})
+	}
+
+	baseSession, err := session.NewSessionWithOptions(session.Options{
+		SharedConfigState: session.SharedConfigEnable,
+		Profile:           base.ProfileName,
 	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session for source profile %s: %w", base.ProfileName, err)
+	}
 
-	return credentials.Get()
+	return baseSession, nil
 }
 
 func GetAWSCallerIdentity() (string, error) {
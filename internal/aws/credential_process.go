@@ -0,0 +1,93 @@
+package aws
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/hpcsc/aws-profile/internal/awsconfig"
+	"github.com/hpcsc/aws-profile/internal/process"
+)
+
+const credentialProcessTimeout = time.Minute
+
+// CredentialProcessOutput has the format of credential_process output expected by AWS SDKs and CLI
+type CredentialProcessOutput struct {
+	Version         int
+	AccessKeyId     string
+	SecretAccessKey string
+	SessionToken    string     `json:",omitempty"`
+	Expiration      *time.Time `json:",omitempty"`
+}
+
+type credentialProcessProvider struct {
+	credentials.Expiry
+	command     string
+	timeout     time.Duration
+	stderr      io.Writer
+	neverExpire bool
+}
+
+func newCredentialProcessSession(profile awsconfig.Profile) (*session.Session, error) {
+	processConfig := aws.NewConfig().WithCredentials(credentialProcessCredentials(profile, credentialProcessTimeout))
+	if profile.Region != "" {
+		processConfig = processConfig.WithRegion(profile.Region)
+	}
+
+	return session.NewSession(processConfig)
+}
+
+func credentialProcessCredentials(profile awsconfig.Profile, timeout time.Duration) *credentials.Credentials {
+	return credentials.NewCredentials(&credentialProcessProvider{
+		command: profile.CredentialProcess,
+		timeout: timeout,
+		stderr:  os.Stderr,
+	})
+}
+
+func (p *credentialProcessProvider) Retrieve() (credentials.Value, error) {
+	// stdin and stderr are passed through so that the process can prompt user, e.g. to touch a hardware key
+	stdout, err := process.RunShellCommand("credential process", p.command, os.Stdin, p.stderr, p.timeout)
+	if err != nil {
+		return credentials.Value{}, err
+	}
+
+	output := CredentialProcessOutput{}
+	if err := json.Unmarshal(stdout, &output); err != nil {
+		return credentials.Value{}, fmt.Errorf("failed to parse output of credential process [%s]: %w", p.command, err)
+	}
+
+	if output.Version != 1 {
+		return credentials.Value{}, fmt.Errorf("unsupported version %d in output of credential process [%s], only version 1 is supported", output.Version, p.command)
+	}
+
+	if output.AccessKeyId == "" || output.SecretAccessKey == "" {
+		return credentials.Value{}, fmt.Errorf("output of credential process [%s] is missing AccessKeyId or SecretAccessKey", p.command)
+	}
+
+	// credentials without expiration are long-lived and don't need to be retrieved again
+	p.neverExpire = output.Expiration == nil
+	if output.Expiration != nil {
+		p.SetExpiration(*output.Expiration, 0)
+	}
+
+	return credentials.Value{
+		AccessKeyID:     output.AccessKeyId,
+		SecretAccessKey: output.SecretAccessKey,
+		SessionToken:    output.SessionToken,
+		ProviderName:    "CredentialProcessProvider",
+	}, nil
+}
+
+func (p *credentialProcessProvider) IsExpired() bool {
+	if p.neverExpire {
+		return false
+	}
+
+	return p.Expiry.IsExpired()
+}
@@ -0,0 +1,82 @@
+package aws
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hpcsc/aws-profile/internal/awsconfig"
+	"github.com/stretchr/testify/require"
+)
+
+func stubCredentialProcessProfile(command string) awsconfig.Profile {
+	return awsconfig.Profile{
+		ProfileName:       "profile vault",
+		CredentialProcess: command,
+	}
+}
+
+func TestCredentialProcessCredentials(t *testing.T) {
+	t.Run("return credentials parsed from process output", func(t *testing.T) {
+		profile := stubCredentialProcessProfile(`echo '{"Version": 1, "AccessKeyId": "process-access-key-id", "SecretAccessKey": "process-secret-access-key", "SessionToken": "process-session-token", "Expiration": "2100-01-01T12:00:00Z"}'`)
+
+		processCredentials := credentialProcessCredentials(profile, time.Second)
+		value, err := processCredentials.Get()
+
+		require.NoError(t, err)
+		require.Equal(t, "process-access-key-id", value.AccessKeyID)
+		require.Equal(t, "process-secret-access-key", value.SecretAccessKey)
+		require.Equal(t, "process-session-token", value.SessionToken)
+
+		expiresAt, err := processCredentials.ExpiresAt()
+		require.NoError(t, err)
+		require.Equal(t, time.Date(2100, 1, 1, 12, 0, 0, 0, time.UTC), expiresAt.UTC())
+	})
+
+	t.Run("return error if process output has unsupported version", func(t *testing.T) {
+		profile := stubCredentialProcessProfile(`echo '{"Version": 2, "AccessKeyId": "process-access-key-id", "SecretAccessKey": "process-secret-access-key"}'`)
+
+		_, err := credentialProcessCredentials(profile, time.Second).Get()
+
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unsupported version 2")
+	})
+
+	t.Run("return error if process output is missing secret access key", func(t *testing.T) {
+		profile := stubCredentialProcessProfile(`echo '{"Version": 1, "AccessKeyId": "process-access-key-id"}'`)
+
+		_, err := credentialProcessCredentials(profile, time.Second).Get()
+
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "missing AccessKeyId or SecretAccessKey")
+	})
+
+	t.Run("return error if process fails", func(t *testing.T) {
+		profile := stubCredentialProcessProfile("exit 3")
+
+		_, err := credentialProcessCredentials(profile, time.Second).Get()
+
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "exit status 3")
+	})
+
+	t.Run("return error if process does not finish before timeout", func(t *testing.T) {
+		profile := stubCredentialProcessProfile("sleep 1")
+
+		_, err := credentialProcessCredentials(profile, 100*time.Millisecond).Get()
+
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "timed out")
+	})
+}
+
+func TestGetAWSCredentials_CredentialProcess(t *testing.T) {
+	t.Run("return credentials of credential process profile without assuming role", func(t *testing.T) {
+		profile := stubCredentialProcessProfile(`echo '{"Version": 1, "AccessKeyId": "process-access-key-id", "SecretAccessKey": "process-secret-access-key", "SessionToken": "process-session-token"}'`)
+
+		value, err := GetAWSCredentials([]awsconfig.Profile{profile}, 0, nil)
+
+		require.NoError(t, err)
+		require.Equal(t, "process-access-key-id", value.AccessKeyID)
+		require.True(t, value.Expiration.IsZero())
+	})
+}
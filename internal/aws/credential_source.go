@@ -0,0 +1,81 @@
+package aws
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/ec2rolecreds"
+	"github.com/aws/aws-sdk-go/aws/credentials/endpointcreds"
+	"github.com/aws/aws-sdk-go/aws/ec2metadata"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/hpcsc/aws-profile/internal/awsconfig"
+	"github.com/hpcsc/aws-profile/internal/utils"
+)
+
+const (
+	credentialSourceEnvironment         = "Environment"
+	credentialSourceEc2InstanceMetadata = "Ec2InstanceMetadata"
+	credentialSourceEcsContainer        = "EcsContainer"
+	ecsContainerCredentialsHost         = "http://169.254.170.2"
+)
+
+func newCredentialSourceSession(profile awsconfig.Profile) (*session.Session, error) {
+	sourceConfig := aws.NewConfig()
+	if profile.Region != "" {
+		sourceConfig = sourceConfig.WithRegion(profile.Region)
+	}
+
+	sourceSession, err := session.NewSession(sourceConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session for credential source of %s: %v", profile.ProfileName, err)
+	}
+
+	sourceCredentials, err := credentialSourceCredentials(profile, sourceSession)
+	if err != nil {
+		return nil, err
+	}
+
+	return sourceSession.Copy(&aws.Config{Credentials: sourceCredentials}), nil
+}
+
+func credentialSourceCredentials(profile awsconfig.Profile, sourceSession *session.Session) (*credentials.Credentials, error) {
+	switch profile.CredentialSource {
+	case credentialSourceEnvironment:
+		return credentials.NewEnvCredentials(), nil
+	case credentialSourceEc2InstanceMetadata:
+		metadataConfig := aws.NewConfig()
+		if endpoint := utils.GetEnvVariableOrDefault("AWS_EC2_METADATA_SERVICE_ENDPOINT", ""); endpoint != "" {
+			// metadata client expects endpoint to include api version, e.g. http://169.254.169.254/latest
+			metadataConfig = metadataConfig.WithEndpoint(strings.TrimSuffix(endpoint, "/") + "/latest")
+		}
+
+		return ec2rolecreds.NewCredentialsWithClient(ec2metadata.New(sourceSession, metadataConfig)), nil
+	case credentialSourceEcsContainer:
+		endpoint := ecsContainerCredentialsEndpoint()
+		if endpoint == "" {
+			return nil, fmt.Errorf("credential_source of %s is %s but neither AWS_CONTAINER_CREDENTIALS_RELATIVE_URI nor AWS_CONTAINER_CREDENTIALS_FULL_URI is set", profile.ProfileName, credentialSourceEcsContainer)
+		}
+
+		return endpointcreds.NewCredentialsClient(*sourceSession.Config, sourceSession.Handlers, endpoint, func(p *endpointcreds.Provider) {
+			p.AuthorizationToken = os.Getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN")
+		}), nil
+	default:
+		return nil, fmt.Errorf("unsupported credential_source [%s] of %s, valid values are: %s, %s, %s",
+			profile.CredentialSource,
+			profile.ProfileName,
+			credentialSourceEnvironment,
+			credentialSourceEc2InstanceMetadata,
+			credentialSourceEcsContainer)
+	}
+}
+
+func ecsContainerCredentialsEndpoint() string {
+	if relativeUri := utils.GetEnvVariableOrDefault("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI", ""); relativeUri != "" {
+		return ecsContainerCredentialsHost + relativeUri
+	}
+
+	return utils.GetEnvVariableOrDefault("AWS_CONTAINER_CREDENTIALS_FULL_URI", "")
+}
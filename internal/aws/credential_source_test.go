@@ -0,0 +1,153 @@
+package aws
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/hpcsc/aws-profile/internal/awsconfig"
+	"github.com/stretchr/testify/require"
+)
+
+const stubCredentialsResponse = `{
+  "Code": "Success",
+  "AccessKeyId": "source-access-key-id",
+  "SecretAccessKey": "source-secret-access-key",
+  "Token": "source-session-token",
+  "Expiration": "2100-01-01T00:00:00Z"
+}`
+
+func newStubInstanceMetadataServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/latest/api/token":
+			require.Equal(t, http.MethodPut, r.Method)
+			w.Header().Set("x-aws-ec2-metadata-token-ttl-seconds", r.Header.Get("x-aws-ec2-metadata-token-ttl-seconds"))
+			_, _ = w.Write([]byte("metadata-token"))
+		case "/latest/meta-data/iam/security-credentials/":
+			require.Equal(t, "metadata-token", r.Header.Get("x-aws-ec2-metadata-token"))
+			_, _ = w.Write([]byte("instance-role"))
+		case "/latest/meta-data/iam/security-credentials/instance-role":
+			require.Equal(t, "metadata-token", r.Header.Get("x-aws-ec2-metadata-token"))
+			_, _ = w.Write([]byte(stubCredentialsResponse))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func newStubContainerCredentialsServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "container-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		_, _ = w.Write([]byte(stubCredentialsResponse))
+	}))
+}
+
+func stubCredentialSourceProfile(credentialSource string) awsconfig.Profile {
+	return awsconfig.Profile{
+		ProfileName:      "profile ci",
+		RoleArn:          "arn:aws:iam::123456789012:role/ci",
+		CredentialSource: credentialSource,
+	}
+}
+
+func withEnvVariables(t *testing.T, variables map[string]string, fn func()) {
+	for name, value := range variables {
+		require.NoError(t, os.Setenv(name, value))
+	}
+
+	defer func() {
+		for name := range variables {
+			_ = os.Unsetenv(name)
+		}
+	}()
+
+	fn()
+}
+
+func TestCredentialSourceCredentials(t *testing.T) {
+	t.Run("return credentials from environment variables when credential source is Environment", func(t *testing.T) {
+		withEnvVariables(t, map[string]string{
+			"AWS_ACCESS_KEY_ID":     "env-access-key-id",
+			"AWS_SECRET_ACCESS_KEY": "env-secret-access-key",
+		}, func() {
+			sourceCredentials, err := credentialSourceCredentials(stubCredentialSourceProfile("Environment"), session.Must(session.NewSession()))
+			require.NoError(t, err)
+
+			value, err := sourceCredentials.Get()
+
+			require.NoError(t, err)
+			require.Equal(t, "env-access-key-id", value.AccessKeyID)
+			require.Equal(t, "env-secret-access-key", value.SecretAccessKey)
+		})
+	})
+
+	t.Run("return credentials from instance metadata service when credential source is Ec2InstanceMetadata", func(t *testing.T) {
+		server := newStubInstanceMetadataServer(t)
+		defer server.Close()
+
+		withEnvVariables(t, map[string]string{
+			"AWS_EC2_METADATA_SERVICE_ENDPOINT": server.URL,
+		}, func() {
+			sourceCredentials, err := credentialSourceCredentials(stubCredentialSourceProfile("Ec2InstanceMetadata"), session.Must(session.NewSession()))
+			require.NoError(t, err)
+
+			value, err := sourceCredentials.Get()
+
+			require.NoError(t, err)
+			require.Equal(t, "source-access-key-id", value.AccessKeyID)
+			require.Equal(t, "source-secret-access-key", value.SecretAccessKey)
+			require.Equal(t, "source-session-token", value.SessionToken)
+		})
+	})
+
+	t.Run("return credentials from container credentials endpoint when credential source is EcsContainer", func(t *testing.T) {
+		server := newStubContainerCredentialsServer(t)
+		defer server.Close()
+
+		withEnvVariables(t, map[string]string{
+			"AWS_CONTAINER_CREDENTIALS_FULL_URI": server.URL,
+			"AWS_CONTAINER_AUTHORIZATION_TOKEN":  "container-token",
+		}, func() {
+			sourceCredentials, err := credentialSourceCredentials(stubCredentialSourceProfile("EcsContainer"), session.Must(session.NewSession()))
+			require.NoError(t, err)
+
+			value, err := sourceCredentials.Get()
+
+			require.NoError(t, err)
+			require.Equal(t, "source-access-key-id", value.AccessKeyID)
+			require.Equal(t, "source-session-token", value.SessionToken)
+		})
+	})
+
+	t.Run("return error when credential source is EcsContainer and container credentials uri is not set", func(t *testing.T) {
+		_, err := credentialSourceCredentials(stubCredentialSourceProfile("EcsContainer"), session.Must(session.NewSession()))
+
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "neither AWS_CONTAINER_CREDENTIALS_RELATIVE_URI nor AWS_CONTAINER_CREDENTIALS_FULL_URI is set")
+	})
+
+	t.Run("return error when credential source is not supported", func(t *testing.T) {
+		_, err := credentialSourceCredentials(stubCredentialSourceProfile("Unknown"), session.Must(session.NewSession()))
+
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unsupported credential_source [Unknown]")
+	})
+}
+
+func TestGetAWSCredentials_ConfigError(t *testing.T) {
+	t.Run("return config error when credential source is not supported", func(t *testing.T) {
+		_, err := GetAWSCredentials([]awsconfig.Profile{stubCredentialSourceProfile("Unknown")}, 0, nil)
+
+		var configErr *ConfigError
+		require.True(t, errors.As(err, &configErr))
+		require.Contains(t, err.Error(), "unsupported credential_source [Unknown]")
+	})
+}
@@ -0,0 +1,21 @@
+package aws
+
+import "fmt"
+
+// ConfigError is returned when credentials can't be requested because of invalid profile configuration, as opposed to
+// errors returned by AWS when credentials are requested
+type ConfigError struct {
+	Err error
+}
+
+func (e *ConfigError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+func newConfigError(format string, args ...interface{}) error {
+	return &ConfigError{Err: fmt.Errorf(format, args...)}
+}
@@ -0,0 +1,55 @@
+package aws
+
+import (
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/hpcsc/aws-profile/internal/awsconfig"
+	"github.com/hpcsc/aws-profile/internal/mfa"
+	"time"
+)
+
+// default duration of GetSessionToken, sessions are refreshed less often than assumed roles
+const mfaSessionDuration = 12 * time.Hour
+
+// GetSessionToken gets MFA session credentials using long-term keys of given credentials file profile
+func GetSessionToken(profile awsconfig.Profile, mfaTokenProvider MFATokenProviderFn) (Credentials, error) {
+	baseSession, err := session.NewSessionWithOptions(session.Options{
+		SharedConfigState: session.SharedConfigEnable,
+		Profile:           profile.ProfileName,
+	})
+	if err != nil {
+		return Credentials{}, newConfigError("failed to create session for %s: %w", profile.ProfileName, err)
+	}
+
+	tokenProvider := mfa.Prompt(profile.MFASerialNumber)
+	if mfaTokenProvider != nil {
+		tokenProvider = mfaTokenProvider(profile)
+	}
+
+	token, err := tokenProvider()
+	if err != nil {
+		return Credentials{}, err
+	}
+
+	output, err := sts.New(baseSession, stsClientConfig()).GetSessionToken(&sts.GetSessionTokenInput{
+		DurationSeconds: aws.Int64(int64(mfaSessionDuration / time.Second)),
+		SerialNumber:    aws.String(profile.MFASerialNumber),
+		TokenCode:       aws.String(token),
+	})
+	if err != nil {
+		return Credentials{}, fmt.Errorf("failed to get MFA session for %s: %w", profile.ProfileName, err)
+	}
+
+	return Credentials{
+		Value: credentials.Value{
+			AccessKeyID:     aws.StringValue(output.Credentials.AccessKeyId),
+			SecretAccessKey: aws.StringValue(output.Credentials.SecretAccessKey),
+			SessionToken:    aws.StringValue(output.Credentials.SessionToken),
+			ProviderName:    "GetSessionTokenProvider",
+		},
+		Expiration: aws.TimeValue(output.Credentials.Expiration),
+	}, nil
+}
@@ -0,0 +1,77 @@
+package aws
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hpcsc/aws-profile/internal/awsconfig"
+	"github.com/hpcsc/aws-profile/internal/mfa"
+	"github.com/stretchr/testify/require"
+)
+
+const stubGetSessionTokenResponse = `<GetSessionTokenResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
+  <GetSessionTokenResult>
+    <Credentials>
+      <AccessKeyId>session-access-key-id</AccessKeyId>
+      <SecretAccessKey>session-secret-access-key</SecretAccessKey>
+      <SessionToken>session-token</SessionToken>
+      <Expiration>2100-01-01T00:00:00Z</Expiration>
+    </Credentials>
+  </GetSessionTokenResult>
+</GetSessionTokenResponse>`
+
+func newStubGetSessionTokenServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		require.Equal(t, "GetSessionToken", r.Form.Get("Action"))
+		require.Equal(t, "arn:aws:iam::123456789012:mfa/user", r.Form.Get("SerialNumber"))
+		require.Equal(t, "123456", r.Form.Get("TokenCode"))
+		require.Equal(t, "43200", r.Form.Get("DurationSeconds"))
+		require.Contains(t, r.Header.Get("Authorization"), "Credential=long-term-access-key-id/")
+
+		_, _ = w.Write([]byte(stubGetSessionTokenResponse))
+	}))
+}
+
+func TestGetSessionToken(t *testing.T) {
+	t.Run("get session credentials using long-term keys of profile and provided mfa token", func(t *testing.T) {
+		server := newStubGetSessionTokenServer(t)
+		defer server.Close()
+		directory := createTempDirectory(t)
+		defer os.RemoveAll(directory)
+
+		credentialsFilePath := filepath.Join(directory, "credentials")
+		require.NoError(t, ioutil.WriteFile(credentialsFilePath, []byte(`[user]
+aws_access_key_id = long-term-access-key-id
+aws_secret_access_key = long-term-secret-access-key
+`), 0600))
+
+		profile := awsconfig.Profile{
+			ProfileName:     "user",
+			MFASerialNumber: "arn:aws:iam::123456789012:mfa/user",
+		}
+		mfaTokenProvider := func(awsconfig.Profile) mfa.TokenProvider {
+			return mfa.Static("123456")
+		}
+
+		withEnvVariables(t, map[string]string{
+			"AWS_ENDPOINT_URL_STS":        server.URL,
+			"AWS_SHARED_CREDENTIALS_FILE": credentialsFilePath,
+			"AWS_CONFIG_FILE":             filepath.Join(directory, "config"),
+			"AWS_REGION":                  "us-east-1",
+		}, func() {
+			value, err := GetSessionToken(profile, mfaTokenProvider)
+
+			require.NoError(t, err)
+			require.Equal(t, "session-access-key-id", value.AccessKeyID)
+			require.Equal(t, "session-secret-access-key", value.SecretAccessKey)
+			require.Equal(t, "session-token", value.SessionToken)
+			require.Equal(t, time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC), value.Expiration)
+		})
+	})
+}
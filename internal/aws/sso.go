@@ -0,0 +1,296 @@
+package aws
+
+import (
+	"crypto/sha1" // #nosec
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sso"
+	"github.com/aws/aws-sdk-go/service/ssooidc"
+	"github.com/hpcsc/aws-profile/internal/awsconfig"
+	fileio "github.com/hpcsc/aws-profile/internal/io"
+	"github.com/hpcsc/aws-profile/internal/utils"
+)
+
+const (
+	ssoCacheDirectory       = "~/.aws/sso/cache"
+	ssoTokenTimeFormat      = "2006-01-02T15:04:05Z"
+	ssoClientName           = "aws-profile"
+	ssoClientType           = "public"
+	ssoDeviceCodeGrantType  = "urn:ietf:params:oauth:grant-type:device_code"
+	ssoDefaultPollInterval  = 5 * time.Second
+	ssoDefaultDeviceExpiry  = 10 * time.Minute
+	ssoTokenExpiryThreshold = time.Minute
+)
+
+// SSOToken has the same format as the token cache written by AWS CLI v2 so that both tools can share it
+type SSOToken struct {
+	StartUrl              string `json:"startUrl"`
+	Region                string `json:"region"`
+	AccessToken           string `json:"accessToken"`
+	ExpiresAt             string `json:"expiresAt"`
+	ClientId              string `json:"clientId,omitempty"`
+	ClientSecret          string `json:"clientSecret,omitempty"`
+	RegistrationExpiresAt string `json:"registrationExpiresAt,omitempty"`
+}
+
+func (token SSOToken) isValid(now time.Time) bool {
+	return token.AccessToken != "" && isAfter(token.ExpiresAt, now.Add(ssoTokenExpiryThreshold))
+}
+
+func (token SSOToken) hasValidRegistration(now time.Time) bool {
+	return token.ClientId != "" && token.ClientSecret != "" && isAfter(token.RegistrationExpiresAt, now.Add(ssoTokenExpiryThreshold))
+}
+
+func SSOLogin(profile *awsconfig.Profile) (time.Time, error) {
+	oidcSession, err := session.NewSession()
+	if err != nil {
+		return time.Time{}, newConfigError("failed to create sso oidc session: %w", err)
+	}
+
+	oidcClient := ssooidc.New(oidcSession, ssoClientConfig(profile.SSORegion, "AWS_ENDPOINT_URL_SSO_OIDC"))
+	return ssoLogin(profile, oidcClient, utils.ExpandHomeDirectory(ssoCacheDirectory), os.Stderr, time.Now)
+}
+
+func ssoLogin(profile *awsconfig.Profile, oidcClient *ssooidc.SSOOIDC, cacheDirectory string, output io.Writer, now func() time.Time) (time.Time, error) {
+	cacheFilePath := ssoTokenCacheFilePath(cacheDirectory, profile)
+
+	cachedToken, err := readSSOToken(cacheFilePath)
+	if err == nil && cachedToken.isValid(now()) {
+		expiresAt, _ := parseSSOTime(cachedToken.ExpiresAt)
+		return expiresAt, nil
+	}
+
+	token := SSOToken{
+		StartUrl: profile.SSOStartUrl,
+		Region:   profile.SSORegion,
+	}
+
+	if cachedToken != nil && cachedToken.hasValidRegistration(now()) {
+		token.ClientId = cachedToken.ClientId
+		token.ClientSecret = cachedToken.ClientSecret
+		token.RegistrationExpiresAt = cachedToken.RegistrationExpiresAt
+	} else {
+		registerOutput, err := oidcClient.RegisterClient(&ssooidc.RegisterClientInput{
+			ClientName: aws.String(ssoClientName),
+			ClientType: aws.String(ssoClientType),
+		})
+		if err != nil {
+			return time.Time{}, fmt.Errorf("failed to register sso oidc client: %v", err)
+		}
+
+		token.ClientId = aws.StringValue(registerOutput.ClientId)
+		token.ClientSecret = aws.StringValue(registerOutput.ClientSecret)
+		token.RegistrationExpiresAt = time.Unix(aws.Int64Value(registerOutput.ClientSecretExpiresAt), 0).UTC().Format(ssoTokenTimeFormat)
+	}
+
+	authorizationOutput, err := oidcClient.StartDeviceAuthorization(&ssooidc.StartDeviceAuthorizationInput{
+		ClientId:     aws.String(token.ClientId),
+		ClientSecret: aws.String(token.ClientSecret),
+		StartUrl:     aws.String(profile.SSOStartUrl),
+	})
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to start sso device authorization: %v", err)
+	}
+
+	_, _ = fmt.Fprintf(output, "Open the following url in your browser to authorize this device:\n\n%s\n\nThen confirm the code: %s\n",
+		aws.StringValue(authorizationOutput.VerificationUriComplete),
+		aws.StringValue(authorizationOutput.UserCode))
+
+	createTokenOutput, err := pollForSSOToken(oidcClient, &token, authorizationOutput, now)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	expiresAt := now().Add(time.Duration(aws.Int64Value(createTokenOutput.ExpiresIn)) * time.Second).UTC()
+	token.AccessToken = aws.StringValue(createTokenOutput.AccessToken)
+	token.ExpiresAt = expiresAt.Format(ssoTokenTimeFormat)
+
+	if err := writeSSOToken(cacheFilePath, token); err != nil {
+		return time.Time{}, err
+	}
+
+	return expiresAt, nil
+}
+
+type ssoRoleProvider struct {
+	credentials.Expiry
+	profile        *awsconfig.Profile
+	client         *sso.SSO
+	cacheDirectory string
+}
+
+func (p *ssoRoleProvider) Retrieve() (credentials.Value, error) {
+	roleCredentials, err := getSSORoleCredentials(p.profile, p.client, p.cacheDirectory, time.Now)
+	if err != nil {
+		return credentials.Value{}, err
+	}
+
+	p.SetExpiration(roleCredentials.Expiration, 0)
+	return roleCredentials.Value, nil
+}
+
+func getSSORoleCredentials(profile *awsconfig.Profile, ssoClient *sso.SSO, cacheDirectory string, now func() time.Time) (Credentials, error) {
+	token, err := readSSOToken(ssoTokenCacheFilePath(cacheDirectory, profile))
+	if err != nil || !token.isValid(now()) {
+		return Credentials{}, newSSOLoginRequiredError(profile)
+	}
+
+	output, err := ssoClient.GetRoleCredentials(&sso.GetRoleCredentialsInput{
+		AccessToken: aws.String(token.AccessToken),
+		AccountId:   aws.String(profile.SSOAccountId),
+		RoleName:    aws.String(profile.SSORoleName),
+	})
+	if err != nil {
+		if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == sso.ErrCodeUnauthorizedException {
+			return Credentials{}, newSSOLoginRequiredError(profile)
+		}
+
+		return Credentials{}, fmt.Errorf("failed to get sso role credentials for %s: %w", profile.ProfileName, err)
+	}
+
+	// expiration returned by sso portal is in milliseconds since epoch
+	return Credentials{
+		Value: credentials.Value{
+			AccessKeyID:     aws.StringValue(output.RoleCredentials.AccessKeyId),
+			SecretAccessKey: aws.StringValue(output.RoleCredentials.SecretAccessKey),
+			SessionToken:    aws.StringValue(output.RoleCredentials.SessionToken),
+			ProviderName:    "SSOProvider",
+		},
+		Expiration: time.Unix(0, aws.Int64Value(output.RoleCredentials.Expiration)*int64(time.Millisecond)).UTC(),
+	}, nil
+}
+
+func newSSOLoginRequiredError(profile *awsconfig.Profile) error {
+	return fmt.Errorf("sso token for [%s] is missing or expired, run \"aws-profile sso login\" first", profile.SSOStartUrl)
+}
+
+// pollForSSOToken waits for the user to confirm device authorization, polling no more often than the interval returned
+// with the authorization and giving up once device code expires
+func pollForSSOToken(oidcClient *ssooidc.SSOOIDC, token *SSOToken, authorizationOutput *ssooidc.StartDeviceAuthorizationOutput, now func() time.Time) (*ssooidc.CreateTokenOutput, error) {
+	interval := ssoDefaultPollInterval
+	if authorizationOutput.Interval != nil {
+		interval = time.Duration(aws.Int64Value(authorizationOutput.Interval)) * time.Second
+	}
+
+	expiry := ssoDefaultDeviceExpiry
+	if authorizationOutput.ExpiresIn != nil {
+		expiry = time.Duration(aws.Int64Value(authorizationOutput.ExpiresIn)) * time.Second
+	}
+	deadline := now().Add(expiry)
+
+	for {
+		time.Sleep(interval)
+
+		if !now().Before(deadline) {
+			return nil, errors.New("sso device authorization expired before it was confirmed, run \"aws-profile sso login\" again")
+		}
+
+		createTokenOutput, err := oidcClient.CreateToken(&ssooidc.CreateTokenInput{
+			ClientId:     aws.String(token.ClientId),
+			ClientSecret: aws.String(token.ClientSecret),
+			DeviceCode:   authorizationOutput.DeviceCode,
+			GrantType:    aws.String(ssoDeviceCodeGrantType),
+		})
+		if err == nil {
+			return createTokenOutput, nil
+		}
+
+		awsErr, ok := err.(awserr.Error)
+		if !ok {
+			return nil, fmt.Errorf("failed to create sso token: %v", err)
+		}
+
+		switch awsErr.Code() {
+		case ssooidc.ErrCodeAuthorizationPendingException:
+		case ssooidc.ErrCodeSlowDownException:
+			interval += ssoDefaultPollInterval
+		default:
+			return nil, fmt.Errorf("failed to create sso token: %v", err)
+		}
+	}
+}
+
+func readSSOToken(cacheFilePath string) (*SSOToken, error) {
+	content, err := ioutil.ReadFile(filepath.Clean(cacheFilePath))
+	if err != nil {
+		return nil, err
+	}
+
+	token := &SSOToken{}
+	if err := json.Unmarshal(content, token); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal sso token cache %s: %v", cacheFilePath, err)
+	}
+
+	return token, nil
+}
+
+func writeSSOToken(cacheFilePath string, token SSOToken) error {
+	if err := os.MkdirAll(filepath.Dir(cacheFilePath), os.FileMode(0700)); err != nil {
+		return fmt.Errorf("failed to create sso cache directory: %v", err)
+	}
+
+	content, err := json.Marshal(token)
+	if err != nil {
+		return fmt.Errorf("failed to marshal sso token: %v", err)
+	}
+
+	// AWS CLI may read the cache at the same time, it must never see a partially written token
+	if err := fileio.WriteFileAtomically(cacheFilePath, content); err != nil {
+		return fmt.Errorf("failed to write sso token cache %s: %v", cacheFilePath, err)
+	}
+
+	return nil
+}
+
+// AWS CLI names cache file after sha1 of sso session name, or sha1 of start url for legacy profiles without sso_session
+func ssoTokenCacheFilePath(cacheDirectory string, profile *awsconfig.Profile) string {
+	cacheKey := profile.SSOStartUrl
+	if profile.SSOSession != "" {
+		cacheKey = profile.SSOSession
+	}
+
+	hash := sha1.Sum([]byte(cacheKey)) // #nosec
+	return filepath.Join(cacheDirectory, hex.EncodeToString(hash[:])+".json")
+}
+
+func ssoClientConfig(region string, endpointEnvVariable string) *aws.Config {
+	clientConfig := aws.NewConfig().WithRegion(region)
+
+	if endpoint := utils.GetEnvVariableOrDefault(endpointEnvVariable, ""); endpoint != "" {
+		clientConfig = clientConfig.WithEndpoint(endpoint)
+	}
+
+	return clientConfig
+}
+
+func isAfter(formattedTime string, threshold time.Time) bool {
+	parsedTime, err := parseSSOTime(formattedTime)
+	if err != nil {
+		return false
+	}
+
+	return parsedTime.After(threshold)
+}
+
+// older versions of AWS CLI write expiry time with "UTC" suffix instead of "Z"
+func parseSSOTime(formattedTime string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05UTC"} {
+		if parsedTime, err := time.Parse(layout, formattedTime); err == nil {
+			return parsedTime, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid sso token time format: %s", formattedTime)
+}
@@ -0,0 +1,298 @@
+package aws
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sso"
+	"github.com/aws/aws-sdk-go/service/ssooidc"
+	"github.com/hpcsc/aws-profile/internal/awsconfig"
+	"github.com/stretchr/testify/require"
+)
+
+type stubOIDCServer struct {
+	server               *httptest.Server
+	registerClientCalled int
+	pendingTokenRequests int
+	createTokenCalled    int
+	deviceCodeExpiresIn  int
+}
+
+func newStubOIDCServer(pendingTokenRequests int) *stubOIDCServer {
+	stub := &stubOIDCServer{pendingTokenRequests: pendingTokenRequests, deviceCodeExpiresIn: 600}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/client/register", func(w http.ResponseWriter, r *http.Request) {
+		stub.registerClientCalled++
+		writeJSON(w, map[string]interface{}{
+			"clientId":              "client-id",
+			"clientSecret":          "client-secret",
+			"clientSecretExpiresAt": time.Now().Add(90 * 24 * time.Hour).Unix(),
+		})
+	})
+	mux.HandleFunc("/device_authorization", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]interface{}{
+			"deviceCode":              "device-code",
+			"userCode":                "ABCD-EFGH",
+			"verificationUriComplete": "https://device.sso.us-east-1.amazonaws.com/?user_code=ABCD-EFGH",
+			"interval":                0,
+			"expiresIn":               stub.deviceCodeExpiresIn,
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		stub.createTokenCalled++
+		if stub.createTokenCalled <= stub.pendingTokenRequests {
+			w.Header().Set("X-Amzn-Errortype", ssooidc.ErrCodeAuthorizationPendingException)
+			w.WriteHeader(http.StatusBadRequest)
+			writeJSON(w, map[string]interface{}{"error": "authorization_pending"})
+			return
+		}
+
+		writeJSON(w, map[string]interface{}{
+			"accessToken": "access-token",
+			"expiresIn":   28800,
+			"tokenType":   "Bearer",
+		})
+	})
+
+	stub.server = httptest.NewServer(mux)
+	return stub
+}
+
+func writeJSON(w http.ResponseWriter, body map[string]interface{}) {
+	content, _ := json.Marshal(body)
+	_, _ = w.Write(content)
+}
+
+func stubSSOProfile() *awsconfig.Profile {
+	return &awsconfig.Profile{
+		ProfileName:  "profile sso",
+		SSOStartUrl:  "https://my-sso-portal.awsapps.com/start",
+		SSORegion:    "us-east-1",
+		SSOAccountId: "123456789012",
+		SSORoleName:  "ReadOnly",
+	}
+}
+
+func setupOIDCClient(t *testing.T, serverUrl string) *ssooidc.SSOOIDC {
+	require.NoError(t, os.Setenv("AWS_ENDPOINT_URL_SSO_OIDC", serverUrl))
+	defer os.Unsetenv("AWS_ENDPOINT_URL_SSO_OIDC")
+
+	return ssooidc.New(session.Must(session.NewSession()), ssoClientConfig("us-east-1", "AWS_ENDPOINT_URL_SSO_OIDC"))
+}
+
+func createTempDirectory(t *testing.T) string {
+	directory, err := ioutil.TempDir("", "aws-profile-sso")
+	require.NoError(t, err)
+	return directory
+}
+
+func TestSSOLogin(t *testing.T) {
+	now := func() time.Time {
+		return time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+
+	t.Run("write token to cache file named after sha1 of start url", func(t *testing.T) {
+		stub := newStubOIDCServer(1)
+		defer stub.server.Close()
+		cacheDirectory := createTempDirectory(t)
+		defer os.RemoveAll(cacheDirectory)
+
+		var output bytes.Buffer
+		expiresAt, err := ssoLogin(stubSSOProfile(), setupOIDCClient(t, stub.server.URL), cacheDirectory, &output, now)
+
+		require.NoError(t, err)
+		require.Equal(t, time.Date(2020, 1, 1, 8, 0, 0, 0, time.UTC), expiresAt)
+		require.Equal(t, 2, stub.createTokenCalled)
+		require.Contains(t, output.String(), "ABCD-EFGH")
+
+		// sha1 of https://my-sso-portal.awsapps.com/start
+		token, err := readSSOToken(filepath.Join(cacheDirectory, "c7aaaf71fcc8777ae2475525ed049d39fe16c484.json"))
+		require.NoError(t, err)
+		require.Equal(t, "https://my-sso-portal.awsapps.com/start", token.StartUrl)
+		require.Equal(t, "us-east-1", token.Region)
+		require.Equal(t, "access-token", token.AccessToken)
+		require.Equal(t, "2020-01-01T08:00:00Z", token.ExpiresAt)
+		require.Equal(t, "client-id", token.ClientId)
+		require.Equal(t, "client-secret", token.ClientSecret)
+	})
+
+	t.Run("return error without creating token when device code expires", func(t *testing.T) {
+		stub := newStubOIDCServer(0)
+		stub.deviceCodeExpiresIn = 0
+		defer stub.server.Close()
+		cacheDirectory := createTempDirectory(t)
+		defer os.RemoveAll(cacheDirectory)
+
+		_, err := ssoLogin(stubSSOProfile(), setupOIDCClient(t, stub.server.URL), cacheDirectory, &bytes.Buffer{}, now)
+
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "sso device authorization expired")
+		require.Equal(t, 0, stub.createTokenCalled)
+	})
+
+	t.Run("reuse cached token if it is still valid", func(t *testing.T) {
+		stub := newStubOIDCServer(0)
+		defer stub.server.Close()
+		cacheDirectory := createTempDirectory(t)
+		defer os.RemoveAll(cacheDirectory)
+
+		profile := stubSSOProfile()
+		require.NoError(t, writeSSOToken(ssoTokenCacheFilePath(cacheDirectory, profile), SSOToken{
+			StartUrl:    profile.SSOStartUrl,
+			Region:      profile.SSORegion,
+			AccessToken: "cached-access-token",
+			ExpiresAt:   "2020-01-01T02:00:00Z",
+		}))
+
+		var output bytes.Buffer
+		expiresAt, err := ssoLogin(profile, setupOIDCClient(t, stub.server.URL), cacheDirectory, &output, now)
+
+		require.NoError(t, err)
+		require.Equal(t, time.Date(2020, 1, 1, 2, 0, 0, 0, time.UTC), expiresAt)
+		require.Equal(t, 0, stub.registerClientCalled)
+		require.Equal(t, 0, stub.createTokenCalled)
+	})
+
+	t.Run("reuse cached client registration when cached token is expired", func(t *testing.T) {
+		stub := newStubOIDCServer(0)
+		defer stub.server.Close()
+		cacheDirectory := createTempDirectory(t)
+		defer os.RemoveAll(cacheDirectory)
+
+		profile := stubSSOProfile()
+		require.NoError(t, writeSSOToken(ssoTokenCacheFilePath(cacheDirectory, profile), SSOToken{
+			StartUrl:              profile.SSOStartUrl,
+			Region:                profile.SSORegion,
+			AccessToken:           "cached-access-token",
+			ExpiresAt:             "2019-12-31T00:00:00UTC",
+			ClientId:              "cached-client-id",
+			ClientSecret:          "cached-client-secret",
+			RegistrationExpiresAt: "2020-03-01T00:00:00Z",
+		}))
+
+		var output bytes.Buffer
+		_, err := ssoLogin(profile, setupOIDCClient(t, stub.server.URL), cacheDirectory, &output, now)
+
+		require.NoError(t, err)
+		require.Equal(t, 0, stub.registerClientCalled)
+
+		token, err := readSSOToken(ssoTokenCacheFilePath(cacheDirectory, profile))
+		require.NoError(t, err)
+		require.Equal(t, "access-token", token.AccessToken)
+		require.Equal(t, "cached-client-id", token.ClientId)
+	})
+
+	t.Run("name cache file after sso session name when profile uses sso session", func(t *testing.T) {
+		profile := stubSSOProfile()
+		profile.SSOSession = "my-sso"
+
+		// sha1 of my-sso
+		require.Equal(t, filepath.Join("cache", "0ad374308c5a4e22f723adf10145eafad7c4031c.json"), ssoTokenCacheFilePath("cache", profile))
+	})
+}
+
+func newStubSSOPortalServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/federation/credentials", r.URL.Path)
+		require.Equal(t, "123456789012", r.URL.Query().Get("account_id"))
+		require.Equal(t, "ReadOnly", r.URL.Query().Get("role_name"))
+
+		if r.Header.Get("x-amz-sso_bearer_token") != "access-token" {
+			w.Header().Set("X-Amzn-Errortype", sso.ErrCodeUnauthorizedException)
+			w.WriteHeader(http.StatusUnauthorized)
+			writeJSON(w, map[string]interface{}{"message": "Session token not found or invalid"})
+			return
+		}
+
+		writeJSON(w, map[string]interface{}{
+			"roleCredentials": map[string]interface{}{
+				"accessKeyId":     "access-key-id",
+				"secretAccessKey": "secret-access-key",
+				"sessionToken":    "session-token",
+				"expiration":      1577840400000,
+			},
+		})
+	}))
+}
+
+func setupSSOClient(t *testing.T, serverUrl string) *sso.SSO {
+	require.NoError(t, os.Setenv("AWS_ENDPOINT_URL_SSO", serverUrl))
+	defer os.Unsetenv("AWS_ENDPOINT_URL_SSO")
+
+	return sso.New(session.Must(session.NewSession()), ssoClientConfig("us-east-1", "AWS_ENDPOINT_URL_SSO"))
+}
+
+func TestGetSSORoleCredentials(t *testing.T) {
+	now := func() time.Time {
+		return time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+
+	writeCachedToken := func(t *testing.T, cacheDirectory string, accessToken string, expiresAt string) {
+		profile := stubSSOProfile()
+		require.NoError(t, writeSSOToken(ssoTokenCacheFilePath(cacheDirectory, profile), SSOToken{
+			StartUrl:    profile.SSOStartUrl,
+			Region:      profile.SSORegion,
+			AccessToken: accessToken,
+			ExpiresAt:   expiresAt,
+		}))
+	}
+
+	t.Run("return role credentials using cached sso token", func(t *testing.T) {
+		server := newStubSSOPortalServer(t)
+		defer server.Close()
+		cacheDirectory := createTempDirectory(t)
+		defer os.RemoveAll(cacheDirectory)
+		writeCachedToken(t, cacheDirectory, "access-token", "2020-01-01T08:00:00Z")
+
+		value, err := getSSORoleCredentials(stubSSOProfile(), setupSSOClient(t, server.URL), cacheDirectory, now)
+
+		require.NoError(t, err)
+		require.Equal(t, "access-key-id", value.AccessKeyID)
+		require.Equal(t, "secret-access-key", value.SecretAccessKey)
+		require.Equal(t, "session-token", value.SessionToken)
+		require.Equal(t, time.Date(2020, 1, 1, 1, 0, 0, 0, time.UTC), value.Expiration)
+	})
+
+	t.Run("return error asking to login when sso token is not cached", func(t *testing.T) {
+		cacheDirectory := createTempDirectory(t)
+		defer os.RemoveAll(cacheDirectory)
+
+		_, err := getSSORoleCredentials(stubSSOProfile(), setupSSOClient(t, "http://localhost:1"), cacheDirectory, now)
+
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "run \"aws-profile sso login\" first")
+	})
+
+	t.Run("return error asking to login when cached sso token is expired", func(t *testing.T) {
+		cacheDirectory := createTempDirectory(t)
+		defer os.RemoveAll(cacheDirectory)
+		writeCachedToken(t, cacheDirectory, "access-token", "2019-12-31T23:00:00Z")
+
+		_, err := getSSORoleCredentials(stubSSOProfile(), setupSSOClient(t, "http://localhost:1"), cacheDirectory, now)
+
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "is missing or expired")
+	})
+
+	t.Run("return error asking to login when sso token is rejected", func(t *testing.T) {
+		server := newStubSSOPortalServer(t)
+		defer server.Close()
+		cacheDirectory := createTempDirectory(t)
+		defer os.RemoveAll(cacheDirectory)
+		writeCachedToken(t, cacheDirectory, "revoked-access-token", "2020-01-01T08:00:00Z")
+
+		_, err := getSSORoleCredentials(stubSSOProfile(), setupSSOClient(t, server.URL), cacheDirectory, now)
+
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "run \"aws-profile sso login\" first")
+	})
+}
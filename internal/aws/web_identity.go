@@ -0,0 +1,73 @@
+package aws
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/hpcsc/aws-profile/internal/awsconfig"
+	"github.com/hpcsc/aws-profile/internal/utils"
+)
+
+// stscreds.WebIdentityRoleProvider doesn't support session duration, this provider reads the token file on every
+// retrieval so that tokens rotated by EKS or CI runners are picked up
+type webIdentityRoleProvider struct {
+	credentials.Expiry
+	client          *sts.STS
+	roleArn         string
+	roleSessionName string
+	tokenFilePath   string
+	duration        time.Duration
+}
+
+func newWebIdentityCredentials(sess *session.Session, profile awsconfig.Profile, roleSessionName string, duration time.Duration) *credentials.Credentials {
+	return credentials.NewCredentials(&webIdentityRoleProvider{
+		client:          sts.New(sess, stsClientConfig()),
+		roleArn:         profile.RoleArn,
+		roleSessionName: roleSessionName,
+		tokenFilePath:   utils.ExpandHomeDirectory(profile.WebIdentityTokenFile),
+		duration:        duration,
+	})
+}
+
+func (p *webIdentityRoleProvider) Retrieve() (credentials.Value, error) {
+	token, err := ioutil.ReadFile(filepath.Clean(p.tokenFilePath))
+	if err != nil {
+		return credentials.Value{}, fmt.Errorf("failed to read web identity token file %s: %w", p.tokenFilePath, err)
+	}
+
+	output, err := p.client.AssumeRoleWithWebIdentity(&sts.AssumeRoleWithWebIdentityInput{
+		RoleArn:          aws.String(p.roleArn),
+		RoleSessionName:  aws.String(p.roleSessionName),
+		WebIdentityToken: aws.String(strings.TrimSpace(string(token))),
+		DurationSeconds:  aws.Int64(int64(p.duration / time.Second)),
+	})
+	if err != nil {
+		return credentials.Value{}, fmt.Errorf("failed to assume role %s with web identity: %w", p.roleArn, err)
+	}
+
+	p.SetExpiration(aws.TimeValue(output.Credentials.Expiration), 0)
+
+	return credentials.Value{
+		AccessKeyID:     aws.StringValue(output.Credentials.AccessKeyId),
+		SecretAccessKey: aws.StringValue(output.Credentials.SecretAccessKey),
+		SessionToken:    aws.StringValue(output.Credentials.SessionToken),
+		ProviderName:    "WebIdentityRoleProvider",
+	}, nil
+}
+
+func stsClientConfig() *aws.Config {
+	clientConfig := aws.NewConfig()
+
+	if endpoint := utils.GetEnvVariableOrDefault("AWS_ENDPOINT_URL_STS", ""); endpoint != "" {
+		clientConfig = clientConfig.WithEndpoint(endpoint)
+	}
+
+	return clientConfig
+}
@@ -0,0 +1,97 @@
+package aws
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hpcsc/aws-profile/internal/awsconfig"
+	"github.com/stretchr/testify/require"
+)
+
+const stubAssumeRoleWithWebIdentityResponse = `<AssumeRoleWithWebIdentityResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
+  <AssumeRoleWithWebIdentityResult>
+    <Credentials>
+      <AccessKeyId>web-identity-access-key-id</AccessKeyId>
+      <SecretAccessKey>web-identity-secret-access-key</SecretAccessKey>
+      <SessionToken>web-identity-session-token</SessionToken>
+      <Expiration>2100-01-01T00:00:00Z</Expiration>
+    </Credentials>
+  </AssumeRoleWithWebIdentityResult>
+</AssumeRoleWithWebIdentityResponse>`
+
+func newStubSTSServer(t *testing.T, expectedDurationSeconds string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		require.Equal(t, "AssumeRoleWithWebIdentity", r.Form.Get("Action"))
+		require.Equal(t, "arn:aws:iam::123456789012:role/eks", r.Form.Get("RoleArn"))
+		require.Equal(t, "eks-session", r.Form.Get("RoleSessionName"))
+		require.Equal(t, "web-identity-token", r.Form.Get("WebIdentityToken"))
+		require.Equal(t, expectedDurationSeconds, r.Form.Get("DurationSeconds"))
+
+		_, _ = w.Write([]byte(stubAssumeRoleWithWebIdentityResponse))
+	}))
+}
+
+func stubWebIdentityProfile(t *testing.T, directory string) awsconfig.Profile {
+	tokenFilePath := filepath.Join(directory, "token")
+	require.NoError(t, ioutil.WriteFile(tokenFilePath, []byte("web-identity-token\n"), 0600))
+
+	return awsconfig.Profile{
+		ProfileName:          "profile eks",
+		RoleArn:              "arn:aws:iam::123456789012:role/eks",
+		RoleSessionName:      "eks-session",
+		WebIdentityTokenFile: tokenFilePath,
+		Region:               "us-east-1",
+	}
+}
+
+func TestGetAWSCredentials_WebIdentity(t *testing.T) {
+	t.Run("assume role with web identity token read from token file", func(t *testing.T) {
+		server := newStubSTSServer(t, "900")
+		defer server.Close()
+		directory := createTempDirectory(t)
+		defer os.RemoveAll(directory)
+
+		withEnvVariables(t, map[string]string{"AWS_ENDPOINT_URL_STS": server.URL}, func() {
+			value, err := GetAWSCredentials([]awsconfig.Profile{stubWebIdentityProfile(t, directory)}, 0, nil)
+
+			require.NoError(t, err)
+			require.Equal(t, "web-identity-access-key-id", value.AccessKeyID)
+			require.Equal(t, "web-identity-secret-access-key", value.SecretAccessKey)
+			require.Equal(t, "web-identity-session-token", value.SessionToken)
+			require.Equal(t, time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC), value.Expiration)
+		})
+	})
+
+	t.Run("assume role with web identity using given duration", func(t *testing.T) {
+		server := newStubSTSServer(t, "3600")
+		defer server.Close()
+		directory := createTempDirectory(t)
+		defer os.RemoveAll(directory)
+
+		withEnvVariables(t, map[string]string{"AWS_ENDPOINT_URL_STS": server.URL}, func() {
+			_, err := GetAWSCredentials([]awsconfig.Profile{stubWebIdentityProfile(t, directory)}, time.Hour, nil)
+
+			require.NoError(t, err)
+		})
+	})
+
+	t.Run("return error if token file does not exist", func(t *testing.T) {
+		profile := awsconfig.Profile{
+			ProfileName:          "profile eks",
+			RoleArn:              "arn:aws:iam::123456789012:role/eks",
+			WebIdentityTokenFile: "/not-exists/token",
+			Region:               "us-east-1",
+		}
+
+		_, err := GetAWSCredentials([]awsconfig.Profile{profile}, 0, nil)
+
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to read web identity token file /not-exists/token")
+	})
+}
@@ -0,0 +1,121 @@
+package awsconfig
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ResolveSourceChain returns profiles needed to get credentials for given profile, starting from the profile providing
+// base credentials and ending with given profile, e.g. [base, role A, role B] for B -> A -> base
+func (profiles Profiles) ResolveSourceChain(profile *Profile) ([]Profile, error) {
+	chain := []Profile{*profile}
+	current := *profile
+
+	for current.IsAssumed() {
+		if isSelfReference(current) {
+			if source := profiles.FindProfileInCredentialsFile(current.SourceProfile); source != nil {
+				return append([]Profile{*source}, chain...), nil
+			}
+
+			return nil, fmt.Errorf("source profile [%s] of [%s] references itself but is not found in credentials file", current.SourceProfile, current.ProfileName)
+		}
+
+		source := profiles.findConfigProfileBySourceName(current.SourceProfile)
+		if source == nil {
+			source = profiles.FindProfileInCredentialsFile(current.SourceProfile)
+		}
+
+		if source == nil && profiles.hasOtherSection(current.SourceProfile) {
+			// e.g. [default] or config file profile with static keys, its credentials are loaded as base of the chain
+			base := Profile{ProfileName: current.SourceProfile, DisplayProfileName: current.SourceProfile}
+			return append([]Profile{base}, chain...), nil
+		}
+
+		if source == nil {
+			return nil, fmt.Errorf("source profile [%s] of [%s] not found in config or credentials file", current.SourceProfile, current.ProfileName)
+		}
+
+		for _, visited := range chain {
+			if strings.EqualFold(visited.ProfileName, source.ProfileName) {
+				return nil, fmt.Errorf("source profile cycle detected: %s -> %s", formatChain(chain), source.ProfileName)
+			}
+		}
+
+		chain = append([]Profile{*source}, chain...)
+		current = *source
+	}
+
+	return chain, nil
+}
+
+// SourceChainNames returns names of profiles from given profile to the profile providing its base credentials,
+// following source_profile as far as it's found in loaded profiles. Unlike ResolveSourceChain, it never fails
+func (profiles Profiles) SourceChainNames(profile *Profile) []string {
+	names := []string{profile.ProfileName}
+	current := *profile
+
+	for current.IsAssumed() && !isSelfReference(current) {
+		source := profiles.findConfigProfileBySourceName(current.SourceProfile)
+		if source == nil {
+			source = profiles.FindProfileInCredentialsFile(current.SourceProfile)
+		}
+
+		if source == nil {
+			return append(names, current.SourceProfile)
+		}
+
+		for _, visited := range names {
+			if strings.EqualFold(visited, source.ProfileName) {
+				return append(names, source.ProfileName)
+			}
+		}
+
+		names = append(names, source.ProfileName)
+		current = *source
+	}
+
+	if current.IsAssumed() {
+		names = append(names, current.SourceProfile)
+	}
+
+	return names
+}
+
+// source_profile refers to profile name without "profile " prefix used by config file sections
+func (profiles Profiles) findConfigProfileBySourceName(sourceName string) *Profile {
+	for _, profile := range profiles.ConfigFileProfiles().all() {
+		if strings.EqualFold(trimProfilePrefix(profile.ProfileName), sourceName) {
+			return &profile
+		}
+	}
+
+	return nil
+}
+
+func (profiles Profiles) hasOtherSection(sourceName string) bool {
+	for _, name := range profiles.OtherSectionNames {
+		if strings.EqualFold(name, sourceName) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isSelfReference(profile Profile) bool {
+	return strings.EqualFold(trimProfilePrefix(profile.ProfileName), profile.SourceProfile)
+}
+
+func trimProfilePrefix(name string) string {
+	return strings.TrimPrefix(name, "profile ")
+}
+
+func formatChain(chain []Profile) string {
+	var names []string
+
+	for i := len(chain) - 1; i >= 0; i-- {
+		names = append(names, chain[i].ProfileName)
+	}
+
+	return strings.Join(names, " -> ")
+}
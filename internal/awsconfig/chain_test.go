@@ -0,0 +1,254 @@
+package awsconfig
+
+import (
+	"github.com/stretchr/testify/require"
+	"gopkg.in/ini.v1"
+	"testing"
+)
+
+func AddChainedConfigSection(file *ini.File, sectionName string, sourceProfile string) *ini.Section {
+	section, _ := file.NewSection(sectionName)
+	section.Key("role_arn").SetValue(sectionName + "-role-arn")
+	section.Key("source_profile").SetValue(sourceProfile)
+	return section
+}
+
+func profileNames(chain []Profile) []string {
+	var names []string
+	for _, profile := range chain {
+		names = append(names, profile.ProfileName)
+	}
+	return names
+}
+
+func TestResolveSourceChain(t *testing.T) {
+	t.Run("return credentials profile followed by selected profile when source profile is in credentials file", func(t *testing.T) {
+		credentialsFile := ini.Empty()
+		AddCredentialsSection(credentialsFile, "base")
+		configFile := ini.Empty()
+		AddChainedConfigSection(configFile, "profile target", "base")
+		profiles, err := LoadProfilesFromConfigAndCredentials(credentialsFile, configFile)
+		require.NoError(t, err)
+
+		chain, err := profiles.ResolveSourceChain(profiles.FindProfileInConfigFile("profile target"))
+
+		require.NoError(t, err)
+		require.Equal(t, []string{"base", "profile target"}, profileNames(chain))
+	})
+
+	t.Run("return all assumed profiles in order when source profiles are chained", func(t *testing.T) {
+		credentialsFile := ini.Empty()
+		AddCredentialsSection(credentialsFile, "base")
+		configFile := ini.Empty()
+		AddChainedConfigSection(configFile, "profile a", "base")
+		bSection := AddChainedConfigSection(configFile, "profile b", "a")
+		bSection.Key("mfa_serial").SetValue("b-mfa-serial")
+		bSection.Key("external_id").SetValue("b-external-id")
+		bSection.Key("duration_seconds").SetValue("3600")
+		AddChainedConfigSection(configFile, "profile c", "b")
+		profiles, err := LoadProfilesFromConfigAndCredentials(credentialsFile, configFile)
+		require.NoError(t, err)
+
+		chain, err := profiles.ResolveSourceChain(profiles.FindProfileInConfigFile("profile c"))
+
+		require.NoError(t, err)
+		require.Equal(t, []string{"base", "profile a", "profile b", "profile c"}, profileNames(chain))
+		require.Equal(t, "b-mfa-serial", chain[2].MFASerialNumber)
+		require.Equal(t, "b-external-id", chain[2].ExternalId)
+		require.Equal(t, 3600, chain[2].DurationSeconds)
+	})
+
+	t.Run("return sso profile as base of chain when source profile is an sso profile", func(t *testing.T) {
+		configFile := ini.Empty()
+		AddSSOConfigSection(configFile, "profile sso")
+		AddChainedConfigSection(configFile, "profile target", "sso")
+		profiles, err := LoadProfilesFromConfigAndCredentials(nil, configFile)
+		require.NoError(t, err)
+
+		chain, err := profiles.ResolveSourceChain(profiles.FindProfileInConfigFile("profile target"))
+
+		require.NoError(t, err)
+		require.Equal(t, []string{"profile sso", "profile target"}, profileNames(chain))
+		require.True(t, chain[0].IsSSO())
+	})
+
+	t.Run("return credential process profile as base of chain", func(t *testing.T) {
+		configFile := ini.Empty()
+		processSection, _ := configFile.NewSection("profile vault")
+		processSection.Key("credential_process").SetValue("vault-creds")
+		AddChainedConfigSection(configFile, "profile target", "vault")
+		profiles, err := LoadProfilesFromConfigAndCredentials(nil, configFile)
+		require.NoError(t, err)
+
+		chain, err := profiles.ResolveSourceChain(profiles.FindProfileInConfigFile("profile target"))
+
+		require.NoError(t, err)
+		require.Equal(t, []string{"profile vault", "profile target"}, profileNames(chain))
+		require.Equal(t, "vault-creds", chain[0].CredentialProcess)
+	})
+
+	t.Run("return web identity profile as base of chain", func(t *testing.T) {
+		configFile := ini.Empty()
+		webIdentitySection, _ := configFile.NewSection("profile eks")
+		webIdentitySection.Key("role_arn").SetValue("eks-role-arn")
+		webIdentitySection.Key("web_identity_token_file").SetValue("/tmp/token")
+		AddChainedConfigSection(configFile, "profile target", "eks")
+		profiles, err := LoadProfilesFromConfigAndCredentials(nil, configFile)
+		require.NoError(t, err)
+
+		chain, err := profiles.ResolveSourceChain(profiles.FindProfileInConfigFile("profile target"))
+
+		require.NoError(t, err)
+		require.Equal(t, []string{"profile eks", "profile target"}, profileNames(chain))
+		require.True(t, chain[0].IsWebIdentity())
+	})
+
+	t.Run("return credential source profile as base of chain when source profile has credential source", func(t *testing.T) {
+		configFile := ini.Empty()
+		ciSection, _ := configFile.NewSection("profile ci")
+		ciSection.Key("role_arn").SetValue("ci-role-arn")
+		ciSection.Key("credential_source").SetValue("EcsContainer")
+		AddChainedConfigSection(configFile, "profile target", "ci")
+		profiles, err := LoadProfilesFromConfigAndCredentials(nil, configFile)
+		require.NoError(t, err)
+
+		chain, err := profiles.ResolveSourceChain(profiles.FindProfileInConfigFile("profile target"))
+
+		require.NoError(t, err)
+		require.Equal(t, []string{"profile ci", "profile target"}, profileNames(chain))
+		require.Equal(t, "EcsContainer", chain[0].CredentialSource)
+	})
+
+	t.Run("return only selected profile when it does not have source profile", func(t *testing.T) {
+		configFile := ini.Empty()
+		AddSSOConfigSection(configFile, "profile sso")
+		profiles, err := LoadProfilesFromConfigAndCredentials(nil, configFile)
+		require.NoError(t, err)
+
+		chain, err := profiles.ResolveSourceChain(profiles.FindSSOProfileInConfigFile("profile sso"))
+
+		require.NoError(t, err)
+		require.Equal(t, []string{"profile sso"}, profileNames(chain))
+	})
+
+	t.Run("return credentials profile with same name when profile references itself", func(t *testing.T) {
+		credentialsFile := ini.Empty()
+		AddCredentialsSection(credentialsFile, "self")
+		configFile := ini.Empty()
+		AddChainedConfigSection(configFile, "profile self", "self")
+		profiles, err := LoadProfilesFromConfigAndCredentials(credentialsFile, configFile)
+		require.NoError(t, err)
+
+		chain, err := profiles.ResolveSourceChain(profiles.FindProfileInConfigFile("profile self"))
+
+		require.NoError(t, err)
+		require.Equal(t, []string{"self", "profile self"}, profileNames(chain))
+	})
+
+	t.Run("return default profile as base of chain when source profile is default", func(t *testing.T) {
+		credentialsFile := ini.Empty()
+		AddCredentialsSection(credentialsFile, "default")
+		configFile := ini.Empty()
+		AddChainedConfigSection(configFile, "profile a", "default")
+		AddChainedConfigSection(configFile, "profile b", "a")
+		profiles, err := LoadProfilesFromConfigAndCredentials(credentialsFile, configFile)
+		require.NoError(t, err)
+
+		chain, err := profiles.ResolveSourceChain(profiles.FindProfileInConfigFile("profile b"))
+
+		require.NoError(t, err)
+		require.Equal(t, []string{"default", "profile a", "profile b"}, profileNames(chain))
+		require.False(t, chain[0].IsAssumed())
+	})
+
+	t.Run("return config file profile with static keys as base of chain", func(t *testing.T) {
+		configFile := ini.Empty()
+		AddCredentialsSection(configFile, "profile base")
+		AddChainedConfigSection(configFile, "profile target", "base")
+		profiles, err := LoadProfilesFromConfigAndCredentials(ini.Empty(), configFile)
+		require.NoError(t, err)
+
+		chain, err := profiles.ResolveSourceChain(profiles.FindProfileInConfigFile("profile target"))
+
+		require.NoError(t, err)
+		require.Equal(t, []string{"base", "profile target"}, profileNames(chain))
+	})
+
+	t.Run("return error when source profile is not found", func(t *testing.T) {
+		configFile := ini.Empty()
+		AddChainedConfigSection(configFile, "profile a", "not-exists")
+		AddChainedConfigSection(configFile, "profile b", "a")
+		profiles, err := LoadProfilesFromConfigAndCredentials(nil, configFile)
+		require.NoError(t, err)
+
+		_, err = profiles.ResolveSourceChain(profiles.FindProfileInConfigFile("profile b"))
+
+		require.Error(t, err)
+		require.Equal(t, "source profile [not-exists] of [profile a] not found in config or credentials file", err.Error())
+	})
+
+	t.Run("return error when source profiles form a cycle", func(t *testing.T) {
+		configFile := ini.Empty()
+		AddChainedConfigSection(configFile, "profile a", "c")
+		AddChainedConfigSection(configFile, "profile b", "a")
+		AddChainedConfigSection(configFile, "profile c", "b")
+		profiles, err := LoadProfilesFromConfigAndCredentials(nil, configFile)
+		require.NoError(t, err)
+
+		_, err = profiles.ResolveSourceChain(profiles.FindProfileInConfigFile("profile c"))
+
+		require.Error(t, err)
+		require.Equal(t, "source profile cycle detected: profile c -> profile b -> profile a -> profile c", err.Error())
+	})
+}
+
+func TestSourceChainNames(t *testing.T) {
+	t.Run("return only given profile when it is not assumed", func(t *testing.T) {
+		credentialsFile := ini.Empty()
+		AddCredentialsSection(credentialsFile, "base")
+		profiles, err := LoadProfilesFromConfigAndCredentials(credentialsFile, nil)
+		require.NoError(t, err)
+
+		require.Equal(t, []string{"base"}, profiles.SourceChainNames(profiles.FindProfileInCredentialsFile("base")))
+	})
+
+	t.Run("return names from given profile to profile providing base credentials", func(t *testing.T) {
+		credentialsFile := ini.Empty()
+		AddCredentialsSection(credentialsFile, "base")
+		configFile := ini.Empty()
+		AddChainedConfigSection(configFile, "profile a", "base")
+		AddChainedConfigSection(configFile, "profile b", "a")
+		profiles, err := LoadProfilesFromConfigAndCredentials(credentialsFile, configFile)
+		require.NoError(t, err)
+
+		require.Equal(t, []string{"profile b", "profile a", "base"}, profiles.SourceChainNames(profiles.FindProfileInConfigFile("profile b")))
+	})
+
+	t.Run("end with source profile name when it is not loaded", func(t *testing.T) {
+		configFile := ini.Empty()
+		AddChainedConfigSection(configFile, "profile a", "not-loaded")
+		profiles, err := LoadProfilesFromConfigAndCredentials(nil, configFile)
+		require.NoError(t, err)
+
+		require.Equal(t, []string{"profile a", "not-loaded"}, profiles.SourceChainNames(profiles.FindProfileInConfigFile("profile a")))
+	})
+
+	t.Run("end with source profile name when profile references itself", func(t *testing.T) {
+		configFile := ini.Empty()
+		AddChainedConfigSection(configFile, "profile a", "a")
+		profiles, err := LoadProfilesFromConfigAndCredentials(nil, configFile)
+		require.NoError(t, err)
+
+		require.Equal(t, []string{"profile a", "a"}, profiles.SourceChainNames(profiles.FindProfileInConfigFile("profile a")))
+	})
+
+	t.Run("stop when source profiles form a cycle", func(t *testing.T) {
+		configFile := ini.Empty()
+		AddChainedConfigSection(configFile, "profile a", "b")
+		AddChainedConfigSection(configFile, "profile b", "a")
+		profiles, err := LoadProfilesFromConfigAndCredentials(nil, configFile)
+		require.NoError(t, err)
+
+		require.Equal(t, []string{"profile a", "profile b", "profile a"}, profiles.SourceChainNames(profiles.FindProfileInConfigFile("profile a")))
+	})
+}
@@ -6,14 +6,59 @@ import (
 	"strings"
 )
 
-func LoadProfilesFromConfigAndCredentials(credentialsFile *ini.File, configFile *ini.File) Profiles {
-	return Profiles{
-		CredentialsProfiles:   loadFromCredentialsFile(credentialsFile),
-		ConfigAssumedProfiles: loadFromConfigFile(configFile),
+const ssoSessionSectionPrefix = "sso-session "
+
+// LoadProfilesFromConfigAndCredentials returns error when a profile has invalid value that would be ignored silently
+// otherwise
+func LoadProfilesFromConfigAndCredentials(credentialsFile *ini.File, configFile *ini.File) (Profiles, error) {
+	assumedProfiles, err := loadFromConfigFile(configFile)
+	if err != nil {
+		return Profiles{}, err
+	}
+
+	webIdentityProfiles, err := loadWebIdentityProfilesFromConfigFile(configFile)
+	if err != nil {
+		return Profiles{}, err
+	}
+
+	profiles := Profiles{
+		CredentialsProfiles:       loadFromCredentialsFile(credentialsFile, configFile),
+		ConfigAssumedProfiles:     assumedProfiles,
+		ConfigSSOProfiles:         loadSSOProfilesFromConfigFile(configFile),
+		ConfigProcessProfiles:     loadProcessProfilesFromConfigFile(configFile),
+		ConfigWebIdentityProfiles: webIdentityProfiles,
+	}
+	profiles.OtherSectionNames = loadOtherSectionNames(profiles, credentialsFile, configFile)
+
+	return profiles, nil
+}
+
+// loadOtherSectionNames returns names of sections not loaded as profiles, without "profile " prefix of config file
+// sections, the same way they are referred to by source_profile
+func loadOtherSectionNames(profiles Profiles, credentialsFile *ini.File, configFile *ini.File) []string {
+	var names []string
+
+	for _, file := range []*ini.File{credentialsFile, configFile} {
+		if file == nil {
+			continue
+		}
+
+		for _, section := range file.Sections() {
+			if (section.Name() == ini.DefaultSection && len(section.Keys()) == 0) ||
+				strings.HasPrefix(section.Name(), ssoSessionSectionPrefix) ||
+				(file == credentialsFile && isMFASessionSection(credentialsFile, configFile, section)) ||
+				findProfileByName(profiles.all(), section.Name()) != nil {
+				continue
+			}
+
+			names = append(names, trimProfilePrefix(section.Name()))
+		}
 	}
+
+	return names
 }
 
-func loadFromCredentialsFile(credentialsFile *ini.File) []Profile {
+func loadFromCredentialsFile(credentialsFile *ini.File, configFile *ini.File) []Profile {
 	var profiles []Profile
 
 	if credentialsFile == nil {
@@ -21,10 +66,12 @@ func loadFromCredentialsFile(credentialsFile *ini.File) []Profile {
 	}
 
 	for _, section := range credentialsFile.Sections() {
-		if !strings.EqualFold(section.Name(), "default") {
+		if !strings.EqualFold(section.Name(), "default") && !isMFASessionSection(credentialsFile, configFile, section) {
 			profiles = append(profiles, Profile{
 				ProfileName:        section.Name(),
 				DisplayProfileName: section.Name(),
+				MFASerialNumber:    credentialsProfileMFASerial(credentialsFile, configFile, section.Name()),
+				OriginSection:      section.Name(),
 			})
 		}
 	}
@@ -32,22 +79,29 @@ func loadFromCredentialsFile(credentialsFile *ini.File) []Profile {
 	return profiles
 }
 
-func loadFromConfigFile(configFile *ini.File) []Profile {
+func loadFromConfigFile(configFile *ini.File) ([]Profile, error) {
 	var profiles []Profile
 
 	if configFile == nil {
-		return profiles
+		return profiles, nil
 	}
 
 	for _, section := range configFile.Sections() {
 		if !strings.EqualFold(section.Name(), "default") &&
 			section.HasKey("role_arn") &&
-			section.HasKey("source_profile") {
+			(section.HasKey("source_profile") || section.HasKey("credential_source")) {
 			profile := Profile{
 				ProfileName:        section.Name(),
 				DisplayProfileName: fmt.Sprintf("assume %s", section.Name()),
 				RoleArn:            section.Key("role_arn").Value(),
-				SourceProfile:      section.Key("source_profile").Value(),
+				OriginSection:      section.Name(),
+			}
+
+			// source_profile takes precedence when both are set
+			if section.HasKey("source_profile") {
+				profile.SourceProfile = section.Key("source_profile").Value()
+			} else {
+				profile.CredentialSource = section.Key("credential_source").Value()
 			}
 
 			if section.HasKey("mfa_serial") {
@@ -58,9 +112,189 @@ func loadFromConfigFile(configFile *ini.File) []Profile {
 				profile.Region = section.Key("region").Value()
 			}
 
+			if section.HasKey("external_id") {
+				profile.ExternalId = section.Key("external_id").Value()
+			}
+
+			if section.HasKey("role_session_name") {
+				profile.RoleSessionName = section.Key("role_session_name").Value()
+			}
+
+			durationSeconds, err := durationSecondsOf(section)
+			if err != nil {
+				return nil, err
+			}
+			profile.DurationSeconds = durationSeconds
+
 			profiles = append(profiles, profile)
 		}
 	}
 
+	return profiles, nil
+}
+
+func loadSSOProfilesFromConfigFile(configFile *ini.File) []Profile {
+	var profiles []Profile
+
+	if configFile == nil {
+		return profiles
+	}
+
+	for _, section := range configFile.Sections() {
+		if strings.EqualFold(section.Name(), "default") ||
+			!section.HasKey("sso_account_id") ||
+			!section.HasKey("sso_role_name") {
+			continue
+		}
+
+		profile := Profile{
+			ProfileName:        section.Name(),
+			DisplayProfileName: fmt.Sprintf("sso %s", section.Name()),
+			SSOAccountId:       section.Key("sso_account_id").Value(),
+			SSORoleName:        section.Key("sso_role_name").Value(),
+			SSOStartUrl:        valueOf(section, "sso_start_url"),
+			SSORegion:          valueOf(section, "sso_region"),
+			Region:             valueOf(section, "region"),
+			OriginSection:      section.Name(),
+		}
+
+		// newer AWS CLI configuration keeps start url and region in a shared [sso-session name] section
+		if section.HasKey("sso_session") {
+			profile.SSOSession = section.Key("sso_session").Value()
+
+			if sessionSection, err := configFile.GetSection(ssoSessionSectionPrefix + profile.SSOSession); err == nil {
+				profile.SSOStartUrl = valueOf(sessionSection, "sso_start_url")
+				profile.SSORegion = valueOf(sessionSection, "sso_region")
+			}
+		}
+
+		if profile.SSOStartUrl == "" {
+			continue
+		}
+
+		profiles = append(profiles, profile)
+	}
+
 	return profiles
 }
+
+func loadProcessProfilesFromConfigFile(configFile *ini.File) []Profile {
+	var profiles []Profile
+
+	if configFile == nil {
+		return profiles
+	}
+
+	for _, section := range configFile.Sections() {
+		// profiles with role_arn assume role instead of using credential_process directly
+		if strings.EqualFold(section.Name(), "default") ||
+			section.HasKey("role_arn") ||
+			valueOf(section, "credential_process") == "" {
+			continue
+		}
+
+		profiles = append(profiles, Profile{
+			ProfileName:        section.Name(),
+			DisplayProfileName: fmt.Sprintf("process %s", section.Name()),
+			CredentialProcess:  section.Key("credential_process").Value(),
+			Region:             valueOf(section, "region"),
+			OriginSection:      section.Name(),
+		})
+	}
+
+	return profiles
+}
+
+func loadWebIdentityProfilesFromConfigFile(configFile *ini.File) ([]Profile, error) {
+	var profiles []Profile
+
+	if configFile == nil {
+		return profiles, nil
+	}
+
+	for _, section := range configFile.Sections() {
+		// source_profile and credential_source take precedence over web_identity_token_file
+		if strings.EqualFold(section.Name(), "default") ||
+			!section.HasKey("role_arn") ||
+			!section.HasKey("web_identity_token_file") ||
+			section.HasKey("source_profile") ||
+			section.HasKey("credential_source") {
+			continue
+		}
+
+		profile := Profile{
+			ProfileName:          section.Name(),
+			DisplayProfileName:   fmt.Sprintf("web-identity %s", section.Name()),
+			RoleArn:              section.Key("role_arn").Value(),
+			WebIdentityTokenFile: section.Key("web_identity_token_file").Value(),
+			Region:               valueOf(section, "region"),
+			RoleSessionName:      valueOf(section, "role_session_name"),
+			OriginSection:        section.Name(),
+		}
+
+		durationSeconds, err := durationSecondsOf(section)
+		if err != nil {
+			return nil, err
+		}
+		profile.DurationSeconds = durationSeconds
+
+		profiles = append(profiles, profile)
+	}
+
+	return profiles, nil
+}
+
+// durationSecondsOf returns 0 when duration_seconds is not set, default duration is used instead
+func durationSecondsOf(section *ini.Section) (int, error) {
+	if !section.HasKey("duration_seconds") {
+		return 0, nil
+	}
+
+	durationSeconds, err := section.Key("duration_seconds").Int()
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration_seconds [%s] of [%s], it must be a number of seconds", section.Key("duration_seconds").Value(), section.Name())
+	}
+
+	return durationSeconds, nil
+}
+
+// MFA session sections are managed by aws-profile, they are used in place of the profile they are derived from
+func isMFASessionSection(credentialsFile *ini.File, configFile *ini.File, section *ini.Section) bool {
+	if !strings.HasSuffix(section.Name(), mfaSessionSuffix) {
+		return false
+	}
+
+	sourceName := strings.TrimSuffix(section.Name(), mfaSessionSuffix)
+	if _, err := credentialsFile.GetSection(sourceName); err != nil {
+		return false
+	}
+
+	return credentialsProfileMFASerial(credentialsFile, configFile, sourceName) != ""
+}
+
+// mfa_serial of credentials file profile is usually kept in its [profile name] section in config file, value in
+// credentials file takes precedence like in AWS CLI
+func credentialsProfileMFASerial(credentialsFile *ini.File, configFile *ini.File, profileName string) string {
+	if section, err := credentialsFile.GetSection(profileName); err == nil && valueOf(section, "mfa_serial") != "" {
+		return valueOf(section, "mfa_serial")
+	}
+
+	if configFile == nil {
+		return ""
+	}
+
+	if section, err := configFile.GetSection("profile " + profileName); err == nil {
+		return valueOf(section, "mfa_serial")
+	}
+
+	return ""
+}
+
+// section.Key() creates the key when it doesn't exist, use this to read without modifying the file
+func valueOf(section *ini.Section, key string) string {
+	if !section.HasKey(key) {
+		return ""
+	}
+
+	return section.Key(key).Value()
+}
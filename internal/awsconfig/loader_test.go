@@ -20,13 +20,23 @@ func AddConfigSection(file *ini.File, sectionName string) *ini.Section {
 	return section
 }
 
+func AddSSOConfigSection(file *ini.File, sectionName string) *ini.Section {
+	section, _ := file.NewSection(sectionName)
+	section.Key("sso_start_url").SetValue("https://" + sectionName + ".awsapps.com/start")
+	section.Key("sso_region").SetValue("us-east-1")
+	section.Key("sso_account_id").SetValue(sectionName + "-account-id")
+	section.Key("sso_role_name").SetValue(sectionName + "-role-name")
+	return section
+}
+
 func TestLoadProfilesFromConfigAndCredentials(t *testing.T) {
 	t.Run("return non default profiles from credentials file", func(t *testing.T) {
 		credentialsFile := ini.Empty()
 		AddCredentialsSection(credentialsFile, "default")
 		AddCredentialsSection(credentialsFile, "profile-1")
 
-		result := LoadProfilesFromConfigAndCredentials(credentialsFile, nil)
+		result, err := LoadProfilesFromConfigAndCredentials(credentialsFile, nil)
+		require.NoError(t, err)
 
 		require.Equal(t, 0, len(result.ConfigAssumedProfiles))
 
@@ -42,7 +52,8 @@ func TestLoadProfilesFromConfigAndCredentials(t *testing.T) {
 		profile1Section.Key("some_attribute").SetValue("some-value")
 		AddConfigSection(configFile, "profile-2")
 
-		result := LoadProfilesFromConfigAndCredentials(nil, configFile)
+		result, err := LoadProfilesFromConfigAndCredentials(nil, configFile)
+		require.NoError(t, err)
 
 		require.Equal(t, 0, len(result.CredentialsProfiles))
 
@@ -60,7 +71,8 @@ func TestLoadProfilesFromConfigAndCredentials(t *testing.T) {
 		profile2Section := AddConfigSection(configFile, "profile-2")
 		profile2Section.Key("mfa_serial").SetValue("12345")
 
-		result := LoadProfilesFromConfigAndCredentials(nil, configFile)
+		result, err := LoadProfilesFromConfigAndCredentials(nil, configFile)
+		require.NoError(t, err)
 		configProfiles := result.ConfigAssumedProfiles
 
 		require.Equal(t, 2, len(configProfiles))
@@ -76,7 +88,8 @@ func TestLoadProfilesFromConfigAndCredentials(t *testing.T) {
 		profile2Section := AddConfigSection(configFile, "profile-2")
 		profile2Section.Key("region").SetValue("ap-southeast-2")
 
-		result := LoadProfilesFromConfigAndCredentials(nil, configFile)
+		result, err := LoadProfilesFromConfigAndCredentials(nil, configFile)
+		require.NoError(t, err)
 		configProfiles := result.ConfigAssumedProfiles
 
 		require.Equal(t, 2, len(configProfiles))
@@ -84,4 +97,255 @@ func TestLoadProfilesFromConfigAndCredentials(t *testing.T) {
 		require.Equal(t, "profile-2-role-arn", configProfiles[1].RoleArn)
 		require.Equal(t, "ap-southeast-2", configProfiles[1].Region)
 	})
+
+	t.Run("return external id, role session name and duration seconds with config profiles if available", func(t *testing.T) {
+		configFile := ini.Empty()
+		AddConfigSection(configFile, "profile-1")
+		profile2Section := AddConfigSection(configFile, "profile-2")
+		profile2Section.Key("external_id").SetValue("some-external-id")
+		profile2Section.Key("role_session_name").SetValue("some-session-name")
+		profile2Section.Key("duration_seconds").SetValue("3600")
+
+		result, err := LoadProfilesFromConfigAndCredentials(nil, configFile)
+		require.NoError(t, err)
+		configProfiles := result.ConfigAssumedProfiles
+
+		require.Equal(t, 2, len(configProfiles))
+		require.Empty(t, configProfiles[0].ExternalId)
+		require.Empty(t, configProfiles[0].RoleSessionName)
+		require.Equal(t, 0, configProfiles[0].DurationSeconds)
+		require.Equal(t, "some-external-id", configProfiles[1].ExternalId)
+		require.Equal(t, "some-session-name", configProfiles[1].RoleSessionName)
+		require.Equal(t, 3600, configProfiles[1].DurationSeconds)
+	})
+
+	t.Run("return error when duration seconds is not a number", func(t *testing.T) {
+		configFile := ini.Empty()
+		AddConfigSection(configFile, "profile-1").Key("duration_seconds").SetValue("1h")
+
+		_, err := LoadProfilesFromConfigAndCredentials(nil, configFile)
+
+		require.Error(t, err)
+		require.Equal(t, "invalid duration_seconds [1h] of [profile-1], it must be a number of seconds", err.Error())
+	})
+
+	t.Run("return error when duration seconds of web identity profile is not a number", func(t *testing.T) {
+		configFile := ini.Empty()
+		section, _ := configFile.NewSection("profile web-identity")
+		section.Key("role_arn").SetValue("web-identity-role-arn")
+		section.Key("web_identity_token_file").SetValue("/var/run/token")
+		section.Key("duration_seconds").SetValue("30m")
+
+		_, err := LoadProfilesFromConfigAndCredentials(nil, configFile)
+
+		require.Error(t, err)
+		require.Equal(t, "invalid duration_seconds [30m] of [profile web-identity], it must be a number of seconds", err.Error())
+	})
+
+	t.Run("return profiles with role arn and credential source from config file", func(t *testing.T) {
+		configFile := ini.Empty()
+		profile1Section, _ := configFile.NewSection("profile-1")
+		profile1Section.Key("role_arn").SetValue("profile-1-role-arn")
+		profile1Section.Key("credential_source").SetValue("Ec2InstanceMetadata")
+		profile2Section, _ := configFile.NewSection("profile-2")
+		profile2Section.Key("credential_source").SetValue("Environment")
+
+		result, err := LoadProfilesFromConfigAndCredentials(nil, configFile)
+		require.NoError(t, err)
+		configProfiles := result.ConfigAssumedProfiles
+
+		require.Equal(t, 1, len(configProfiles))
+		require.Equal(t, "profile-1", configProfiles[0].ProfileName)
+		require.Equal(t, "assume profile-1", configProfiles[0].DisplayProfileName)
+		require.Equal(t, "profile-1-role-arn", configProfiles[0].RoleArn)
+		require.Equal(t, "Ec2InstanceMetadata", configProfiles[0].CredentialSource)
+		require.Empty(t, configProfiles[0].SourceProfile)
+	})
+
+	t.Run("ignore credential source if source profile is also set", func(t *testing.T) {
+		configFile := ini.Empty()
+		profile1Section := AddConfigSection(configFile, "profile-1")
+		profile1Section.Key("credential_source").SetValue("Environment")
+
+		result, err := LoadProfilesFromConfigAndCredentials(nil, configFile)
+		require.NoError(t, err)
+		configProfiles := result.ConfigAssumedProfiles
+
+		require.Equal(t, 1, len(configProfiles))
+		require.Equal(t, "profile-1-source-profile", configProfiles[0].SourceProfile)
+		require.Empty(t, configProfiles[0].CredentialSource)
+	})
+
+	t.Run("return sso profiles from config file with sso start url, account id and role name", func(t *testing.T) {
+		configFile := ini.Empty()
+		AddConfigSection(configFile, "default")
+		AddSSOConfigSection(configFile, "profile sso-1")
+		ssoWithRegionSection := AddSSOConfigSection(configFile, "profile sso-2")
+		ssoWithRegionSection.Key("region").SetValue("ap-southeast-2")
+
+		result, err := LoadProfilesFromConfigAndCredentials(nil, configFile)
+		require.NoError(t, err)
+
+		require.Equal(t, 0, len(result.ConfigAssumedProfiles))
+
+		ssoProfiles := result.ConfigSSOProfiles
+		require.Equal(t, 2, len(ssoProfiles))
+		require.Equal(t, "profile sso-1", ssoProfiles[0].ProfileName)
+		require.Equal(t, "sso profile sso-1", ssoProfiles[0].DisplayProfileName)
+		require.Equal(t, "https://profile sso-1.awsapps.com/start", ssoProfiles[0].SSOStartUrl)
+		require.Equal(t, "us-east-1", ssoProfiles[0].SSORegion)
+		require.Equal(t, "profile sso-1-account-id", ssoProfiles[0].SSOAccountId)
+		require.Equal(t, "profile sso-1-role-name", ssoProfiles[0].SSORoleName)
+		require.Equal(t, "ap-southeast-2", ssoProfiles[1].Region)
+	})
+
+	t.Run("return sso profiles with start url and region from referenced sso-session section", func(t *testing.T) {
+		configFile := ini.Empty()
+		sessionSection, _ := configFile.NewSection("sso-session my-sso")
+		sessionSection.Key("sso_start_url").SetValue("https://my-sso.awsapps.com/start")
+		sessionSection.Key("sso_region").SetValue("eu-west-1")
+		profileSection, _ := configFile.NewSection("profile sso-1")
+		profileSection.Key("sso_session").SetValue("my-sso")
+		profileSection.Key("sso_account_id").SetValue("123456789012")
+		profileSection.Key("sso_role_name").SetValue("ReadOnly")
+
+		result, err := LoadProfilesFromConfigAndCredentials(nil, configFile)
+		require.NoError(t, err)
+
+		ssoProfiles := result.ConfigSSOProfiles
+		require.Equal(t, 1, len(ssoProfiles))
+		require.Equal(t, "profile sso-1", ssoProfiles[0].ProfileName)
+		require.Equal(t, "my-sso", ssoProfiles[0].SSOSession)
+		require.Equal(t, "https://my-sso.awsapps.com/start", ssoProfiles[0].SSOStartUrl)
+		require.Equal(t, "eu-west-1", ssoProfiles[0].SSORegion)
+	})
+
+	t.Run("ignore sso profiles without start url", func(t *testing.T) {
+		configFile := ini.Empty()
+		profileSection, _ := configFile.NewSection("profile sso-1")
+		profileSection.Key("sso_session").SetValue("not-exists")
+		profileSection.Key("sso_account_id").SetValue("123456789012")
+		profileSection.Key("sso_role_name").SetValue("ReadOnly")
+
+		result, err := LoadProfilesFromConfigAndCredentials(nil, configFile)
+		require.NoError(t, err)
+
+		require.Equal(t, 0, len(result.ConfigSSOProfiles))
+		require.False(t, profileSection.HasKey("sso_start_url"))
+	})
+
+	t.Run("return credential process profiles from config file", func(t *testing.T) {
+		configFile := ini.Empty()
+		defaultSection, _ := configFile.NewSection("default")
+		defaultSection.Key("credential_process").SetValue("default-process")
+		processSection, _ := configFile.NewSection("profile process-1")
+		processSection.Key("credential_process").SetValue("/usr/local/bin/vault-creds --role admin")
+		processSection.Key("region").SetValue("ap-southeast-2")
+		assumedSection := AddConfigSection(configFile, "profile assumed-1")
+		assumedSection.Key("credential_process").SetValue("ignored-process")
+
+		result, err := LoadProfilesFromConfigAndCredentials(nil, configFile)
+		require.NoError(t, err)
+
+		processProfiles := result.ConfigProcessProfiles
+		require.Equal(t, 1, len(processProfiles))
+		require.Equal(t, "profile process-1", processProfiles[0].ProfileName)
+		require.Equal(t, "process profile process-1", processProfiles[0].DisplayProfileName)
+		require.Equal(t, "/usr/local/bin/vault-creds --role admin", processProfiles[0].CredentialProcess)
+		require.Equal(t, "ap-southeast-2", processProfiles[0].Region)
+		require.Equal(t, 1, len(result.ConfigAssumedProfiles))
+	})
+
+	t.Run("return web identity profiles from config file", func(t *testing.T) {
+		configFile := ini.Empty()
+		webIdentitySection, _ := configFile.NewSection("profile eks")
+		webIdentitySection.Key("role_arn").SetValue("eks-role-arn")
+		webIdentitySection.Key("web_identity_token_file").SetValue("/var/run/secrets/eks.amazonaws.com/serviceaccount/token")
+		webIdentitySection.Key("role_session_name").SetValue("eks-session")
+		webIdentitySection.Key("duration_seconds").SetValue("1800")
+		assumedSection := AddConfigSection(configFile, "profile assumed-1")
+		assumedSection.Key("web_identity_token_file").SetValue("ignored-token-file")
+
+		result, err := LoadProfilesFromConfigAndCredentials(nil, configFile)
+		require.NoError(t, err)
+
+		webIdentityProfiles := result.ConfigWebIdentityProfiles
+		require.Equal(t, 1, len(webIdentityProfiles))
+		require.Equal(t, "profile eks", webIdentityProfiles[0].ProfileName)
+		require.Equal(t, "web-identity profile eks", webIdentityProfiles[0].DisplayProfileName)
+		require.Equal(t, "eks-role-arn", webIdentityProfiles[0].RoleArn)
+		require.Equal(t, "/var/run/secrets/eks.amazonaws.com/serviceaccount/token", webIdentityProfiles[0].WebIdentityTokenFile)
+		require.Equal(t, "eks-session", webIdentityProfiles[0].RoleSessionName)
+		require.Equal(t, 1800, webIdentityProfiles[0].DurationSeconds)
+		require.Equal(t, 1, len(result.ConfigAssumedProfiles))
+	})
+
+	t.Run("return section name of each profile as origin section", func(t *testing.T) {
+		credentialsFile := ini.Empty()
+		AddCredentialsSection(credentialsFile, "credentials-1")
+		configFile := ini.Empty()
+		AddConfigSection(configFile, "profile assumed-1")
+		AddSSOConfigSection(configFile, "profile sso-1")
+
+		result, err := LoadProfilesFromConfigAndCredentials(credentialsFile, configFile)
+		require.NoError(t, err)
+
+		require.Equal(t, "credentials-1", result.CredentialsProfiles[0].OriginSection)
+		require.Equal(t, "profile assumed-1", result.ConfigAssumedProfiles[0].OriginSection)
+		require.Equal(t, "profile sso-1", result.ConfigSSOProfiles[0].OriginSection)
+	})
+
+	t.Run("return mfa serial of credentials profiles", func(t *testing.T) {
+		credentialsFile := ini.Empty()
+		AddCredentialsSection(credentialsFile, "iam-user").Key("mfa_serial").SetValue("arn:aws:iam::123456789012:mfa/user")
+
+		result, err := LoadProfilesFromConfigAndCredentials(credentialsFile, nil)
+		require.NoError(t, err)
+
+		require.Equal(t, "arn:aws:iam::123456789012:mfa/user", result.CredentialsProfiles[0].MFASerialNumber)
+	})
+
+	t.Run("return mfa serial of credentials profiles from matching config file profile", func(t *testing.T) {
+		credentialsFile := ini.Empty()
+		AddCredentialsSection(credentialsFile, "iam-user")
+		AddCredentialsSection(credentialsFile, "iam-user-mfa")
+		configFile := ini.Empty()
+		configFile.Section("profile iam-user").Key("mfa_serial").SetValue("arn:aws:iam::123456789012:mfa/user")
+
+		result, err := LoadProfilesFromConfigAndCredentials(credentialsFile, configFile)
+		require.NoError(t, err)
+
+		require.Len(t, result.CredentialsProfiles, 1)
+		require.Equal(t, "arn:aws:iam::123456789012:mfa/user", result.CredentialsProfiles[0].MFASerialNumber)
+		require.True(t, result.CredentialsProfiles[0].UsesMFASession())
+	})
+
+	t.Run("prefer mfa serial in credentials file over mfa serial in config file", func(t *testing.T) {
+		credentialsFile := ini.Empty()
+		AddCredentialsSection(credentialsFile, "iam-user").Key("mfa_serial").SetValue("credentials-mfa-serial")
+		configFile := ini.Empty()
+		configFile.Section("profile iam-user").Key("mfa_serial").SetValue("config-mfa-serial")
+
+		result, err := LoadProfilesFromConfigAndCredentials(credentialsFile, configFile)
+		require.NoError(t, err)
+
+		require.Equal(t, "credentials-mfa-serial", result.CredentialsProfiles[0].MFASerialNumber)
+	})
+
+	t.Run("exclude mfa session sections of credentials profiles with mfa serial", func(t *testing.T) {
+		credentialsFile := ini.Empty()
+		AddCredentialsSection(credentialsFile, "iam-user").Key("mfa_serial").SetValue("arn:aws:iam::123456789012:mfa/user")
+		AddCredentialsSection(credentialsFile, "iam-user-mfa")
+		AddCredentialsSection(credentialsFile, "other")
+		AddCredentialsSection(credentialsFile, "other-mfa")
+
+		result, err := LoadProfilesFromConfigAndCredentials(credentialsFile, nil)
+		require.NoError(t, err)
+
+		var names []string
+		for _, profile := range result.CredentialsProfiles {
+			names = append(names, profile.ProfileName)
+		}
+		require.Equal(t, []string{"iam-user", "other", "other-mfa"}, names)
+	})
 }
@@ -0,0 +1,68 @@
+package awsconfig
+
+import (
+	"sort"
+	"strings"
+)
+
+type PreferenceGroup int
+
+const (
+	FavouriteGroup PreferenceGroup = iota
+	RecentGroup
+	OtherGroup
+)
+
+// Preference orders profiles by favourites set in aws-profile config, then by most recently used profiles.
+// Profile names may be given with or without "profile " prefix of config file sections
+type Preference struct {
+	Favourites []string
+	Recent     []string
+}
+
+func (preference Preference) Group(profile Profile) PreferenceGroup {
+	if indexOfProfileName(preference.Favourites, profile.ProfileName) >= 0 {
+		return FavouriteGroup
+	}
+
+	if indexOfProfileName(preference.Recent, profile.ProfileName) >= 0 {
+		return RecentGroup
+	}
+
+	return OtherGroup
+}
+
+// Order returns favourites in the order they are configured, then recently used profiles with the most recent
+// first, then the remaining profiles alphabetically
+func (preference Preference) Order(profiles []Profile) []Profile {
+	ordered := make([]Profile, len(profiles))
+	copy(ordered, profiles)
+
+	sort.SliceStable(ordered, func(i, j int) bool {
+		groupI, groupJ := preference.Group(ordered[i]), preference.Group(ordered[j])
+		if groupI != groupJ {
+			return groupI < groupJ
+		}
+
+		switch groupI {
+		case FavouriteGroup:
+			return indexOfProfileName(preference.Favourites, ordered[i].ProfileName) < indexOfProfileName(preference.Favourites, ordered[j].ProfileName)
+		case RecentGroup:
+			return indexOfProfileName(preference.Recent, ordered[i].ProfileName) < indexOfProfileName(preference.Recent, ordered[j].ProfileName)
+		default:
+			return strings.ToLower(trimProfilePrefix(ordered[i].ProfileName)) < strings.ToLower(trimProfilePrefix(ordered[j].ProfileName))
+		}
+	})
+
+	return ordered
+}
+
+func indexOfProfileName(names []string, profileName string) int {
+	for i, name := range names {
+		if strings.EqualFold(trimProfilePrefix(name), trimProfilePrefix(profileName)) {
+			return i
+		}
+	}
+
+	return -1
+}
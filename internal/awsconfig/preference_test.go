@@ -0,0 +1,58 @@
+package awsconfig
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func stubProfilesNamed(names ...string) []Profile {
+	var profiles []Profile
+	for _, name := range names {
+		profiles = append(profiles, Profile{ProfileName: name})
+	}
+	return profiles
+}
+
+func TestPreferenceOrder(t *testing.T) {
+	t.Run("order favourites first, then recently used, then remaining profiles alphabetically", func(t *testing.T) {
+		preference := Preference{
+			Favourites: []string{"prod", "staging"},
+			Recent:     []string{"profile sandbox", "profile dev", "profile prod"},
+		}
+		profiles := stubProfilesNamed("profile zeta", "profile dev", "profile staging", "Alpha", "profile sandbox", "profile prod")
+
+		result := preference.Order(profiles)
+
+		require.Equal(t,
+			[]string{"profile prod", "profile staging", "profile sandbox", "profile dev", "Alpha", "profile zeta"},
+			profileNames(result))
+	})
+
+	t.Run("not modify given profiles", func(t *testing.T) {
+		profiles := stubProfilesNamed("b", "a")
+
+		_ = Preference{}.Order(profiles)
+
+		require.Equal(t, []string{"b", "a"}, profileNames(profiles))
+	})
+}
+
+func TestPreferenceGroup(t *testing.T) {
+	preference := Preference{
+		Favourites: []string{"profile prod"},
+		Recent:     []string{"dev"},
+	}
+
+	t.Run("return favourite group for favourite given with or without profile prefix", func(t *testing.T) {
+		require.Equal(t, FavouriteGroup, preference.Group(Profile{ProfileName: "prod"}))
+		require.Equal(t, FavouriteGroup, preference.Group(Profile{ProfileName: "profile prod"}))
+	})
+
+	t.Run("return recent group for recently used profile", func(t *testing.T) {
+		require.Equal(t, RecentGroup, preference.Group(Profile{ProfileName: "profile dev"}))
+	})
+
+	t.Run("return other group for remaining profiles", func(t *testing.T) {
+		require.Equal(t, OtherGroup, preference.Group(Profile{ProfileName: "profile staging"}))
+	})
+}
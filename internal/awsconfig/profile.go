@@ -1,10 +1,71 @@
 package awsconfig
 
+import "strings"
+
+const mfaSessionSuffix = "-mfa"
+
 type Profile struct {
-	ProfileName        string
-	DisplayProfileName string
-	RoleArn            string
-	MFASerialNumber    string
-	Region             string
-	SourceProfile      string
+	ProfileName          string
+	DisplayProfileName   string
+	RoleArn              string
+	MFASerialNumber      string
+	Region               string
+	SourceProfile        string
+	CredentialSource     string
+	ExternalId           string
+	RoleSessionName      string
+	DurationSeconds      int
+	SSOStartUrl          string
+	SSORegion            string
+	SSOAccountId         string
+	SSORoleName          string
+	SSOSession           string
+	CredentialProcess    string
+	WebIdentityTokenFile string
+	OriginFile           string
+	OriginSection        string
+}
+
+func (profile Profile) IsSSO() bool {
+	return profile.SSOAccountId != "" && profile.SSORoleName != ""
+}
+
+func (profile Profile) IsAssumed() bool {
+	return profile.RoleArn != "" && profile.SourceProfile != ""
+}
+
+func (profile Profile) IsCredentialProcess() bool {
+	return profile.CredentialProcess != ""
+}
+
+func (profile Profile) IsWebIdentity() bool {
+	return profile.RoleArn != "" && profile.WebIdentityTokenFile != ""
+}
+
+// UsesMFASession is true for credentials file profiles with mfa_serial, their long-term keys are only used to get MFA
+// session credentials with GetSessionToken
+func (profile Profile) UsesMFASession() bool {
+	return profile.MFASerialNumber != "" &&
+		profile.RoleArn == "" &&
+		!profile.IsSSO() &&
+		!profile.IsCredentialProcess()
+}
+
+// MFASessionProfileName returns name of credentials file section keeping MFA session credentials of given profile
+func MFASessionProfileName(profileName string) string {
+	return profileName + mfaSessionSuffix
+}
+
+// AccountId returns account of SSO profile, or account parsed from role arn (arn:aws:iam::<account>:role/<name>)
+func (profile Profile) AccountId() string {
+	if profile.SSOAccountId != "" {
+		return profile.SSOAccountId
+	}
+
+	parts := strings.Split(profile.RoleArn, ":")
+	if len(parts) < 6 || parts[0] != "arn" {
+		return ""
+	}
+
+	return parts[4]
 }
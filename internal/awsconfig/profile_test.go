@@ -0,0 +1,44 @@
+package awsconfig
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestAccountId(t *testing.T) {
+	t.Run("return account of role arn", func(t *testing.T) {
+		profile := Profile{RoleArn: "arn:aws:iam::123456789012:role/admin"}
+
+		require.Equal(t, "123456789012", profile.AccountId())
+	})
+
+	t.Run("return sso account id of sso profile", func(t *testing.T) {
+		profile := Profile{SSOAccountId: "123456789012", SSORoleName: "ReadOnly"}
+
+		require.Equal(t, "123456789012", profile.AccountId())
+	})
+
+	t.Run("return empty if role arn is not an arn", func(t *testing.T) {
+		profile := Profile{RoleArn: "not-an-arn"}
+
+		require.Empty(t, profile.AccountId())
+	})
+}
+
+func TestUsesMFASession(t *testing.T) {
+	t.Run("return true for credentials profile with mfa serial", func(t *testing.T) {
+		profile := Profile{ProfileName: "iam-user", MFASerialNumber: "arn:aws:iam::123456789012:mfa/user"}
+
+		require.True(t, profile.UsesMFASession())
+	})
+
+	t.Run("return false for assumed profile with mfa serial", func(t *testing.T) {
+		profile := Profile{RoleArn: "arn:aws:iam::123456789012:role/admin", MFASerialNumber: "arn:aws:iam::123456789012:mfa/user"}
+
+		require.False(t, profile.UsesMFASession())
+	})
+
+	t.Run("return false for profile without mfa serial", func(t *testing.T) {
+		require.False(t, Profile{ProfileName: "iam-user"}.UsesMFASession())
+	})
+}
@@ -3,37 +3,66 @@ package awsconfig
 import "strings"
 
 type Profiles struct {
-	CredentialsProfiles   []Profile
-	ConfigAssumedProfiles []Profile
+	CredentialsProfiles       []Profile
+	ConfigAssumedProfiles     []Profile
+	ConfigSSOProfiles         []Profile
+	ConfigProcessProfiles     []Profile
+	ConfigWebIdentityProfiles []Profile
+	// names of other sections in either file, e.g. [default] or config file profiles with static keys. They are not
+	// selectable but can be source_profile of other profiles
+	OtherSectionNames []string
 }
 
 func (profiles Profiles) FindProfileInCredentialsFile(selected string) *Profile {
-	for _, profile := range profiles.CredentialsProfiles {
-		if strings.EqualFold(profile.ProfileName, selected) {
-			return &profile
-		}
-	}
-
-	return nil
+	return findProfileByName(profiles.CredentialsProfiles, selected)
 }
 
 func (profiles Profiles) FindProfileInConfigFile(selected string) *Profile {
-	for _, profile := range profiles.ConfigAssumedProfiles {
-		if strings.EqualFold(profile.ProfileName, selected) {
-			return &profile
-		}
+	return findProfileByName(profiles.ConfigAssumedProfiles, selected)
+}
+
+func (profiles Profiles) FindSSOProfileInConfigFile(selected string) *Profile {
+	return findProfileByName(profiles.ConfigSSOProfiles, selected)
+}
+
+func (profiles Profiles) FindProcessProfileInConfigFile(selected string) *Profile {
+	return findProfileByName(profiles.ConfigProcessProfiles, selected)
+}
+
+func (profiles Profiles) FindWebIdentityProfileInConfigFile(selected string) *Profile {
+	return findProfileByName(profiles.ConfigWebIdentityProfiles, selected)
+}
+
+// FindConfigFileProfile finds profile of any kind in config file
+func (profiles Profiles) FindConfigFileProfile(selected string) *Profile {
+	return findProfileByName(profiles.ConfigFileProfiles().all(), selected)
+}
+
+func (profiles Profiles) ConfigFileProfiles() Profiles {
+	return Profiles{
+		ConfigAssumedProfiles:     profiles.ConfigAssumedProfiles,
+		ConfigSSOProfiles:         profiles.ConfigSSOProfiles,
+		ConfigProcessProfiles:     profiles.ConfigProcessProfiles,
+		ConfigWebIdentityProfiles: profiles.ConfigWebIdentityProfiles,
+	}
+}
+
+// WithOriginFiles sets the path of the file each profile is loaded from, files are not known when profiles are loaded
+func (profiles Profiles) WithOriginFiles(credentialsFilePath string, configFilePath string) Profiles {
+	return Profiles{
+		CredentialsProfiles:       withOriginFile(profiles.CredentialsProfiles, credentialsFilePath),
+		ConfigAssumedProfiles:     withOriginFile(profiles.ConfigAssumedProfiles, configFilePath),
+		ConfigSSOProfiles:         withOriginFile(profiles.ConfigSSOProfiles, configFilePath),
+		ConfigProcessProfiles:     withOriginFile(profiles.ConfigProcessProfiles, configFilePath),
+		ConfigWebIdentityProfiles: withOriginFile(profiles.ConfigWebIdentityProfiles, configFilePath),
+		OtherSectionNames:         profiles.OtherSectionNames,
 	}
-	return nil
 }
 
 func (profiles Profiles) GetAllDisplayProfileNames() []string {
 	var displayProfileNames []string
 
-	for _, profile := range profiles.CredentialsProfiles {
-		displayProfileNames = append(displayProfileNames, profile.DisplayProfileName)
-	}
-
-	for _, profile := range profiles.ConfigAssumedProfiles {
+	for _, profile := range profiles.all() {
 		displayProfileNames = append(displayProfileNames, profile.DisplayProfileName)
 	}
 
@@ -43,17 +72,49 @@ func (profiles Profiles) GetAllDisplayProfileNames() []string {
 func (profiles Profiles) Filter(pattern string) []Profile {
 	var filteredProfiles []Profile
 
-	for _, profile := range profiles.CredentialsProfiles {
+	for _, profile := range profiles.all() {
 		if pattern == "" || strings.Contains(profile.ProfileName, pattern) {
 			filteredProfiles = append(filteredProfiles, profile)
 		}
 	}
 
-	for _, profile := range profiles.ConfigAssumedProfiles {
-		if pattern == "" || strings.Contains(profile.ProfileName, pattern) {
-			filteredProfiles = append(filteredProfiles, profile)
+	return filteredProfiles
+}
+
+// FilterByPreference returns profiles filtered by given pattern, ordered by given preference
+func (profiles Profiles) FilterByPreference(pattern string, preference Preference) []Profile {
+	return preference.Order(profiles.Filter(pattern))
+}
+
+func (profiles Profiles) all() []Profile {
+	var all []Profile
+
+	all = append(all, profiles.CredentialsProfiles...)
+	all = append(all, profiles.ConfigAssumedProfiles...)
+	all = append(all, profiles.ConfigSSOProfiles...)
+	all = append(all, profiles.ConfigProcessProfiles...)
+	all = append(all, profiles.ConfigWebIdentityProfiles...)
+
+	return all
+}
+
+func withOriginFile(profiles []Profile, originFile string) []Profile {
+	var result []Profile
+
+	for _, profile := range profiles {
+		profile.OriginFile = originFile
+		result = append(result, profile)
+	}
+
+	return result
+}
+
+func findProfileByName(profiles []Profile, selected string) *Profile {
+	for _, profile := range profiles {
+		if strings.EqualFold(profile.ProfileName, selected) {
+			return &profile
 		}
 	}
 
-	return filteredProfiles
+	return nil
 }
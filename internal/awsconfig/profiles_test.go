@@ -73,11 +73,117 @@ func TestFindProfileInConfigFile(t *testing.T) {
 	})
 }
 
+func TestFindSSOProfileInConfigFile(t *testing.T) {
+	t.Run("return nil if profile not found", func(t *testing.T) {
+		profiles := Profiles{
+			ConfigAssumedProfiles: StubProfiles(1, 2),
+			ConfigSSOProfiles:     StubProfiles(3, 4),
+		}
+
+		result := profiles.FindSSOProfileInConfigFile("profile-1")
+
+		require.Nil(t, result)
+	})
+
+	t.Run("return profile if found", func(t *testing.T) {
+		profiles := Profiles{
+			ConfigAssumedProfiles: StubProfiles(1, 2),
+			ConfigSSOProfiles:     StubProfiles(3, 4),
+		}
+
+		result := profiles.FindSSOProfileInConfigFile("profile-4")
+
+		require.NotNil(t, result)
+		require.Equal(t, result.ProfileName, "profile-4")
+	})
+}
+
+func TestFindProcessProfileInConfigFile(t *testing.T) {
+	t.Run("return nil if profile not found", func(t *testing.T) {
+		profiles := Profiles{
+			ConfigAssumedProfiles: StubProfiles(1, 2),
+			ConfigProcessProfiles: StubProfiles(3, 4),
+		}
+
+		result := profiles.FindProcessProfileInConfigFile("profile-1")
+
+		require.Nil(t, result)
+	})
+
+	t.Run("return profile if found", func(t *testing.T) {
+		profiles := Profiles{
+			ConfigAssumedProfiles: StubProfiles(1, 2),
+			ConfigProcessProfiles: StubProfiles(3, 4),
+		}
+
+		result := profiles.FindProcessProfileInConfigFile("profile-4")
+
+		require.NotNil(t, result)
+		require.Equal(t, result.ProfileName, "profile-4")
+	})
+}
+
+func TestFindWebIdentityProfileInConfigFile(t *testing.T) {
+	t.Run("return nil if profile not found", func(t *testing.T) {
+		profiles := Profiles{
+			ConfigAssumedProfiles:     StubProfiles(1, 2),
+			ConfigWebIdentityProfiles: StubProfiles(3, 4),
+		}
+
+		result := profiles.FindWebIdentityProfileInConfigFile("profile-1")
+
+		require.Nil(t, result)
+	})
+
+	t.Run("return profile if found", func(t *testing.T) {
+		profiles := Profiles{
+			ConfigAssumedProfiles:     StubProfiles(1, 2),
+			ConfigWebIdentityProfiles: StubProfiles(3, 4),
+		}
+
+		result := profiles.FindWebIdentityProfileInConfigFile("profile-4")
+
+		require.NotNil(t, result)
+		require.Equal(t, result.ProfileName, "profile-4")
+	})
+}
+
+func TestFindConfigFileProfile(t *testing.T) {
+	t.Run("return nil if profile is only in credentials file", func(t *testing.T) {
+		profiles := Profiles{
+			CredentialsProfiles:   StubProfiles(1, 2),
+			ConfigAssumedProfiles: StubProfiles(3, 4),
+		}
+
+		result := profiles.FindConfigFileProfile("profile-1")
+
+		require.Nil(t, result)
+	})
+
+	t.Run("return profile of any kind in config file", func(t *testing.T) {
+		profiles := Profiles{
+			ConfigAssumedProfiles:     StubProfiles(1, 1),
+			ConfigSSOProfiles:         StubProfiles(2, 2),
+			ConfigProcessProfiles:     StubProfiles(3, 3),
+			ConfigWebIdentityProfiles: StubProfiles(4, 4),
+		}
+
+		for _, name := range []string{"profile-1", "profile-2", "profile-3", "profile-4"} {
+			result := profiles.FindConfigFileProfile(name)
+
+			require.NotNil(t, result)
+			require.Equal(t, name, result.ProfileName)
+		}
+	})
+}
+
 func TestGetAllDisplayProfileNames(t *testing.T) {
 	t.Run("return profile names from both credentials and config files", func(t *testing.T) {
 		profiles := Profiles{
 			CredentialsProfiles:   StubProfiles(1, 2),
 			ConfigAssumedProfiles: StubProfiles(3, 3),
+			ConfigSSOProfiles:     StubProfiles(4, 4),
+			ConfigProcessProfiles: StubProfiles(5, 5),
 		}
 
 		result := profiles.GetAllDisplayProfileNames()
@@ -86,6 +192,8 @@ func TestGetAllDisplayProfileNames(t *testing.T) {
 			"profile-1 display name",
 			"profile-2 display name",
 			"profile-3 display name",
+			"profile-4 display name",
+			"profile-5 display name",
 		}
 		require.ElementsMatch(t, expected, result)
 
@@ -155,3 +263,20 @@ func TestFilter(t *testing.T) {
 
 	})
 }
+
+func TestWithOriginFiles(t *testing.T) {
+	t.Run("set credentials file path to credentials profiles and config file path to config profiles", func(t *testing.T) {
+		profiles := Profiles{
+			CredentialsProfiles:   StubProfiles(1, 1),
+			ConfigAssumedProfiles: StubProfiles(2, 2),
+			ConfigSSOProfiles:     StubProfiles(3, 3),
+		}
+
+		result := profiles.WithOriginFiles("/home/user/.aws/credentials", "/home/user/.aws/config")
+
+		require.Equal(t, "/home/user/.aws/credentials", result.CredentialsProfiles[0].OriginFile)
+		require.Equal(t, "/home/user/.aws/config", result.ConfigAssumedProfiles[0].OriginFile)
+		require.Equal(t, "/home/user/.aws/config", result.ConfigSSOProfiles[0].OriginFile)
+		require.Empty(t, profiles.CredentialsProfiles[0].OriginFile)
+	})
+}
@@ -1,11 +1,43 @@
 package awsconfig
 
 import (
+	"fmt"
 	"strings"
 
 	"gopkg.in/ini.v1"
 )
 
+var assumeRoleKeys = []string{
+	"role_arn",
+	"source_profile",
+	"credential_source",
+	"external_id",
+	"role_session_name",
+	"duration_seconds",
+}
+
+var ssoKeys = []string{
+	"sso_start_url",
+	"sso_region",
+	"sso_account_id",
+	"sso_role_name",
+	"sso_session",
+}
+
+var staticCredentialsKeys = []string{
+	"aws_access_key_id",
+	"aws_secret_access_key",
+	"aws_session_token",
+}
+
+var processKeys = []string{
+	"credential_process",
+}
+
+var webIdentityKeys = []string{
+	"web_identity_token_file",
+}
+
 func SetSelectedProfileAsDefault(selectedProfileName string, credentialsFile *ini.File, configFile *ini.File) {
 	selectedProfileInCredentials := credentialsFile.Section(selectedProfileName)
 	selectedKeyId := selectedProfileInCredentials.Key("aws_access_key_id").Value()
@@ -17,23 +49,63 @@ func SetSelectedProfileAsDefault(selectedProfileName string, credentialsFile *in
 	copyValueToDefaultProfileIfAvailable(defaultProfileInCredentials, selectedProfileInCredentials, "aws_session_token")
 
 	defaultProfileInConfig := configFile.Section("default")
-	defaultProfileInConfig.DeleteKey("role_arn")
-	defaultProfileInConfig.DeleteKey("source_profile")
+	for _, key := range configDefaultOnlyKeys() {
+		defaultProfileInConfig.DeleteKey(key)
+	}
 
 	selectedProfileInConfig := findConfigSectionByName(selectedProfileName, configFile)
 	copyValueToDefaultProfileIfAvailable(defaultProfileInConfig, selectedProfileInConfig, "region", "mfa_serial")
 }
 
+// SetSelectedMFASessionAsDefault sets default profile like SetSelectedProfileAsDefault, but with MFA session
+// credentials of selected profile instead of its long-term keys. Files are not changed if MFA session section doesn't
+// exist
+func SetSelectedMFASessionAsDefault(selectedProfileName string, credentialsFile *ini.File, configFile *ini.File) error {
+	sessionProfileName := MFASessionProfileName(selectedProfileName)
+	sessionSection, err := credentialsFile.GetSection(sessionProfileName)
+	if err != nil {
+		return fmt.Errorf("MFA session [%s] of [%s] not found in credentials file", sessionProfileName, selectedProfileName)
+	}
+
+	SetSelectedProfileAsDefault(selectedProfileName, credentialsFile, configFile)
+
+	defaultProfileInCredentials := credentialsFile.Section("default")
+	copyValueToDefaultProfileIfAvailable(defaultProfileInCredentials, sessionSection, "aws_access_key_id", "aws_secret_access_key", "aws_session_token")
+	return nil
+}
+
 func SetSelectedAssumedProfileAsDefault(selectedAssumedProfileName string, configFile *ini.File) {
 	selectedProfile := configFile.Section(selectedAssumedProfileName)
-	selectedRoleArn := selectedProfile.Key("role_arn").Value()
-	selectedSourceProfile := selectedProfile.Key("source_profile").Value()
-
 	defaultProfile := configFile.Section("default")
-	defaultProfile.Key("role_arn").SetValue(selectedRoleArn)
-	defaultProfile.Key("source_profile").SetValue(selectedSourceProfile)
 
-	copyValueToDefaultProfileIfAvailable(defaultProfile, selectedProfile, "region", "mfa_serial")
+	// all config file profiles share this function, keys not available in selected profile are cleared from default
+	keys := append([]string{"region", "mfa_serial"}, configDefaultOnlyKeys()...)
+	copyValueToDefaultProfileIfAvailable(defaultProfile, selectedProfile, keys...)
+}
+
+// SetSelectedProcessProfileAsDefault sets default profile like SetSelectedAssumedProfileAsDefault, and removes static
+// credentials from [default] in credentials file since they take precedence over credential_process
+func SetSelectedProcessProfileAsDefault(selectedProcessProfileName string, credentialsFile *ini.File, configFile *ini.File) {
+	SetSelectedAssumedProfileAsDefault(selectedProcessProfileName, configFile)
+
+	if defaultProfileInCredentials, err := credentialsFile.GetSection("default"); err == nil {
+		for _, key := range staticCredentialsKeys {
+			defaultProfileInCredentials.DeleteKey(key)
+		}
+	}
+}
+
+// keys defining how config file profiles get credentials, [default] in config file only keeps those of the profile set
+// as default
+func configDefaultOnlyKeys() []string {
+	var keys []string
+
+	keys = append(keys, assumeRoleKeys...)
+	keys = append(keys, ssoKeys...)
+	keys = append(keys, processKeys...)
+	keys = append(keys, webIdentityKeys...)
+
+	return keys
 }
 
 func SetSelectedRegionAsDefault(selectedRegion string, configFile *ini.File) {
@@ -154,6 +154,42 @@ func TestSetSelectedProfileAsDefault(t *testing.T) {
 	})
 }
 
+func TestSetSelectedMFASessionAsDefault(t *testing.T) {
+	t.Run("set default profile credentials to mfa session of selected profile", func(t *testing.T) {
+		credentialsFile := ini.Empty()
+		AddCredentialsSection(credentialsFile, "default")
+		AddCredentialsSection(credentialsFile, "iam-user").Key("mfa_serial").SetValue("arn:aws:iam::123456789012:mfa/user")
+		AddCredentialsSection(credentialsFile, "iam-user-mfa").Key("aws_session_token").SetValue("iam-user-mfa-token")
+
+		configFile := ini.Empty()
+		configFile.Section("profile iam-user").Key("region").SetValue("us-west-2")
+
+		err := SetSelectedMFASessionAsDefault("iam-user", credentialsFile, configFile)
+
+		require.NoError(t, err)
+		defaultSection := credentialsFile.Section("default")
+		require.Equal(t, "iam-user-mfa-id", defaultSection.Key("aws_access_key_id").Value())
+		require.Equal(t, "iam-user-mfa-secret", defaultSection.Key("aws_secret_access_key").Value())
+		require.Equal(t, "iam-user-mfa-token", defaultSection.Key("aws_session_token").Value())
+		require.Equal(t, "us-west-2", configFile.Section("default").Key("region").Value())
+	})
+
+	t.Run("return error and keep default profile credentials when mfa session section does not exist", func(t *testing.T) {
+		credentialsFile := ini.Empty()
+		AddCredentialsSection(credentialsFile, "default")
+		AddCredentialsSection(credentialsFile, "iam-user").Key("mfa_serial").SetValue("arn:aws:iam::123456789012:mfa/user")
+
+		err := SetSelectedMFASessionAsDefault("iam-user", credentialsFile, ini.Empty())
+
+		require.Error(t, err)
+		require.Equal(t, "MFA session [iam-user-mfa] of [iam-user] not found in credentials file", err.Error())
+		_, getSectionErr := credentialsFile.GetSection("iam-user-mfa")
+		require.Error(t, getSectionErr)
+		require.Equal(t, "default-id", credentialsFile.Section("default").Key("aws_access_key_id").Value())
+		require.Equal(t, "default-secret", credentialsFile.Section("default").Key("aws_secret_access_key").Value())
+	})
+}
+
 func TestSetSelectedAssumedProfileAsDefault(t *testing.T) {
 	t.Run("set role arn and source profile for default profile in config file", func(t *testing.T) {
 		configFile := ini.Empty()
@@ -169,6 +205,35 @@ func TestSetSelectedAssumedProfileAsDefault(t *testing.T) {
 		require.Empty(t, defaultSection.Key("region").Value())
 	})
 
+	t.Run("set external id, role session name and duration seconds for default profile in config file", func(t *testing.T) {
+		configFile := ini.Empty()
+		defaultSection := AddConfigSection(configFile, "default")
+		defaultSection.Key("external_id").SetValue("default-external-id")
+		profile1Section := AddConfigSection(configFile, "profile-1")
+		profile1Section.Key("role_session_name").SetValue("profile-1-session-name")
+		profile1Section.Key("duration_seconds").SetValue("3600")
+
+		SetSelectedAssumedProfileAsDefault("profile-1", configFile)
+
+		require.False(t, defaultSection.HasKey("external_id"))
+		require.Equal(t, "profile-1-session-name", defaultSection.Key("role_session_name").Value())
+		require.Equal(t, "3600", defaultSection.Key("duration_seconds").Value())
+	})
+
+	t.Run("set credential source and clear source profile for default profile in config file", func(t *testing.T) {
+		configFile := ini.Empty()
+		defaultSection := AddConfigSection(configFile, "default")
+		profile1Section, _ := configFile.NewSection("profile-1")
+		profile1Section.Key("role_arn").SetValue("profile-1-role-arn")
+		profile1Section.Key("credential_source").SetValue("Environment")
+
+		SetSelectedAssumedProfileAsDefault("profile-1", configFile)
+
+		require.Equal(t, "profile-1-role-arn", defaultSection.Key("role_arn").Value())
+		require.Equal(t, "Environment", defaultSection.Key("credential_source").Value())
+		require.False(t, defaultSection.HasKey("source_profile"))
+	})
+
 	t.Run("set region for default profile in config file", func(t *testing.T) {
 		configFile := ini.Empty()
 		AddConfigSection(configFile, "default")
@@ -224,6 +289,140 @@ func TestSetSelectedAssumedProfileAsDefault(t *testing.T) {
 	})
 }
 
+func TestSetSelectedAssumedProfileAsDefault_SSOProfile(t *testing.T) {
+	t.Run("set sso keys and clear role arn and source profile for default profile in config file", func(t *testing.T) {
+		configFile := ini.Empty()
+		AddConfigSection(configFile, "default")
+		AddConfigSection(configFile, "profile-1")
+		ssoSection := AddSSOConfigSection(configFile, "profile-2")
+		ssoSection.Key("region").SetValue("us-west-2")
+
+		SetSelectedAssumedProfileAsDefault("profile-2", configFile)
+
+		defaultSection := configFile.Section("default")
+		require.Equal(t, "https://profile-2.awsapps.com/start", defaultSection.Key("sso_start_url").Value())
+		require.Equal(t, "us-east-1", defaultSection.Key("sso_region").Value())
+		require.Equal(t, "profile-2-account-id", defaultSection.Key("sso_account_id").Value())
+		require.Equal(t, "profile-2-role-name", defaultSection.Key("sso_role_name").Value())
+		require.Equal(t, "us-west-2", defaultSection.Key("region").Value())
+		require.False(t, defaultSection.HasKey("role_arn"))
+		require.False(t, defaultSection.HasKey("source_profile"))
+	})
+
+	t.Run("clear sso keys of default profile when selecting an assumed profile", func(t *testing.T) {
+		configFile := ini.Empty()
+		AddSSOConfigSection(configFile, "default")
+		AddConfigSection(configFile, "profile-1")
+
+		SetSelectedAssumedProfileAsDefault("profile-1", configFile)
+
+		defaultSection := configFile.Section("default")
+		require.Equal(t, "profile-1-role-arn", defaultSection.Key("role_arn").Value())
+		require.False(t, defaultSection.HasKey("sso_start_url"))
+		require.False(t, defaultSection.HasKey("sso_account_id"))
+		require.False(t, defaultSection.HasKey("sso_role_name"))
+	})
+
+	t.Run("clear sso keys of default profile when selecting a credentials profile", func(t *testing.T) {
+		credentialsFile := ini.Empty()
+		AddCredentialsSection(credentialsFile, "default")
+		AddCredentialsSection(credentialsFile, "profile-1")
+
+		configFile := ini.Empty()
+		AddSSOConfigSection(configFile, "default")
+
+		SetSelectedProfileAsDefault("profile-1", credentialsFile, configFile)
+
+		defaultSection := configFile.Section("default")
+		require.False(t, defaultSection.HasKey("sso_start_url"))
+		require.False(t, defaultSection.HasKey("sso_account_id"))
+		require.False(t, defaultSection.HasKey("sso_role_name"))
+	})
+}
+
+func TestSetSelectedAssumedProfileAsDefault_CredentialProcessProfile(t *testing.T) {
+	t.Run("set credential process and clear role arn and source profile for default profile in config file", func(t *testing.T) {
+		configFile := ini.Empty()
+		AddConfigSection(configFile, "default")
+		processSection, _ := configFile.NewSection("profile-2")
+		processSection.Key("credential_process").SetValue("vault-creds")
+
+		SetSelectedAssumedProfileAsDefault("profile-2", configFile)
+
+		defaultSection := configFile.Section("default")
+		require.Equal(t, "vault-creds", defaultSection.Key("credential_process").Value())
+		require.False(t, defaultSection.HasKey("role_arn"))
+		require.False(t, defaultSection.HasKey("source_profile"))
+	})
+
+	t.Run("clear credential process of default profile when selecting a credentials profile", func(t *testing.T) {
+		credentialsFile := ini.Empty()
+		AddCredentialsSection(credentialsFile, "default")
+		AddCredentialsSection(credentialsFile, "profile-1")
+
+		configFile := ini.Empty()
+		defaultSection, _ := configFile.NewSection("default")
+		defaultSection.Key("credential_process").SetValue("vault-creds")
+
+		SetSelectedProfileAsDefault("profile-1", credentialsFile, configFile)
+
+		require.False(t, configFile.Section("default").HasKey("credential_process"))
+	})
+}
+
+func TestSetSelectedProcessProfileAsDefault(t *testing.T) {
+	t.Run("remove static credentials from default profile in credentials file", func(t *testing.T) {
+		credentialsFile := ini.Empty()
+		defaultCredentials := AddCredentialsSection(credentialsFile, "default")
+		defaultCredentials.Key("aws_session_token").SetValue("default-token")
+		defaultCredentials.Key("region").SetValue("us-east-1")
+		AddCredentialsSection(credentialsFile, "profile-1")
+
+		configFile := ini.Empty()
+		processSection, _ := configFile.NewSection("profile process")
+		processSection.Key("credential_process").SetValue("vault-creds")
+
+		SetSelectedProcessProfileAsDefault("profile process", credentialsFile, configFile)
+
+		defaultSection := credentialsFile.Section("default")
+		require.False(t, defaultSection.HasKey("aws_access_key_id"))
+		require.False(t, defaultSection.HasKey("aws_secret_access_key"))
+		require.False(t, defaultSection.HasKey("aws_session_token"))
+		require.Equal(t, "us-east-1", defaultSection.Key("region").Value())
+		require.Equal(t, "profile-1-id", credentialsFile.Section("profile-1").Key("aws_access_key_id").Value())
+		require.Equal(t, "vault-creds", configFile.Section("default").Key("credential_process").Value())
+	})
+
+	t.Run("not add default profile to credentials file when it does not exist", func(t *testing.T) {
+		credentialsFile := ini.Empty()
+		configFile := ini.Empty()
+		processSection, _ := configFile.NewSection("profile process")
+		processSection.Key("credential_process").SetValue("vault-creds")
+
+		SetSelectedProcessProfileAsDefault("profile process", credentialsFile, configFile)
+
+		_, err := credentialsFile.GetSection("default")
+		require.Error(t, err)
+	})
+}
+
+func TestSetSelectedAssumedProfileAsDefault_WebIdentityProfile(t *testing.T) {
+	t.Run("set role arn and web identity token file and clear source profile for default profile in config file", func(t *testing.T) {
+		configFile := ini.Empty()
+		AddConfigSection(configFile, "default")
+		webIdentitySection, _ := configFile.NewSection("profile-2")
+		webIdentitySection.Key("role_arn").SetValue("profile-2-role-arn")
+		webIdentitySection.Key("web_identity_token_file").SetValue("/tmp/token")
+
+		SetSelectedAssumedProfileAsDefault("profile-2", configFile)
+
+		defaultSection := configFile.Section("default")
+		require.Equal(t, "profile-2-role-arn", defaultSection.Key("role_arn").Value())
+		require.Equal(t, "/tmp/token", defaultSection.Key("web_identity_token_file").Value())
+		require.False(t, defaultSection.HasKey("source_profile"))
+	})
+}
+
 func TestSetSelectedRegionAsDefault(t *testing.T) {
 	t.Run("set selected region of default profile in config file", func(t *testing.T) {
 		configFile := ini.Empty()
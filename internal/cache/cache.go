@@ -0,0 +1,234 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hpcsc/aws-profile/internal/aws"
+	"github.com/hpcsc/aws-profile/internal/awsconfig"
+	"github.com/hpcsc/aws-profile/internal/encryption"
+	"github.com/hpcsc/aws-profile/internal/utils"
+)
+
+const (
+	cacheDirectory     = "~/.aws-profile/cache"
+	encryptionKeyFile  = "~/.aws-profile/cache.key"
+	cacheFileExtension = ".bin"
+)
+
+type Entry struct {
+	Key         string
+	ProfileName string
+	RoleArn     string
+	Credentials aws.Credentials
+}
+
+type Store struct {
+	directory   string
+	keyFilePath string
+	now         func() time.Time
+}
+
+func NewStore(directory string, keyFilePath string) *Store {
+	return &Store{
+		directory:   directory,
+		keyFilePath: keyFilePath,
+		now:         time.Now,
+	}
+}
+
+func NewDefaultStore() *Store {
+	return NewStore(utils.ExpandHomeDirectory(cacheDirectory), utils.ExpandHomeDirectory(encryptionKeyFile))
+}
+
+// keyHop contains settings of a hop affecting its credentials, settings only used for display, e.g. file a profile is
+// read from, are left out so that the same credentials are reused wherever the profile is defined
+type keyHop struct {
+	ProfileName     string
+	RoleArn         string
+	MFASerialNumber string
+	ExternalId      string
+	DurationSeconds int
+	Region          string
+}
+
+// Key identifies credentials of given chain, credentials are not reused when any setting affecting them changes,
+// e.g. role arn, external id or duration of any hop.
+// Session id identifies temporary credentials at the base of chain, e.g. MFA session, credentials retrieved with a
+// previous session are not reused once it is renewed
+func Key(chain []awsconfig.Profile, duration time.Duration, sessionId string) string {
+	hops := make([]keyHop, len(chain))
+	for i, profile := range chain {
+		hops[i] = keyHop{
+			ProfileName:     profile.ProfileName,
+			RoleArn:         profile.RoleArn,
+			MFASerialNumber: profile.MFASerialNumber,
+			ExternalId:      profile.ExternalId,
+			DurationSeconds: profile.DurationSeconds,
+			Region:          profile.Region,
+		}
+	}
+
+	content, _ := json.Marshal(struct {
+		Chain     []keyHop
+		Duration  time.Duration
+		SessionId string `json:",omitempty"`
+	}{
+		Chain:     hops,
+		Duration:  duration,
+		SessionId: sessionId,
+	})
+
+	hash := sha256.Sum256(content)
+	return hex.EncodeToString(hash[:])
+}
+
+// Read returns nil if credentials are not cached or expire within refresh window
+func (store *Store) Read(key string, refreshWindow time.Duration) (*aws.Credentials, error) {
+	entry, err := store.readEntry(store.entryFilePath(key))
+	// entries can't be decrypted without key, they are replaced when credentials are cached again
+	if os.IsNotExist(err) || errors.Is(err, encryption.ErrKeyNotFound) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !entry.Credentials.Expiration.After(store.now().Add(refreshWindow)) {
+		return nil, nil
+	}
+
+	return &entry.Credentials, nil
+}
+
+func (store *Store) Write(entry Entry) error {
+	if entry.Credentials.Expiration.IsZero() {
+		return fmt.Errorf("credentials of %s without expiration are not cached", entry.ProfileName)
+	}
+
+	key, err := encryption.LoadOrCreateKey(store.keyFilePath)
+	if err != nil {
+		return err
+	}
+
+	content, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal cached credentials: %v", err)
+	}
+
+	encrypted, err := encryption.Encrypt(key, content)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(store.directory, os.FileMode(0700)); err != nil {
+		return fmt.Errorf("failed to create cache directory: %v", err)
+	}
+
+	if err := ioutil.WriteFile(store.entryFilePath(entry.Key), encrypted, os.FileMode(0600)); err != nil {
+		return fmt.Errorf("failed to write cached credentials: %v", err)
+	}
+
+	return nil
+}
+
+// List returns cached entries sorted by profile name, including expired ones, and paths of entries that can't be read,
+// e.g. encrypted with a key that has been removed
+func (store *Store) List() ([]Entry, []string, error) {
+	filePaths, err := store.entryFilePaths()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var entries []Entry
+	var unreadable []string
+	for _, filePath := range filePaths {
+		entry, err := store.readEntry(filePath)
+		if err != nil {
+			unreadable = append(unreadable, filePath)
+			continue
+		}
+
+		entries = append(entries, *entry)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].ProfileName < entries[j].ProfileName
+	})
+
+	return entries, unreadable, nil
+}
+
+// Clear removes all cached entries and returns number of removed entries
+func (store *Store) Clear() (int, error) {
+	filePaths, err := store.entryFilePaths()
+	if err != nil {
+		return 0, err
+	}
+
+	for _, filePath := range filePaths {
+		if err := os.Remove(filePath); err != nil {
+			return 0, fmt.Errorf("failed to remove cached credentials %s: %v", filePath, err)
+		}
+	}
+
+	return len(filePaths), nil
+}
+
+func (store *Store) readEntry(filePath string) (*Entry, error) {
+	encrypted, err := ioutil.ReadFile(filepath.Clean(filePath))
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := encryption.LoadKey(store.keyFilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := encryption.Decrypt(key, encrypted)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt cached credentials %s: %v", filePath, err)
+	}
+
+	entry := &Entry{}
+	if err := json.Unmarshal(content, entry); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal cached credentials %s: %v", filePath, err)
+	}
+
+	return entry, nil
+}
+
+func (store *Store) entryFilePaths() ([]string, error) {
+	files, err := ioutil.ReadDir(store.directory)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cache directory: %v", err)
+	}
+
+	var filePaths []string
+	for _, file := range files {
+		if !file.IsDir() && strings.HasSuffix(file.Name(), cacheFileExtension) {
+			filePaths = append(filePaths, filepath.Join(store.directory, file.Name()))
+		}
+	}
+
+	return filePaths, nil
+}
+
+func (store *Store) entryFilePath(key string) string {
+	return filepath.Join(store.directory, key+cacheFileExtension)
+}
@@ -0,0 +1,238 @@
+package cache
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/hpcsc/aws-profile/internal/aws"
+	"github.com/hpcsc/aws-profile/internal/awsconfig"
+	"github.com/hpcsc/aws-profile/internal/encryption"
+	"github.com/stretchr/testify/require"
+)
+
+func setupStore(t *testing.T) (*Store, func()) {
+	directory, err := ioutil.TempDir("", "aws-profile-cache")
+	require.NoError(t, err)
+
+	store := NewStore(filepath.Join(directory, "cache"), filepath.Join(directory, "cache.key"))
+	store.now = func() time.Time {
+		return time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+
+	return store, func() {
+		_ = os.RemoveAll(directory)
+	}
+}
+
+func stubEntry(key string, profileName string, expiration time.Time) Entry {
+	return Entry{
+		Key:         key,
+		ProfileName: profileName,
+		RoleArn:     profileName + "-role-arn",
+		Credentials: aws.Credentials{
+			Value: credentials.Value{
+				AccessKeyID:     profileName + "-access-key-id",
+				SecretAccessKey: profileName + "-secret-access-key",
+				SessionToken:    profileName + "-session-token",
+			},
+			Expiration: expiration,
+		},
+	}
+}
+
+func TestKey(t *testing.T) {
+	chain := []awsconfig.Profile{
+		{ProfileName: "base"},
+		{ProfileName: "profile target", RoleArn: "target-role-arn", SourceProfile: "base"},
+	}
+
+	t.Run("return same key for same chain and duration", func(t *testing.T) {
+		require.Equal(t, Key(chain, time.Hour, ""), Key(chain, time.Hour, ""))
+	})
+
+	t.Run("return different key when duration changes", func(t *testing.T) {
+		require.NotEqual(t, Key(chain, time.Hour, ""), Key(chain, 0, ""))
+	})
+
+	t.Run("return different key when session settings of any hop change", func(t *testing.T) {
+		changedChain := []awsconfig.Profile{
+			chain[0],
+			{ProfileName: "profile target", RoleArn: "target-role-arn", SourceProfile: "base", ExternalId: "external-id"},
+		}
+
+		require.NotEqual(t, Key(chain, time.Hour, ""), Key(changedChain, time.Hour, ""))
+	})
+
+	t.Run("return same key when only display settings of a profile change", func(t *testing.T) {
+		movedChain := []awsconfig.Profile{
+			chain[0],
+			{ProfileName: "profile target", RoleArn: "target-role-arn", SourceProfile: "base", DisplayProfileName: "target (other)", OriginFile: "/other/config"},
+		}
+
+		require.Equal(t, Key(chain, time.Hour, ""), Key(movedChain, time.Hour, ""))
+	})
+
+	t.Run("return different key when session at the base of chain changes", func(t *testing.T) {
+		require.NotEqual(t, Key(chain, time.Hour, "session-1-key-id"), Key(chain, time.Hour, "session-2-key-id"))
+	})
+}
+
+func TestStore(t *testing.T) {
+	t.Run("return nil if credentials are not cached", func(t *testing.T) {
+		store, cleanup := setupStore(t)
+		defer cleanup()
+
+		cached, err := store.Read("not-exists", time.Minute)
+
+		require.NoError(t, err)
+		require.Nil(t, cached)
+	})
+
+	t.Run("return cached credentials if they expire after refresh window", func(t *testing.T) {
+		store, cleanup := setupStore(t)
+		defer cleanup()
+		require.NoError(t, store.Write(stubEntry("key-1", "profile-1", time.Date(2020, 1, 1, 1, 0, 0, 0, time.UTC))))
+
+		cached, err := store.Read("key-1", 5*time.Minute)
+
+		require.NoError(t, err)
+		require.NotNil(t, cached)
+		require.Equal(t, "profile-1-access-key-id", cached.AccessKeyID)
+		require.Equal(t, "profile-1-secret-access-key", cached.SecretAccessKey)
+		require.Equal(t, "profile-1-session-token", cached.SessionToken)
+		require.Equal(t, time.Date(2020, 1, 1, 1, 0, 0, 0, time.UTC), cached.Expiration.UTC())
+	})
+
+	t.Run("return nil if cached credentials expire within refresh window", func(t *testing.T) {
+		store, cleanup := setupStore(t)
+		defer cleanup()
+		require.NoError(t, store.Write(stubEntry("key-1", "profile-1", time.Date(2020, 1, 1, 0, 4, 0, 0, time.UTC))))
+
+		cached, err := store.Read("key-1", 5*time.Minute)
+
+		require.NoError(t, err)
+		require.Nil(t, cached)
+	})
+
+	t.Run("write cached credentials encrypted", func(t *testing.T) {
+		store, cleanup := setupStore(t)
+		defer cleanup()
+		require.NoError(t, store.Write(stubEntry("key-1", "profile-1", time.Date(2020, 1, 1, 1, 0, 0, 0, time.UTC))))
+
+		content, err := ioutil.ReadFile(store.entryFilePath("key-1"))
+
+		require.NoError(t, err)
+		require.NotContains(t, string(content), "profile-1-secret-access-key")
+	})
+
+	t.Run("return nil without creating key if key does not exist", func(t *testing.T) {
+		store, cleanup := setupStore(t)
+		defer cleanup()
+		require.NoError(t, store.Write(stubEntry("key-1", "profile-1", time.Date(2020, 1, 1, 1, 0, 0, 0, time.UTC))))
+		require.NoError(t, os.Remove(store.keyFilePath))
+
+		cached, err := store.Read("key-1", time.Minute)
+
+		require.NoError(t, err)
+		require.Nil(t, cached)
+		_, err = os.Stat(store.keyFilePath)
+		require.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("return error if cached credentials are encrypted with another key", func(t *testing.T) {
+		store, cleanup := setupStore(t)
+		defer cleanup()
+		require.NoError(t, store.Write(stubEntry("key-1", "profile-1", time.Date(2020, 1, 1, 1, 0, 0, 0, time.UTC))))
+		require.NoError(t, ioutil.WriteFile(store.keyFilePath, make([]byte, encryption.KeySize), 0600))
+
+		_, err := store.Read("key-1", time.Minute)
+
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to decrypt cached credentials")
+	})
+
+	t.Run("return error when writing credentials without expiration", func(t *testing.T) {
+		store, cleanup := setupStore(t)
+		defer cleanup()
+
+		err := store.Write(stubEntry("key-1", "profile-1", time.Time{}))
+
+		require.Error(t, err)
+	})
+
+	t.Run("list cached entries sorted by profile name", func(t *testing.T) {
+		store, cleanup := setupStore(t)
+		defer cleanup()
+		require.NoError(t, store.Write(stubEntry("key-2", "profile-2", time.Date(2020, 1, 1, 1, 0, 0, 0, time.UTC))))
+		require.NoError(t, store.Write(stubEntry("key-1", "profile-1", time.Date(2019, 1, 1, 1, 0, 0, 0, time.UTC))))
+
+		entries, unreadable, err := store.List()
+
+		require.NoError(t, err)
+		require.Empty(t, unreadable)
+		require.Equal(t, 2, len(entries))
+		require.Equal(t, "profile-1", entries[0].ProfileName)
+		require.Equal(t, "key-1", entries[0].Key)
+		require.Equal(t, "profile-2", entries[1].ProfileName)
+		require.Equal(t, "profile-2-role-arn", entries[1].RoleArn)
+	})
+
+	t.Run("return empty list if cache directory does not exist", func(t *testing.T) {
+		store, cleanup := setupStore(t)
+		defer cleanup()
+
+		entries, _, err := store.List()
+
+		require.NoError(t, err)
+		require.Empty(t, entries)
+	})
+
+	t.Run("skip entries that can't be decrypted", func(t *testing.T) {
+		store, cleanup := setupStore(t)
+		defer cleanup()
+		require.NoError(t, store.Write(stubEntry("key-1", "profile-1", time.Date(2020, 1, 1, 1, 0, 0, 0, time.UTC))))
+		require.NoError(t, ioutil.WriteFile(store.entryFilePath("key-2"), []byte("corrupted"), 0600))
+
+		entries, unreadable, err := store.List()
+
+		require.NoError(t, err)
+		require.Equal(t, 1, len(entries))
+		require.Equal(t, "profile-1", entries[0].ProfileName)
+		require.Equal(t, []string{store.entryFilePath("key-2")}, unreadable)
+	})
+
+	t.Run("list without creating key if key does not exist", func(t *testing.T) {
+		store, cleanup := setupStore(t)
+		defer cleanup()
+		require.NoError(t, store.Write(stubEntry("key-1", "profile-1", time.Date(2020, 1, 1, 1, 0, 0, 0, time.UTC))))
+		require.NoError(t, os.Remove(store.keyFilePath))
+
+		entries, unreadable, err := store.List()
+
+		require.NoError(t, err)
+		require.Empty(t, entries)
+		require.Equal(t, []string{store.entryFilePath("key-1")}, unreadable)
+		_, err = os.Stat(store.keyFilePath)
+		require.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("clear all cached entries", func(t *testing.T) {
+		store, cleanup := setupStore(t)
+		defer cleanup()
+		require.NoError(t, store.Write(stubEntry("key-1", "profile-1", time.Date(2020, 1, 1, 1, 0, 0, 0, time.UTC))))
+		require.NoError(t, store.Write(stubEntry("key-2", "profile-2", time.Date(2020, 1, 1, 1, 0, 0, 0, time.UTC))))
+
+		removed, err := store.Clear()
+
+		require.NoError(t, err)
+		require.Equal(t, 2, removed)
+
+		entries, _, err := store.List()
+		require.NoError(t, err)
+		require.Empty(t, entries)
+	})
+}
@@ -8,14 +8,31 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 type Config struct {
-	HighlightColor string   `yaml:"highlightColor"`
-	Regions        []string `yaml:"regions"`
+	HighlightColor     string                 `yaml:"highlightColor"`
+	Regions            []string               `yaml:"regions"`
+	CacheRefreshWindow string                 `yaml:"cacheRefreshWindow"`
+	Favourites         []string               `yaml:"favourites"`
+	MFAProviders       map[string]MFAProvider `yaml:"mfaProviders"`
 }
 
+// MFAProvider configures how MFA token of a profile is provided, command is only used by process provider
+type MFAProvider struct {
+	Type    string `yaml:"type"`
+	Command string `yaml:"command"`
+}
+
+const (
+	MFAProviderPrompt  = "prompt"
+	MFAProviderTOTP    = "totp"
+	MFAProviderProcess = "process"
+)
+
 const defaultHighlightColor = "green"
+const defaultCacheRefreshWindow = "5m"
 
 var defaultRegions = []string{
 	"af-south-1",
@@ -85,21 +102,80 @@ func FromFile(path string) (*Config, error) {
 		c.Regions = defaultRegions
 	}
 
+	if c.CacheRefreshWindow == "" {
+		c.CacheRefreshWindow = defaultCacheRefreshWindow
+	}
+
 	if !isValidHighlightColor(c.HighlightColor) {
 		return nil, fmt.Errorf("valid values for highlight color are: %s", strings.Join(allowedColors, ", "))
 	}
 
+	if _, err := time.ParseDuration(c.CacheRefreshWindow); err != nil {
+		return nil, fmt.Errorf("invalid cache refresh window %s, example of valid value: 5m", c.CacheRefreshWindow)
+	}
+
+	if err := validateMFAProviders(c.MFAProviders); err != nil {
+		return nil, err
+	}
+
 	return c, nil
 }
 
+// MFAProviderOf returns MFA provider configured for given profile name, or for the same profile with or without
+// "profile " prefix. Token is prompted for profiles without configured provider
+func (c *Config) MFAProviderOf(profileName string) MFAProvider {
+	otherName := "profile " + profileName
+	if strings.HasPrefix(profileName, "profile ") {
+		otherName = strings.TrimPrefix(profileName, "profile ")
+	}
+
+	for _, name := range []string{profileName, otherName} {
+		if provider, ok := c.MFAProviders[name]; ok {
+			return provider
+		}
+	}
+
+	return MFAProvider{Type: MFAProviderPrompt}
+}
+
+// CacheRefreshWindowDuration returns how long before expiry cached credentials are refreshed
+func (c *Config) CacheRefreshWindowDuration() time.Duration {
+	refreshWindow, err := time.ParseDuration(c.CacheRefreshWindow)
+	if err != nil {
+		refreshWindow, _ = time.ParseDuration(defaultCacheRefreshWindow)
+	}
+
+	return refreshWindow
+}
+
 func DefaultHighlightColor() string {
 	return defaultHighlightColor
 }
 
+func DefaultCacheRefreshWindow() string {
+	return defaultCacheRefreshWindow
+}
+
 func DefaultRegions() []string {
 	return defaultRegions
 }
 
+func validateMFAProviders(providers map[string]MFAProvider) error {
+	for profileName, provider := range providers {
+		switch provider.Type {
+		case MFAProviderPrompt, MFAProviderTOTP:
+		case MFAProviderProcess:
+			if provider.Command == "" {
+				return fmt.Errorf("mfa provider of %s requires command", profileName)
+			}
+		default:
+			return fmt.Errorf("invalid mfa provider type %s of %s, valid values are: %s, %s, %s", provider.Type, profileName, MFAProviderPrompt, MFAProviderTOTP, MFAProviderProcess)
+		}
+	}
+
+	return nil
+}
+
 func isValidHighlightColor(color string) bool {
 	for _, allowedColor := range allowedColors {
 		if strings.EqualFold(color, allowedColor) {
@@ -121,7 +197,8 @@ func fileExists(path string) bool {
 
 func defaultConfig() *Config {
 	return &Config{
-		HighlightColor: defaultHighlightColor,
-		Regions:        defaultRegions,
+		HighlightColor:     defaultHighlightColor,
+		Regions:            defaultRegions,
+		CacheRefreshWindow: defaultCacheRefreshWindow,
 	}
 }
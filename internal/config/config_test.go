@@ -7,6 +7,7 @@ import (
 	"runtime"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestFromFile(t *testing.T) {
@@ -15,8 +16,9 @@ func TestFromFile(t *testing.T) {
 
 		require.NoError(t, err)
 		expectedConfig := &Config{
-			HighlightColor: DefaultHighlightColor(),
-			Regions:        DefaultRegions(),
+			HighlightColor:     DefaultHighlightColor(),
+			Regions:            DefaultRegions(),
+			CacheRefreshWindow: DefaultCacheRefreshWindow(),
 		}
 		require.Equal(t, expectedConfig, c)
 	})
@@ -32,6 +34,7 @@ func TestFromFile(t *testing.T) {
 				"us-west-2",
 				"us-east-1",
 			},
+			CacheRefreshWindow: DefaultCacheRefreshWindow(),
 		}
 		require.Equal(t, expectedConfig, c)
 	})
@@ -41,8 +44,9 @@ func TestFromFile(t *testing.T) {
 
 		require.NoError(t, err)
 		expectedConfig := &Config{
-			HighlightColor: "yellow",
-			Regions:        DefaultRegions(),
+			HighlightColor:     "yellow",
+			Regions:            DefaultRegions(),
+			CacheRefreshWindow: DefaultCacheRefreshWindow(),
 		}
 		require.Equal(t, expectedConfig, c)
 	})
@@ -59,6 +63,15 @@ func TestFromFile(t *testing.T) {
 				"us-west-2",
 				"us-east-1",
 			},
+			CacheRefreshWindow: "10m",
+			Favourites: []string{
+				"prod-admin",
+				"staging",
+			},
+			MFAProviders: map[string]MFAProvider{
+				"prod-admin": {Type: MFAProviderProcess, Command: "op item get aws --otp"},
+				"staging":    {Type: MFAProviderTOTP},
+			},
 		}
 		require.Equal(t, expectedConfig, c)
 	})
@@ -70,6 +83,27 @@ func TestFromFile(t *testing.T) {
 		require.Equal(t, "valid values for highlight color are: black, red, green, yellow, blue, magenta, cyan, white", err.Error())
 	})
 
+	t.Run("return error if cache refresh window is not a valid duration", func(t *testing.T) {
+		_, err := FromFile("testdata/invalid-cache-refresh-window.yaml")
+
+		require.Error(t, err)
+		require.Equal(t, "invalid cache refresh window 10 minutes, example of valid value: 5m", err.Error())
+	})
+
+	t.Run("return error if mfa provider type is invalid", func(t *testing.T) {
+		_, err := FromFile("testdata/invalid-mfa-provider-type.yaml")
+
+		require.Error(t, err)
+		require.Equal(t, "invalid mfa provider type sms of prod, valid values are: prompt, totp, process", err.Error())
+	})
+
+	t.Run("return error if process mfa provider has no command", func(t *testing.T) {
+		_, err := FromFile("testdata/missing-mfa-provider-command.yaml")
+
+		require.Error(t, err)
+		require.Equal(t, "mfa provider of prod requires command", err.Error())
+	})
+
 	t.Run("return error if failed to unmarshal config file", func(t *testing.T) {
 		_, err := FromFile("testdata/invalid-config.yaml")
 
@@ -79,6 +113,20 @@ func TestFromFile(t *testing.T) {
 
 }
 
+func TestCacheRefreshWindowDuration(t *testing.T) {
+	t.Run("return parsed cache refresh window", func(t *testing.T) {
+		c := &Config{CacheRefreshWindow: "10m"}
+
+		require.Equal(t, 10*time.Minute, c.CacheRefreshWindowDuration())
+	})
+
+	t.Run("return default cache refresh window if not set", func(t *testing.T) {
+		c := &Config{}
+
+		require.Equal(t, 5*time.Minute, c.CacheRefreshWindowDuration())
+	})
+}
+
 func sampleConfigPath(t *testing.T) string {
 	_, filename, _, ok := runtime.Caller(0)
 	if !ok {
@@ -88,3 +136,32 @@ func sampleConfigPath(t *testing.T) string {
 	projectRoot := strings.ReplaceAll(path.Dir(filename), "/internal/config", "")
 	return path.Join(projectRoot, "configs/sample-config.yaml")
 }
+
+func TestMFAProviderOf(t *testing.T) {
+	c := &Config{
+		MFAProviders: map[string]MFAProvider{
+			"prod": {Type: MFAProviderTOTP},
+		},
+	}
+
+	t.Run("return provider configured for profile with or without profile prefix", func(t *testing.T) {
+		require.Equal(t, MFAProviderTOTP, c.MFAProviderOf("prod").Type)
+		require.Equal(t, MFAProviderTOTP, c.MFAProviderOf("profile prod").Type)
+	})
+
+	t.Run("return prompt provider for profile without configured provider", func(t *testing.T) {
+		require.Equal(t, MFAProviderPrompt, c.MFAProviderOf("profile staging").Type)
+	})
+
+	t.Run("prefer provider configured for exact profile name", func(t *testing.T) {
+		both := &Config{
+			MFAProviders: map[string]MFAProvider{
+				"dev":         {Type: MFAProviderTOTP},
+				"profile dev": {Type: MFAProviderProcess, Command: "get-token"},
+			},
+		}
+
+		require.Equal(t, MFAProviderTOTP, both.MFAProviderOf("dev").Type)
+		require.Equal(t, MFAProviderProcess, both.MFAProviderOf("profile dev").Type)
+	})
+}
@@ -0,0 +1,101 @@
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// KeySize is size of AES-256 key in bytes
+const KeySize = 32
+
+// ErrKeyNotFound is returned by LoadKey when key has not been created yet
+var ErrKeyNotFound = errors.New("encryption key not found")
+
+// LoadKey returns key created by LoadOrCreateKey, it never creates a key so that reading encrypted files doesn't leave
+// a key that can't decrypt them behind
+func LoadKey(keyFilePath string) ([]byte, error) {
+	key, err := ioutil.ReadFile(filepath.Clean(keyFilePath))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w: %s", ErrKeyNotFound, keyFilePath)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read encryption key %s: %v", keyFilePath, err)
+	}
+
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("encryption key %s is invalid, remove it to generate a new one", keyFilePath)
+	}
+
+	return key, nil
+}
+
+// LoadOrCreateKey returns key generated on first use and only readable by current user, it protects files written by
+// aws-profile from being read as plain text e.g. from backups or by tools scanning home directory
+func LoadOrCreateKey(keyFilePath string) ([]byte, error) {
+	key, err := LoadKey(keyFilePath)
+	if !errors.Is(err, ErrKeyNotFound) {
+		return key, err
+	}
+
+	key = make([]byte, KeySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, fmt.Errorf("failed to generate encryption key: %v", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(keyFilePath), os.FileMode(0700)); err != nil {
+		return nil, fmt.Errorf("failed to create directory for encryption key: %v", err)
+	}
+
+	if err := ioutil.WriteFile(keyFilePath, key, os.FileMode(0600)); err != nil {
+		return nil, fmt.Errorf("failed to write encryption key %s: %v", keyFilePath, err)
+	}
+
+	return key, nil
+}
+
+// Encrypt encrypts plaintext with AES-GCM, the random nonce is prepended to the result
+func Encrypt(key []byte, plaintext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %v", err)
+	}
+
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// Decrypt decrypts content returned by Encrypt
+func Decrypt(key []byte, ciphertext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ciphertext) < gcm.NonceSize() {
+		return nil, errors.New("encrypted content is too short")
+	}
+
+	nonce, sealed := ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, sealed, nil)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %v", err)
+	}
+
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/hpcsc/aws-profile/internal/cache"
+	"gopkg.in/alecthomas/kingpin.v2"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+type ListCachedCredentialsFn func() ([]cache.Entry, []string, error)
+type ClearCachedCredentialsFn func() (int, error)
+
+type CacheListHandler struct {
+	SubCommand            *kingpin.CmdClause
+	ListCachedCredentials ListCachedCredentialsFn
+}
+
+type CacheClearHandler struct {
+	SubCommand             *kingpin.CmdClause
+	ClearCachedCredentials ClearCachedCredentialsFn
+}
+
+func NewCacheHandlers(app *kingpin.Application, listCachedCredentialsFn ListCachedCredentialsFn, clearCachedCredentialsFn ClearCachedCredentialsFn) (CacheListHandler, CacheClearHandler) {
+	cacheCommand := app.Command("cache", "manage credentials cached by export")
+
+	return CacheListHandler{
+		SubCommand:            cacheCommand.Command("list", "list credentials cached by export and their expiration"),
+		ListCachedCredentials: listCachedCredentialsFn,
+	}, CacheClearHandler{
+		SubCommand:             cacheCommand.Command("clear", "remove all credentials cached by export"),
+		ClearCachedCredentials: clearCachedCredentialsFn,
+	}
+}
+
+func (handler CacheListHandler) Handle(globalArguments GlobalArguments) (Result, error) {
+	entries, unreadable, err := handler.ListCachedCredentials()
+	if err != nil {
+		return Result{}, fmt.Errorf("Failed to list cached credentials: %v", err)
+	}
+
+	var buffer bytes.Buffer
+	if len(entries) == 0 {
+		buffer.WriteString("=== no cached credentials\n")
+	}
+
+	writer := tabwriter.NewWriter(&buffer, 0, 0, 2, ' ', 0)
+	for _, entry := range entries {
+		_, _ = fmt.Fprintf(writer, "%s\t%s\t%s\n", entry.ProfileName, entry.RoleArn, formatCacheExpiration(entry.Credentials.Expiration))
+	}
+	_ = writer.Flush()
+
+	for _, filePath := range unreadable {
+		_, _ = fmt.Fprintf(&buffer, "=== skipped unreadable cached credentials %s, run \"aws-profile cache clear\" to remove it\n", filePath)
+	}
+
+	return Result{Output: strings.TrimSuffix(buffer.String(), "\n")}, nil
+}
+
+func (handler CacheClearHandler) Handle(globalArguments GlobalArguments) (Result, error) {
+	removed, err := handler.ClearCachedCredentials()
+	if err != nil {
+		return Result{}, fmt.Errorf("Failed to clear cached credentials: %v", err)
+	}
+
+	return Result{Output: fmt.Sprintf("=== removed %d cached credentials", removed)}, nil
+}
+
+func formatCacheExpiration(expiration time.Time) string {
+	if !expiration.After(time.Now()) {
+		return "expired"
+	}
+
+	return fmt.Sprintf("expires %s", expiration.Local().Format(time.RFC1123))
+}
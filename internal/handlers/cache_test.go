@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hpcsc/aws-profile/internal/aws"
+	"github.com/hpcsc/aws-profile/internal/cache"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+func setupCacheHandlers(listCachedCredentialsFn ListCachedCredentialsFn, clearCachedCredentialsFn ClearCachedCredentialsFn) (CacheListHandler, CacheClearHandler) {
+	app := kingpin.New("some-app", "some description")
+	return NewCacheHandlers(app, listCachedCredentialsFn, clearCachedCredentialsFn)
+}
+
+func TestCacheListHandler(t *testing.T) {
+	t.Run("return message if there is no cached credentials", func(t *testing.T) {
+		listHandler, _ := setupCacheHandlers(func() ([]cache.Entry, []string, error) {
+			return nil, nil, nil
+		}, nil)
+
+		result, err := listHandler.Handle(GlobalArguments{})
+
+		require.NoError(t, err)
+		require.Equal(t, "=== no cached credentials", result.Output)
+	})
+
+	t.Run("return profile name, role arn and expiration of cached credentials", func(t *testing.T) {
+		listHandler, _ := setupCacheHandlers(func() ([]cache.Entry, []string, error) {
+			return []cache.Entry{
+				{
+					ProfileName: "profile expired",
+					RoleArn:     "expired-role-arn",
+					Credentials: aws.Credentials{Expiration: time.Now().Add(-time.Hour)},
+				},
+				{
+					ProfileName: "profile valid",
+					RoleArn:     "valid-role-arn",
+					Credentials: aws.Credentials{Expiration: time.Now().Add(time.Hour)},
+				},
+			}, nil, nil
+		}, nil)
+
+		result, err := listHandler.Handle(GlobalArguments{})
+
+		require.NoError(t, err)
+		require.Regexp(t, `^profile expired\s+expired-role-arn\s+expired\nprofile valid\s+valid-role-arn\s+expires .+$`, result.Output)
+	})
+
+	t.Run("return error if failed to list cached credentials", func(t *testing.T) {
+		listHandler, _ := setupCacheHandlers(func() ([]cache.Entry, []string, error) {
+			return nil, nil, errors.New("permission denied")
+		}, nil)
+
+		_, err := listHandler.Handle(GlobalArguments{})
+
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "permission denied")
+	})
+
+	t.Run("return unreadable cached credentials after readable ones", func(t *testing.T) {
+		listHandler, _ := setupCacheHandlers(func() ([]cache.Entry, []string, error) {
+			return []cache.Entry{
+				{
+					ProfileName: "profile valid",
+					RoleArn:     "valid-role-arn",
+					Credentials: aws.Credentials{Expiration: time.Now().Add(time.Hour)},
+				},
+			}, []string{"/cache/unreadable.bin"}, nil
+		}, nil)
+
+		result, err := listHandler.Handle(GlobalArguments{})
+
+		require.NoError(t, err)
+		require.Regexp(t, `^profile valid\s+valid-role-arn\s+expires .+\n=== skipped unreadable cached credentials /cache/unreadable.bin, run "aws-profile cache clear" to remove it$`, result.Output)
+	})
+}
+
+func TestCacheClearHandler(t *testing.T) {
+	t.Run("return number of removed cached credentials", func(t *testing.T) {
+		_, clearHandler := setupCacheHandlers(nil, func() (int, error) {
+			return 3, nil
+		})
+
+		result, err := clearHandler.Handle(GlobalArguments{})
+
+		require.NoError(t, err)
+		require.Equal(t, "=== removed 3 cached credentials", result.Output)
+	})
+
+	t.Run("return error if failed to clear cached credentials", func(t *testing.T) {
+		_, clearHandler := setupCacheHandlers(nil, func() (int, error) {
+			return 0, errors.New("permission denied")
+		})
+
+		_, err := clearHandler.Handle(GlobalArguments{})
+
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "permission denied")
+	})
+}
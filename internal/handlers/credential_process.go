@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/hpcsc/aws-profile/internal/aws"
+	"github.com/hpcsc/aws-profile/internal/config"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// CredentialProcessHandler is run by AWS SDKs and CLI as credential_process, it must never show the profile picker and
+// only prints credentials to stdout
+type CredentialProcessHandler struct {
+	SubCommand        *kingpin.CmdClause
+	Arguments         CredentialProcessCommandArguments
+	CredentialsGetter CredentialsGetter
+	Config            *config.Config
+}
+
+type CredentialProcessCommandArguments struct {
+	Profile *string
+	CredentialsArguments
+}
+
+func NewCredentialProcessHandler(
+	app *kingpin.Application,
+	config *config.Config,
+	credentialsGetter CredentialsGetter,
+) CredentialProcessHandler {
+	subCommand := app.Command("credential-process", `print credentials of given profile in credential_process format
+
+Example: "credential_process = aws-profile credential-process --profile prod" in AWS config file`)
+
+	profile := subCommand.Flag("profile", "Name of profile in config file, with or without \"profile \" prefix").Short('p').Required().String()
+	credentialsArguments := credentialsFlags(subCommand)
+
+	return CredentialProcessHandler{
+		SubCommand: subCommand,
+		Arguments: CredentialProcessCommandArguments{
+			Profile:              profile,
+			CredentialsArguments: credentialsArguments,
+		},
+		CredentialsGetter: credentialsGetter,
+		Config:            config,
+	}
+}
+
+// errors are printed to stderr like other commands, AWS SDKs and CLI only parse stdout and show stderr to users
+func (handler CredentialProcessHandler) Handle(globalArguments GlobalArguments) (Result, error) {
+	output, err := handler.credentialProcessOutput(globalArguments)
+	if err != nil {
+		return Result{}, err
+	}
+
+	return Result{Output: output}, nil
+}
+
+func (handler CredentialProcessHandler) credentialProcessOutput(globalArguments GlobalArguments) (string, error) {
+	profiles, err := loadProfilesForCredentials(globalArguments)
+	if err != nil {
+		return "", err
+	}
+
+	options, err := newCredentialsOptions(handler.Arguments.CredentialsArguments, handler.Config, "", globalArguments)
+	if err != nil {
+		return "", err
+	}
+
+	profile := findConfigFileProfileExactly(profiles, *handler.Arguments.Profile)
+	if profile == nil {
+		return "", newError(CategoryNotFound, "=== profile [%s] not found in config file", *handler.Arguments.Profile)
+	}
+
+	chain, err := profiles.ResolveSourceChain(profile)
+	if err != nil {
+		return "", withCategory(CategoryConfigInvalid, err)
+	}
+
+	awsCredentials, err := handler.CredentialsGetter.get(chain, options)
+	if err != nil {
+		return "", err
+	}
+
+	output := aws.CredentialProcessOutput{
+		Version:         1,
+		AccessKeyId:     awsCredentials.AccessKeyID,
+		SecretAccessKey: awsCredentials.SecretAccessKey,
+		SessionToken:    awsCredentials.SessionToken,
+	}
+
+	// credentials without expiration are treated as long-lived by AWS SDKs
+	if !awsCredentials.Expiration.IsZero() {
+		expiration := awsCredentials.Expiration.UTC()
+		output.Expiration = &expiration
+	}
+
+	content, err := json.Marshal(output)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal credentials: %v", err)
+	}
+
+	return string(content), nil
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/hpcsc/aws-profile/internal/aws"
+	"github.com/hpcsc/aws-profile/internal/awsconfig"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/alecthomas/kingpin.v2"
+	"testing"
+	"time"
+)
+
+func setupCredentialProcessHandler(t *testing.T, arguments []string, getAWSCredentialsFn GetAWSCredentialsFn) CredentialProcessHandler {
+	app := kingpin.New("some-app", "some description")
+	credentialProcessHandler := NewCredentialProcessHandler(app, stubConfig(), stubCredentialsGetter(getAWSCredentialsFn))
+
+	if _, err := app.Parse(append([]string{"credential-process"}, arguments...)); err != nil {
+		t.Fatalf("failed to setup test credential process handler: %v\n", err)
+	}
+
+	return credentialProcessHandler
+}
+
+func TestCredentialProcessHandler(t *testing.T) {
+	t.Run("print credentials of given profile in credential process format", func(t *testing.T) {
+		expiration := time.Date(2020, 1, 1, 1, 0, 0, 0, time.UTC)
+		var calledChain []awsconfig.Profile
+		getAWSCredentialsMock := func(chain []awsconfig.Profile, _ time.Duration, _ aws.MFATokenProviderFn) (aws.Credentials, error) {
+			calledChain = chain
+			return aws.Credentials{
+				Value: credentials.Value{
+					AccessKeyID:     "access-key-id",
+					SecretAccessKey: "secret-access-key",
+					SessionToken:    "session-token",
+				},
+				Expiration: expiration,
+			}, nil
+		}
+
+		credentialProcessHandler := setupCredentialProcessHandler(t, []string{"--profile", "config_profile_2"}, getAWSCredentialsMock)
+
+		result, err := credentialProcessHandler.Handle(stubGlobalArgumentsForExport("set-config"))
+
+		require.NoError(t, err)
+		require.Equal(t, "profile config_profile_2", calledChain[len(calledChain)-1].ProfileName)
+		require.JSONEq(t, `{
+			"Version": 1,
+			"AccessKeyId": "access-key-id",
+			"SecretAccessKey": "secret-access-key",
+			"SessionToken": "session-token",
+			"Expiration": "2020-01-01T01:00:00Z"
+		}`, result.Output)
+	})
+
+	t.Run("omit session token and expiration for long-lived credentials", func(t *testing.T) {
+		getAWSCredentialsStub := func(_ []awsconfig.Profile, _ time.Duration, _ aws.MFATokenProviderFn) (aws.Credentials, error) {
+			return aws.Credentials{
+				Value: credentials.Value{
+					AccessKeyID:     "access-key-id",
+					SecretAccessKey: "secret-access-key",
+				},
+			}, nil
+		}
+
+		credentialProcessHandler := setupCredentialProcessHandler(t, []string{"--profile", "profile config_profile_1"}, getAWSCredentialsStub)
+
+		result, err := credentialProcessHandler.Handle(stubGlobalArgumentsForExport("set-config"))
+
+		require.NoError(t, err)
+		require.JSONEq(t, `{"Version":1,"AccessKeyId":"access-key-id","SecretAccessKey":"secret-access-key"}`, result.Output)
+	})
+
+	t.Run("print output parsable as credential process output", func(t *testing.T) {
+		credentialProcessHandler := setupCredentialProcessHandler(t, []string{"--profile", "config_profile_1"}, stubGetAWSCredentials)
+
+		result, err := credentialProcessHandler.Handle(stubGlobalArgumentsForExport("set-config"))
+
+		require.NoError(t, err)
+		var parsed aws.CredentialProcessOutput
+		require.NoError(t, json.Unmarshal([]byte(result.Output), &parsed))
+		require.Equal(t, 1, parsed.Version)
+		require.NotNil(t, parsed.Expiration)
+	})
+
+	t.Run("return error if profile does not match exactly", func(t *testing.T) {
+		credentialProcessHandler := setupCredentialProcessHandler(t, []string{"--profile", "config_profile"}, stubGetAWSCredentials)
+
+		_, err := credentialProcessHandler.Handle(stubGlobalArgumentsForExport("set-config"))
+
+		require.EqualError(t, err, "=== profile [config_profile] not found in config file")
+		require.Equal(t, CategoryNotFound, CategoryOf(err))
+	})
+
+	t.Run("return error if config file is not found", func(t *testing.T) {
+		credentialProcessHandler := setupCredentialProcessHandler(t, []string{"--profile", "config_profile_1"}, stubGetAWSCredentials)
+
+		_, err := credentialProcessHandler.Handle(stubGlobalArgumentsForExport("config_not_exists"))
+
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "Fail to read AWS config file")
+		require.Equal(t, CategoryNotFound, CategoryOf(err))
+	})
+
+	t.Run("return error if failed to get credentials", func(t *testing.T) {
+		getAWSCredentialsStub := func(_ []awsconfig.Profile, _ time.Duration, _ aws.MFATokenProviderFn) (aws.Credentials, error) {
+			return aws.Credentials{}, errors.New("AccessDenied")
+		}
+
+		credentialProcessHandler := setupCredentialProcessHandler(t, []string{"--profile", "config_profile_1"}, getAWSCredentialsStub)
+
+		_, err := credentialProcessHandler.Handle(stubGlobalArgumentsForExport("set-config"))
+
+		require.EqualError(t, err, "AccessDenied")
+		require.Equal(t, CategoryAuthFailed, CategoryOf(err))
+	})
+}
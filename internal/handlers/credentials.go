@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/hpcsc/aws-profile/internal/aws"
+	"github.com/hpcsc/aws-profile/internal/awsconfig"
+	"github.com/hpcsc/aws-profile/internal/cache"
+	"github.com/hpcsc/aws-profile/internal/config"
+	"github.com/hpcsc/aws-profile/internal/io"
+	"gopkg.in/alecthomas/kingpin.v2"
+	"gopkg.in/ini.v1"
+	"strings"
+	"time"
+)
+
+type GetAWSCredentialsFn func([]awsconfig.Profile, time.Duration, aws.MFATokenProviderFn) (aws.Credentials, error)
+type ReadCachedCredentialsFn func(string, time.Duration) (*aws.Credentials, error)
+type WriteCachedCredentialsFn func(cache.Entry) error
+
+// CredentialsGetter gets credentials for commands exporting credentials of a profile, reusing cached credentials and
+// MFA sessions when possible
+type CredentialsGetter struct {
+	GetAWSCredentials      GetAWSCredentialsFn
+	ReadCachedCredentials  ReadCachedCredentialsFn
+	WriteCachedCredentials WriteCachedCredentialsFn
+	GetSessionToken        GetSessionTokenFn
+	WriteToFile            WriteToFileFn
+	LockFile               LockFileFn
+}
+
+// CredentialsArguments are flags of every command getting credentials with CredentialsGetter
+type CredentialsArguments struct {
+	Duration *string
+	NoCache  *bool
+}
+
+// credentialsOptions are options of a single command getting credentials with CredentialsGetter
+type credentialsOptions struct {
+	duration            time.Duration
+	noCache             bool
+	refreshWindow       time.Duration
+	mfaTokenProvider    aws.MFATokenProviderFn
+	credentialsFilePath string
+}
+
+func credentialsFlags(subCommand *kingpin.CmdClause) CredentialsArguments {
+	return CredentialsArguments{
+		Duration: subCommand.Flag("duration", "AWS temporary session token duration, overrides duration_seconds of selected profile. Default to 15m if neither is set. Example of valid duration: 5s, 15m").Short('d').String(),
+		NoCache:  subCommand.Flag("no-cache", "Always get new credentials instead of reusing cached credentials, new credentials are not cached").Bool(),
+	}
+}
+
+func newCredentialsOptions(arguments CredentialsArguments, c *config.Config, mfaToken string, globalArguments GlobalArguments) (credentialsOptions, error) {
+	duration, err := parseDurationArgument(*arguments.Duration)
+	if err != nil {
+		return credentialsOptions{}, err
+	}
+
+	return credentialsOptions{
+		duration:            duration,
+		noCache:             *arguments.NoCache,
+		refreshWindow:       c.CacheRefreshWindowDuration(),
+		mfaTokenProvider:    mfaTokenProvider(c, mfaToken),
+		credentialsFilePath: globalArguments.CredentialsFilePath,
+	}, nil
+}
+
+func loadProfilesForCredentials(globalArguments GlobalArguments) (awsconfig.Profiles, error) {
+	configFile, err := io.ReadFile(globalArguments.ConfigFilePath)
+	if err != nil {
+		return awsconfig.Profiles{}, fileReadError("AWS config file", err)
+	}
+
+	// credentials file is optional, it's only used to resolve source profiles of selected profile
+	credentialsFile, err := io.ReadFile(globalArguments.CredentialsFilePath)
+	if err != nil {
+		credentialsFile = ini.Empty()
+	}
+
+	profiles, err := awsconfig.LoadProfilesFromConfigAndCredentials(credentialsFile, configFile)
+	if err != nil {
+		return awsconfig.Profiles{}, withCategory(CategoryConfigInvalid, err)
+	}
+
+	return profiles.WithOriginFiles(globalArguments.CredentialsFilePath, globalArguments.ConfigFilePath), nil
+}
+
+// config file sections have "profile " prefix, allow profile name to be given with or without it
+func findConfigFileProfileExactly(profiles awsconfig.Profiles, name string) *awsconfig.Profile {
+	if name == "" {
+		return nil
+	}
+
+	for _, candidate := range []string{name, "profile " + name} {
+		if profile := profiles.FindConfigFileProfile(candidate); profile != nil {
+			return profile
+		}
+	}
+
+	return nil
+}
+
+// findConfigFileProfileByName finds config file profile with given name, with or without "profile " prefix. Profile is
+// selected from list filtered by given name if no profile matches exactly
+func findConfigFileProfileByName(profiles awsconfig.Profiles, name string, selectProfile SelectProfileFn, config *config.Config) (*awsconfig.Profile, error) {
+	if profile := findConfigFileProfileExactly(profiles, name); profile != nil {
+		return profile, nil
+	}
+
+	selectProfileResult, err := selectProfile(profiles.ConfigFileProfiles(), name, config)
+	if err != nil {
+		return nil, err
+	}
+
+	trimmedSelectedProfileResult := strings.TrimSuffix(string(selectProfileResult), "\n")
+	profile := profiles.FindConfigFileProfile(trimmedSelectedProfileResult)
+	if profile == nil {
+		return nil, newError(CategoryNotFound, "=== profile [%s] not found in config file", trimmedSelectedProfileResult)
+	}
+
+	return profile, nil
+}
+
+// zero duration means duration is not given, duration_seconds of selected profile is used instead
+func parseDurationArgument(durationArgument string) (time.Duration, error) {
+	if durationArgument == "" {
+		return 0, nil
+	}
+
+	duration, err := time.ParseDuration(durationArgument)
+	if err != nil {
+		return 0, withCategory(CategoryUsage, err)
+	}
+
+	if duration < time.Duration(15)*time.Minute {
+		return 0, newError(CategoryUsage, "Minimum duration is 15 minutes")
+	}
+
+	return duration, nil
+}
+
+// failing to read or write cache doesn't fail the command, credentials are retrieved again instead
+func (getter CredentialsGetter) get(chain []awsconfig.Profile, options credentialsOptions) (aws.Credentials, error) {
+	chain, sessionAccessKeyId, err := getter.useMFASession(chain, options)
+	if err != nil {
+		return aws.Credentials{}, err
+	}
+
+	if options.noCache {
+		awsCredentials, err := getter.GetAWSCredentials(chain, options.duration, options.mfaTokenProvider)
+		return awsCredentials, credentialsError(err)
+	}
+
+	cacheKey := cache.Key(chain, options.duration, sessionAccessKeyId)
+	cachedCredentials, readCacheErr := getter.ReadCachedCredentials(cacheKey, options.refreshWindow)
+	if readCacheErr == nil && cachedCredentials != nil {
+		return *cachedCredentials, nil
+	}
+
+	awsCredentials, err := getter.GetAWSCredentials(chain, options.duration, options.mfaTokenProvider)
+	if err != nil {
+		return aws.Credentials{}, credentialsError(err)
+	}
+
+	if !awsCredentials.Expiration.IsZero() {
+		selected := chain[len(chain)-1]
+		_ = getter.WriteCachedCredentials(cache.Entry{
+			Key:         cacheKey,
+			ProfileName: selected.ProfileName,
+			RoleArn:     selected.RoleArn,
+			Credentials: awsCredentials,
+		})
+	}
+
+	return awsCredentials, nil
+}
+
+// credentialsError is ConfigInvalid when credentials can't be requested because of profile configuration, otherwise
+// credentials are rejected by AWS
+func credentialsError(err error) error {
+	var configErr *aws.ConfigError
+	if errors.As(err, &configErr) {
+		return withCategory(CategoryConfigInvalid, err)
+	}
+
+	return withCategory(CategoryAuthFailed, err)
+}
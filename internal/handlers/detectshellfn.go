@@ -0,0 +1,3 @@
+package handlers
+
+type DetectShellFn func() string
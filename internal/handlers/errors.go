@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"github.com/hpcsc/aws-profile/internal/utils"
+	"os"
+)
+
+// ErrorCategory tells what kind of failure a command has, each category has its own exit code
+type ErrorCategory int
+
+const (
+	CategoryGeneral ErrorCategory = iota
+	CategoryUsage
+	CategoryNotFound
+	CategoryConfigInvalid
+	CategoryAuthFailed
+	CategoryCancelled
+	CategoryCommandNotFound
+)
+
+// exit codes of aws-profile, keep in sync with the table in README
+const (
+	ExitCodeSuccess         = 0
+	ExitCodeGeneral         = 1
+	ExitCodeUsage           = 2
+	ExitCodeNotFound        = 3
+	ExitCodeConfigInvalid   = 4
+	ExitCodeAuthFailed      = 5
+	ExitCodeCommandNotFound = 127
+	ExitCodeCancelled       = 130
+)
+
+var exitCodes = map[ErrorCategory]int{
+	CategoryGeneral:         ExitCodeGeneral,
+	CategoryUsage:           ExitCodeUsage,
+	CategoryNotFound:        ExitCodeNotFound,
+	CategoryConfigInvalid:   ExitCodeConfigInvalid,
+	CategoryAuthFailed:      ExitCodeAuthFailed,
+	CategoryCommandNotFound: ExitCodeCommandNotFound,
+	CategoryCancelled:       ExitCodeCancelled,
+}
+
+// Error is an error returned by a handler with its category
+type Error struct {
+	Category ErrorCategory
+	Err      error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func newError(category ErrorCategory, format string, args ...interface{}) error {
+	return &Error{Category: category, Err: fmt.Errorf(format, args...)}
+}
+
+// withCategory sets category of given error, nil stays nil
+func withCategory(category ErrorCategory, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &Error{Category: category, Err: err}
+}
+
+// fileReadError is NotFound when the file doesn't exist, otherwise the file can't be parsed
+func fileReadError(fileDescription string, err error) error {
+	if os.IsNotExist(err) {
+		return newError(CategoryNotFound, "Fail to read %s: %v", fileDescription, err)
+	}
+
+	return newError(CategoryConfigInvalid, "Fail to read %s: %v", fileDescription, err)
+}
+
+// CategoryOf returns category of the outermost categorized error wrapped in given error, errors without category are
+// General
+func CategoryOf(err error) ErrorCategory {
+	var cancelled *utils.CancelledError
+	if errors.As(err, &cancelled) {
+		return CategoryCancelled
+	}
+
+	var categorized *Error
+	if errors.As(err, &categorized) {
+		return categorized.Category
+	}
+
+	return CategoryGeneral
+}
+
+// ExitCode returns exit code of a command failing with given error
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitCodeSuccess
+	}
+
+	return exitCodes[CategoryOf(err)]
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"github.com/hpcsc/aws-profile/internal/utils"
+	"github.com/stretchr/testify/require"
+	"os"
+	"testing"
+)
+
+func TestCategoryOf(t *testing.T) {
+	t.Run("return category of categorized error", func(t *testing.T) {
+		err := newError(CategoryNotFound, "=== profile [%s] not found in config file", "prod")
+
+		require.Equal(t, CategoryNotFound, CategoryOf(err))
+		require.EqualError(t, err, "=== profile [prod] not found in config file")
+	})
+
+	t.Run("return category of categorized error wrapped in another error", func(t *testing.T) {
+		err := fmt.Errorf("Failed to select profile: %w", newError(CategoryUsage, "multiple profiles match [prod]"))
+
+		require.Equal(t, CategoryUsage, CategoryOf(err))
+	})
+
+	t.Run("return cancelled for cancelled error", func(t *testing.T) {
+		err := fmt.Errorf("Failed to select profile: %w", utils.NewCancelledError())
+
+		require.Equal(t, CategoryCancelled, CategoryOf(err))
+	})
+
+	t.Run("return general for error without category", func(t *testing.T) {
+		require.Equal(t, CategoryGeneral, CategoryOf(errors.New("some error")))
+	})
+}
+
+func TestExitCode(t *testing.T) {
+	t.Run("return success exit code for nil error", func(t *testing.T) {
+		require.Equal(t, 0, ExitCode(nil))
+	})
+
+	t.Run("return exit code of error category", func(t *testing.T) {
+		require.Equal(t, 1, ExitCode(errors.New("some error")))
+		require.Equal(t, 2, ExitCode(newError(CategoryUsage, "Minimum duration is 15 minutes")))
+		require.Equal(t, 3, ExitCode(newError(CategoryNotFound, "no profile matches [prod]")))
+		require.Equal(t, 4, ExitCode(withCategory(CategoryConfigInvalid, errors.New("source profile cycle detected"))))
+		require.Equal(t, 5, ExitCode(withCategory(CategoryAuthFailed, errors.New("AccessDenied"))))
+	})
+
+	t.Run("return 130 for cancelled error", func(t *testing.T) {
+		require.Equal(t, 130, ExitCode(utils.NewCancelledError()))
+	})
+}
+
+func TestFileReadError(t *testing.T) {
+	t.Run("return not found if file does not exist", func(t *testing.T) {
+		_, statErr := os.Stat("./test_data/not_exists")
+
+		err := fileReadError("AWS config file", statErr)
+
+		require.Equal(t, CategoryNotFound, CategoryOf(err))
+		require.Contains(t, err.Error(), "Fail to read AWS config file")
+	})
+
+	t.Run("return config invalid if file can't be parsed", func(t *testing.T) {
+		err := fileReadError("AWS config file", errors.New("key-value delimiter not found"))
+
+		require.Equal(t, CategoryConfigInvalid, CategoryOf(err))
+	})
+}
+
+func TestWithCategory(t *testing.T) {
+	t.Run("return nil for nil error", func(t *testing.T) {
+		require.NoError(t, withCategory(CategoryAuthFailed, nil))
+	})
+}
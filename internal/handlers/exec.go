@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/hpcsc/aws-profile/internal/aws"
+	"github.com/hpcsc/aws-profile/internal/awsconfig"
+	"github.com/hpcsc/aws-profile/internal/config"
+	"github.com/hpcsc/aws-profile/internal/process"
+	"gopkg.in/alecthomas/kingpin.v2"
+	"strings"
+	"time"
+)
+
+type RunCommandFn func(string, []string, []string) (int, error)
+type EnvironFn func() []string
+
+type ExecHandler struct {
+	SubCommand        *kingpin.CmdClause
+	Arguments         ExecCommandArguments
+	SelectProfile     SelectProfileFn
+	CredentialsGetter CredentialsGetter
+	RunCommand        RunCommandFn
+	Environ           EnvironFn
+	Config            *config.Config
+}
+
+type ExecCommandArguments struct {
+	Profile  *string
+	Command  *[]string
+	MFAToken *string
+	CredentialsArguments
+}
+
+// environment variables that would make AWS SDKs ignore injected credentials or mix them with another profile
+var conflictingEnvironmentVariables = []string{
+	"AWS_PROFILE",
+	"AWS_DEFAULT_PROFILE",
+	"AWS_ACCESS_KEY_ID",
+	"AWS_SECRET_ACCESS_KEY",
+	"AWS_SESSION_TOKEN",
+	"AWS_SECURITY_TOKEN",
+	"AWS_CREDENTIAL_EXPIRATION",
+}
+
+func NewExecHandler(
+	app *kingpin.Application,
+	config *config.Config,
+	selectProfileFn SelectProfileFn,
+	credentialsGetter CredentialsGetter,
+	runCommandFn RunCommandFn,
+	environFn EnvironFn,
+) ExecHandler {
+	subCommand := app.Command("exec", `run a command with credentials of given profile set in its environment variables
+
+Example: "aws-profile exec prod -- aws s3 ls"`)
+
+	profile := subCommand.Arg("profile", "Name of profile in config file, profile is selected from list filtered by this name if it doesn't match exactly").Required().String()
+	command := subCommand.Arg("command", "Command to run, followed by its arguments").Required().Strings()
+	credentialsArguments := credentialsFlags(subCommand)
+	mfaToken := mfaTokenFlag(subCommand)
+
+	return ExecHandler{
+		SubCommand: subCommand,
+		Arguments: ExecCommandArguments{
+			Profile:              profile,
+			Command:              command,
+			MFAToken:             mfaToken,
+			CredentialsArguments: credentialsArguments,
+		},
+		SelectProfile:     selectProfileFn,
+		CredentialsGetter: credentialsGetter,
+		RunCommand:        runCommandFn,
+		Environ:           environFn,
+		Config:            config,
+	}
+}
+
+func (handler ExecHandler) Handle(globalArguments GlobalArguments) (Result, error) {
+	profiles, err := loadProfilesForCredentials(globalArguments)
+	if err != nil {
+		return Result{}, err
+	}
+
+	options, err := newCredentialsOptions(handler.Arguments.CredentialsArguments, handler.Config, *handler.Arguments.MFAToken, globalArguments)
+	if err != nil {
+		return Result{}, err
+	}
+
+	profile, err := findConfigFileProfileByName(profiles, *handler.Arguments.Profile, handler.SelectProfile, handler.Config)
+	if err != nil {
+		return Result{}, err
+	}
+
+	chain, err := profiles.ResolveSourceChain(profile)
+	if err != nil {
+		return Result{}, withCategory(CategoryConfigInvalid, err)
+	}
+
+	awsCredentials, err := handler.CredentialsGetter.get(chain, options)
+	if err != nil {
+		return Result{}, err
+	}
+
+	command := *handler.Arguments.Command
+	exitCode, err := handler.RunCommand(command[0], command[1:], execEnvironment(handler.Environ(), profile, awsCredentials))
+	// same exit code as shells for commands that can't be found
+	if errors.Is(err, process.ErrCommandNotFound) {
+		return Result{}, withCategory(CategoryCommandNotFound, err)
+	}
+
+	if err != nil {
+		return Result{}, err
+	}
+
+	return Result{ExitCode: exitCode}, nil
+}
+
+func execEnvironment(environ []string, profile *awsconfig.Profile, awsCredentials aws.Credentials) []string {
+	var env []string
+
+	for _, variable := range environ {
+		if !isConflictingEnvironmentVariable(variable) &&
+			(profile.Region == "" || !isRegionEnvironmentVariable(variable)) {
+			env = append(env, variable)
+		}
+	}
+
+	env = append(env,
+		"AWS_ACCESS_KEY_ID="+awsCredentials.AccessKeyID,
+		"AWS_SECRET_ACCESS_KEY="+awsCredentials.SecretAccessKey,
+	)
+
+	if awsCredentials.SessionToken != "" {
+		env = append(env, "AWS_SESSION_TOKEN="+awsCredentials.SessionToken)
+	}
+
+	if !awsCredentials.Expiration.IsZero() {
+		env = append(env, "AWS_CREDENTIAL_EXPIRATION="+awsCredentials.Expiration.UTC().Format(time.RFC3339))
+	}
+
+	if profile.Region != "" {
+		env = append(env,
+			"AWS_REGION="+profile.Region,
+			"AWS_DEFAULT_REGION="+profile.Region,
+		)
+	}
+
+	return env
+}
+
+func isConflictingEnvironmentVariable(variable string) bool {
+	for _, name := range conflictingEnvironmentVariables {
+		if strings.HasPrefix(variable, name+"=") {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isRegionEnvironmentVariable(variable string) bool {
+	return strings.HasPrefix(variable, "AWS_REGION=") || strings.HasPrefix(variable, "AWS_DEFAULT_REGION=")
+}
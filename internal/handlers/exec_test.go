@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"github.com/hpcsc/aws-profile/internal/aws"
+	"github.com/hpcsc/aws-profile/internal/awsconfig"
+	"github.com/hpcsc/aws-profile/internal/config"
+	"github.com/hpcsc/aws-profile/internal/process"
+	"github.com/hpcsc/aws-profile/internal/utils"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/alecthomas/kingpin.v2"
+	"testing"
+	"time"
+)
+
+func stubEnviron() []string {
+	return []string{
+		"PATH=/usr/bin",
+		"AWS_PROFILE=another-profile",
+		"AWS_ACCESS_KEY_ID=another-access-key-id",
+		"AWS_REGION=us-east-1",
+	}
+}
+
+func setupExecHandler(t *testing.T, arguments []string, selectProfileFn SelectProfileFn, getAWSCredentialsFn GetAWSCredentialsFn, runCommandFn RunCommandFn) ExecHandler {
+	app := kingpin.New("some-app", "some description")
+	execHandler := NewExecHandler(app, stubConfig(), selectProfileFn, stubCredentialsGetter(getAWSCredentialsFn), runCommandFn, stubEnviron)
+
+	if _, err := app.Parse(append([]string{"exec"}, arguments...)); err != nil {
+		t.Fatalf("failed to setup test exec handler: %v\n", err)
+	}
+
+	return execHandler
+}
+
+func TestExecHandler(t *testing.T) {
+	noopRunCommand := func(_ string, _ []string, _ []string) (int, error) {
+		return 0, nil
+	}
+
+	selectProfileNotExpected := func(profiles awsconfig.Profiles, pattern string, c *config.Config) ([]byte, error) {
+		require.Fail(t, "unexpected call to SelectProfile")
+		return nil, nil
+	}
+
+	t.Run("return error if config file is not found", func(t *testing.T) {
+		execHandler := setupExecHandler(t, []string{"config_profile_1", "--", "aws"}, nil, nil, nil)
+
+		_, err := execHandler.Handle(stubGlobalArgumentsForExport("config_not_exists"))
+
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "Fail to read AWS config file")
+	})
+
+	t.Run("run command with credentials of profile matching given name exactly without selecting profile", func(t *testing.T) {
+		var calledName string
+		var calledArgs []string
+		var calledEnv []string
+		runCommandMock := func(name string, args []string, env []string) (int, error) {
+			calledName = name
+			calledArgs = args
+			calledEnv = env
+			return 0, nil
+		}
+
+		execHandler := setupExecHandler(t, []string{"config_profile_2", "--", "aws", "s3", "ls"}, selectProfileNotExpected, stubGetAWSCredentials, runCommandMock)
+
+		result, err := execHandler.Handle(stubGlobalArgumentsForExport("set-config"))
+
+		require.NoError(t, err)
+		require.Empty(t, result.Output)
+		require.Equal(t, "aws", calledName)
+		require.Equal(t, []string{"s3", "ls"}, calledArgs)
+		require.Contains(t, calledEnv, "PATH=/usr/bin")
+		require.Contains(t, calledEnv, "AWS_ACCESS_KEY_ID=access-key-id")
+		require.Contains(t, calledEnv, "AWS_SECRET_ACCESS_KEY=secret-access-key")
+		require.Contains(t, calledEnv, "AWS_SESSION_TOKEN=session-token")
+		require.Contains(t, calledEnv, "AWS_REGION=us-west-2")
+		require.Contains(t, calledEnv, "AWS_DEFAULT_REGION=us-west-2")
+		require.NotContains(t, calledEnv, "AWS_PROFILE=another-profile")
+		require.NotContains(t, calledEnv, "AWS_ACCESS_KEY_ID=another-access-key-id")
+		require.NotContains(t, calledEnv, "AWS_REGION=us-east-1")
+	})
+
+	t.Run("keep region environment variable if profile has no region", func(t *testing.T) {
+		var calledEnv []string
+		runCommandMock := func(_ string, _ []string, env []string) (int, error) {
+			calledEnv = env
+			return 0, nil
+		}
+
+		execHandler := setupExecHandler(t, []string{"profile config_profile_1", "--", "aws"}, selectProfileNotExpected, stubGetAWSCredentials, runCommandMock)
+
+		_, err := execHandler.Handle(stubGlobalArgumentsForExport("set-config"))
+
+		require.NoError(t, err)
+		require.Contains(t, calledEnv, "AWS_REGION=us-east-1")
+	})
+
+	t.Run("select profile filtered by given name if it does not match any profile exactly", func(t *testing.T) {
+		selectProfileMock := func(profiles awsconfig.Profiles, pattern string, c *config.Config) ([]byte, error) {
+			require.Equal(t, "config_profile", pattern)
+			return []byte("profile config_profile_1"), nil
+		}
+
+		var calledChain []awsconfig.Profile
+		getAWSCredentialsMock := func(chain []awsconfig.Profile, _ time.Duration, _ aws.MFATokenProviderFn) (aws.Credentials, error) {
+			calledChain = chain
+			return stubAWSCredentials(), nil
+		}
+
+		execHandler := setupExecHandler(t, []string{"config_profile", "--", "aws"}, selectProfileMock, getAWSCredentialsMock, noopRunCommand)
+
+		_, err := execHandler.Handle(stubGlobalArgumentsForExport("set-config"))
+
+		require.NoError(t, err)
+		require.Equal(t, "profile config_profile_1", calledChain[len(calledChain)-1].ProfileName)
+	})
+
+	t.Run("return cancelled error without running command if selecting profile is cancelled", func(t *testing.T) {
+		selectProfileMock := func(profiles awsconfig.Profiles, pattern string, c *config.Config) ([]byte, error) {
+			return nil, utils.NewCancelledError()
+		}
+
+		runCommandMock := func(_ string, _ []string, _ []string) (int, error) {
+			require.Fail(t, "unexpected call to RunCommand")
+			return 0, nil
+		}
+
+		execHandler := setupExecHandler(t, []string{"not_exists", "--", "aws"}, selectProfileMock, stubGetAWSCredentials, runCommandMock)
+
+		_, err := execHandler.Handle(stubGlobalArgumentsForExport("set-config"))
+
+		require.Equal(t, CategoryCancelled, CategoryOf(err))
+	})
+
+	t.Run("return error if failed to get credentials", func(t *testing.T) {
+		getAWSCredentialsStub := func(_ []awsconfig.Profile, _ time.Duration, _ aws.MFATokenProviderFn) (aws.Credentials, error) {
+			return aws.Credentials{}, errors.New("AccessDenied")
+		}
+
+		execHandler := setupExecHandler(t, []string{"config_profile_1", "--", "aws"}, selectProfileNotExpected, getAWSCredentialsStub, noopRunCommand)
+
+		_, err := execHandler.Handle(stubGlobalArgumentsForExport("set-config"))
+
+		require.Error(t, err)
+		require.Equal(t, "AccessDenied", err.Error())
+	})
+
+	t.Run("return exit code of command", func(t *testing.T) {
+		runCommandStub := func(_ string, _ []string, _ []string) (int, error) {
+			return 3, nil
+		}
+
+		execHandler := setupExecHandler(t, []string{"config_profile_1", "--", "aws"}, selectProfileNotExpected, stubGetAWSCredentials, runCommandStub)
+
+		result, err := execHandler.Handle(stubGlobalArgumentsForExport("set-config"))
+
+		require.NoError(t, err)
+		require.Equal(t, 3, result.ExitCode)
+	})
+
+	t.Run("return error if command failed to start", func(t *testing.T) {
+		runCommandStub := func(name string, _ []string, _ []string) (int, error) {
+			return 0, fmt.Errorf("failed to start %s", name)
+		}
+
+		execHandler := setupExecHandler(t, []string{"config_profile_1", "--", "not-exists"}, selectProfileNotExpected, stubGetAWSCredentials, runCommandStub)
+
+		_, err := execHandler.Handle(stubGlobalArgumentsForExport("set-config"))
+
+		require.Error(t, err)
+		require.Equal(t, "failed to start not-exists", err.Error())
+	})
+
+	t.Run("return command not found error if command does not exist", func(t *testing.T) {
+		runCommandStub := func(name string, _ []string, _ []string) (int, error) {
+			return 0, fmt.Errorf("failed to start %s: %w", name, process.ErrCommandNotFound)
+		}
+
+		execHandler := setupExecHandler(t, []string{"config_profile_1", "--", "not-exists"}, selectProfileNotExpected, stubGetAWSCredentials, runCommandStub)
+
+		_, err := execHandler.Handle(stubGlobalArgumentsForExport("set-config"))
+
+		require.Error(t, err)
+		require.Equal(t, ExitCodeCommandNotFound, ExitCode(err))
+	})
+}
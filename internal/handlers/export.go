@@ -1,126 +1,307 @@
 package handlers
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/hpcsc/aws-profile/internal/aws"
 	"github.com/hpcsc/aws-profile/internal/awsconfig"
 	"github.com/hpcsc/aws-profile/internal/config"
 	"github.com/hpcsc/aws-profile/internal/io"
+	"github.com/hpcsc/aws-profile/internal/shell"
 	"gopkg.in/alecthomas/kingpin.v2"
 	"gopkg.in/ini.v1"
 	"strings"
 	"time"
 )
 
-type GetAWSCredentialsFn func(*awsconfig.Profile, time.Duration) (credentials.Value, error)
-
 type ExportHandler struct {
 	SubCommand        *kingpin.CmdClause
-	IsWindows         bool
+	DetectShell       DetectShellFn
 	SelectProfile     SelectProfileFn
-	GetAWSCredentials GetAWSCredentialsFn
+	CredentialsGetter CredentialsGetter
+	RecordHistory     RecordHistoryFn
 	Arguments         ExportCommandArguments
 	Config            *config.Config
 }
 
+const (
+	exportFormatShell  = "shell"
+	exportFormatJSON   = "json"
+	exportFormatDotenv = "dotenv"
+	exportFormatINI    = "ini"
+)
+
+type exportJSONOutput struct {
+	AccessKeyId     string
+	SecretAccessKey string
+	SessionToken    string     `json:",omitempty"`
+	Expiration      *time.Time `json:",omitempty"`
+	Region          string     `json:",omitempty"`
+}
+
 type ExportCommandArguments struct {
-	Pattern  *string
-	Duration *string
+	Pattern    *string
+	Shell      *string
+	Format     *string
+	ToProfile  *string
+	SetDefault *bool
+	MFAToken   *string
+	CredentialsArguments
 }
 
-func NewExportHandler(app *kingpin.Application, config *config.Config, isWindows bool, selectProfileFn SelectProfileFn, getAWSCredentialsFn GetAWSCredentialsFn) ExportHandler {
+func NewExportHandler(
+	app *kingpin.Application,
+	config *config.Config,
+	detectShellFn DetectShellFn,
+	selectProfileFn SelectProfileFn,
+	credentialsGetter CredentialsGetter,
+	recordHistoryFn RecordHistoryFn,
+) ExportHandler {
 	subCommand := app.Command("export", `print commands to set environment variables for assuming a AWS role
 
 To execute the command without printing it to console:
 
 - For Linux/MacOS, execute: "eval $(aws-profile export)"
 
-- For Windows, execute: "Invoke-Expression (path\to\aws-profile.exe export)"`)
+- For Windows, execute: "Invoke-Expression (path\to\aws-profile.exe export)"
+
+- For fish, execute: "eval (aws-profile export)"
+
+Commands are printed for the shell detected from parent process or $SHELL, use --shell to choose another shell`)
 
 	pattern := subCommand.Arg("pattern", "Filter profiles by given pattern").String()
-	duration := subCommand.Flag("duration", "AWS temporary session token duration. Example of valid duration: 5s, 15m").Short('d').Default("15m").String()
+	shellName := shellFlag(subCommand)
+	format := subCommand.Flag("format", "Output format: shell commands (default), JSON, dotenv file for docker --env-file or credentials file profile").Enum(exportFormatShell, exportFormatJSON, exportFormatDotenv, exportFormatINI)
+	toProfile := subCommand.Flag("to-profile", "Write credentials to given profile in credentials file instead of printing them, for tools that can't read environment variables").String()
+	setDefault := subCommand.Flag("set-default", "Also set profile given by --to-profile as default profile").Bool()
+	credentialsArguments := credentialsFlags(subCommand)
+	mfaToken := mfaTokenFlag(subCommand)
 
 	return ExportHandler{
 		SubCommand:        subCommand,
-		IsWindows:         isWindows,
+		DetectShell:       detectShellFn,
 		SelectProfile:     selectProfileFn,
-		GetAWSCredentials: getAWSCredentialsFn,
+		CredentialsGetter: credentialsGetter,
+		RecordHistory:     recordHistoryFn,
 		Arguments: ExportCommandArguments{
-			Pattern:  pattern,
-			Duration: duration,
+			Pattern:              pattern,
+			Shell:                shellName,
+			Format:               format,
+			ToProfile:            toProfile,
+			SetDefault:           setDefault,
+			MFAToken:             mfaToken,
+			CredentialsArguments: credentialsArguments,
 		},
 		Config: config,
 	}
 }
 
-func (handler ExportHandler) Handle(globalArguments GlobalArguments) (bool, string) {
-	configFile, readConfigErr := io.ReadFile(globalArguments.ConfigFilePath)
-	if readConfigErr != nil {
-		return false, fmt.Sprintf("Fail to read AWS config file: %v", readConfigErr)
+func (handler ExportHandler) Handle(globalArguments GlobalArguments) (Result, error) {
+	if *handler.Arguments.SetDefault && *handler.Arguments.ToProfile == "" {
+		return Result{}, newError(CategoryUsage, "--set-default requires --to-profile")
+	}
+
+	if *handler.Arguments.Format != "" && *handler.Arguments.ToProfile != "" {
+		return Result{}, newError(CategoryUsage, "--format can't be used with --to-profile")
 	}
 
-	var duration, parseDurationErr = time.ParseDuration(*handler.Arguments.Duration)
-	if parseDurationErr != nil {
-		return false, parseDurationErr.Error()
+	profiles, loadProfilesErr := loadProfilesForCredentials(globalArguments)
+	if loadProfilesErr != nil {
+		return Result{}, loadProfilesErr
 	}
 
-	if duration < time.Duration(15)*time.Minute {
-		return false, "Minimum duration is 15 minutes"
+	options, parseOptionsErr := newCredentialsOptions(handler.Arguments.CredentialsArguments, handler.Config, *handler.Arguments.MFAToken, globalArguments)
+	if parseOptionsErr != nil {
+		return Result{}, parseOptionsErr
 	}
 
-	profiles := awsconfig.LoadProfilesFromConfigAndCredentials(ini.Empty(), configFile)
+	formatter, getFormatterErr := shellFormatter(*handler.Arguments.Shell, handler.DetectShell)
+	if getFormatterErr != nil {
+		return Result{}, getFormatterErr
+	}
 
-	selectProfileResult, selectProfileErr := handler.SelectProfile(profiles, *handler.Arguments.Pattern, handler.Config)
+	selectProfileResult, selectProfileErr := handler.SelectProfile(profiles.ConfigFileProfiles(), *handler.Arguments.Pattern, handler.Config)
 	if selectProfileErr != nil {
-		// cancel by user
-		return true, ""
+		return Result{}, fmt.Errorf("Failed to select profile: %w", selectProfileErr)
 	}
 
 	trimmedSelectedProfileResult := strings.TrimSuffix(string(selectProfileResult), "\n")
-	profile := profiles.FindProfileInConfigFile(trimmedSelectedProfileResult)
+	profile := profiles.FindConfigFileProfile(trimmedSelectedProfileResult)
+	if profile == nil {
+		return Result{}, newError(CategoryNotFound, "=== profile [%s] not found in config file", trimmedSelectedProfileResult)
+	}
 
-	credentialsValue, getCredentialsErr := handler.GetAWSCredentials(profile, duration)
+	chain, resolveChainErr := profiles.ResolveSourceChain(profile)
+	if resolveChainErr != nil {
+		return Result{}, withCategory(CategoryConfigInvalid, resolveChainErr)
+	}
+
+	awsCredentials, getCredentialsErr := handler.CredentialsGetter.get(chain, options)
 	if getCredentialsErr != nil {
-		return false, getCredentialsErr.Error()
+		return Result{}, getCredentialsErr
 	}
 
-	if handler.IsWindows {
-		return true, formatOutputForWindows(credentialsValue, profile)
-	} else {
-		return true, formatOutputForLinuxAndMacOS(credentialsValue, profile)
+	result, err := handler.output(globalArguments, awsCredentials, profile, formatter)
+	if err != nil {
+		return Result{}, err
 	}
+
+	recordHistory(handler.RecordHistory, profile.ProfileName)
+
+	return result, nil
 }
 
-func formatOutputForWindows(credentialsValue credentials.Value, profile *awsconfig.Profile) string {
-	output := fmt.Sprintf("$env:AWS_ACCESS_KEY_ID = '%s'; $env:AWS_SECRET_ACCESS_KEY = '%s'; $env:AWS_SESSION_TOKEN = '%s'",
-		credentialsValue.AccessKeyID,
-		credentialsValue.SecretAccessKey,
-		credentialsValue.SessionToken,
-	)
+func (handler ExportHandler) output(globalArguments GlobalArguments, awsCredentials aws.Credentials, profile *awsconfig.Profile, formatter shell.Formatter) (Result, error) {
+	if *handler.Arguments.ToProfile != "" {
+		return handler.writeToProfile(globalArguments, awsCredentials, profile)
+	}
+
+	switch *handler.Arguments.Format {
+	case exportFormatJSON:
+		return formatJSONOutput(awsCredentials, profile)
+	case exportFormatDotenv:
+		return Result{Output: formatDotenvOutput(credentialsVariables(awsCredentials.Value, profile))}, nil
+	case exportFormatINI:
+		return formatINIOutput(awsCredentials, profile)
+	default:
+		return Result{Output: formatter.Set(credentialsVariables(awsCredentials.Value, profile))}, nil
+	}
+}
+
+func credentialsVariables(credentialsValue credentials.Value, profile *awsconfig.Profile) []shell.Variable {
+	variables := []shell.Variable{
+		{Name: "AWS_ACCESS_KEY_ID", Value: credentialsValue.AccessKeyID},
+		{Name: "AWS_SECRET_ACCESS_KEY", Value: credentialsValue.SecretAccessKey},
+		{Name: "AWS_SESSION_TOKEN", Value: credentialsValue.SessionToken},
+	}
 
 	if profile.Region == "" {
-		return output
+		return variables
 	}
 
-	return fmt.Sprintf("%s; $env:AWS_REGION = '%s'; $env:AWS_DEFAULT_REGION = '%s'",
-		output,
-		profile.Region,
-		profile.Region)
+	return append(variables,
+		shell.Variable{Name: "AWS_REGION", Value: profile.Region},
+		shell.Variable{Name: "AWS_DEFAULT_REGION", Value: profile.Region},
+	)
 }
 
-func formatOutputForLinuxAndMacOS(credentialsValue credentials.Value, profile *awsconfig.Profile) string {
-	output := fmt.Sprintf("export AWS_ACCESS_KEY_ID='%s' AWS_SECRET_ACCESS_KEY='%s' AWS_SESSION_TOKEN='%s'",
-		credentialsValue.AccessKeyID,
-		credentialsValue.SecretAccessKey,
-		credentialsValue.SessionToken,
-	)
+func formatJSONOutput(awsCredentials aws.Credentials, profile *awsconfig.Profile) (Result, error) {
+	output := exportJSONOutput{
+		AccessKeyId:     awsCredentials.AccessKeyID,
+		SecretAccessKey: awsCredentials.SecretAccessKey,
+		SessionToken:    awsCredentials.SessionToken,
+		Region:          profile.Region,
+	}
 
-	if profile.Region == "" {
-		return output
+	if !awsCredentials.Expiration.IsZero() {
+		expiration := awsCredentials.Expiration.UTC()
+		output.Expiration = &expiration
+	}
+
+	content, err := json.Marshal(output)
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to marshal credentials: %v", err)
+	}
+
+	return Result{Output: string(content)}, nil
+}
+
+// docker --env-file takes everything after "=" as value, values must not be quoted
+func formatDotenvOutput(variables []shell.Variable) string {
+	var lines []string
+	for _, variable := range variables {
+		lines = append(lines, variable.Name+"="+variable.Value)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// credentials file sections don't have "profile " prefix of config file sections
+func formatINIOutput(awsCredentials aws.Credentials, profile *awsconfig.Profile) (Result, error) {
+	file := ini.Empty()
+	setCredentialsFileKeys(file.Section(strings.TrimPrefix(profile.ProfileName, "profile ")), awsCredentials)
+
+	var buffer bytes.Buffer
+	if _, err := file.WriteTo(&buffer); err != nil {
+		return Result{}, fmt.Errorf("fail to write to buffer: %v", err)
+	}
+
+	return Result{Output: strings.TrimSpace(buffer.String())}, nil
+}
+
+// expiration is not used by AWS SDKs, it tells users and scripts when credentials need to be exported again
+func setCredentialsFileKeys(section *ini.Section, awsCredentials aws.Credentials) {
+	section.Key("aws_access_key_id").SetValue(awsCredentials.AccessKeyID)
+	section.Key("aws_secret_access_key").SetValue(awsCredentials.SecretAccessKey)
+
+	if awsCredentials.SessionToken != "" {
+		section.Key("aws_session_token").SetValue(awsCredentials.SessionToken)
+	} else {
+		section.DeleteKey("aws_session_token")
+	}
+
+	if !awsCredentials.Expiration.IsZero() {
+		section.Key("expiration").SetValue(awsCredentials.Expiration.UTC().Format(time.RFC3339))
+	} else {
+		section.DeleteKey("expiration")
+	}
+}
+
+func (handler ExportHandler) writeToProfile(globalArguments GlobalArguments, awsCredentials aws.Credentials, profile *awsconfig.Profile) (Result, error) {
+	targetProfileName := *handler.Arguments.ToProfile
+
+	filePaths := []string{globalArguments.CredentialsFilePath}
+	if *handler.Arguments.SetDefault {
+		filePaths = append(filePaths, globalArguments.ConfigFilePath)
+	}
+
+	unlock, err := lockFiles(handler.CredentialsGetter.LockFile, filePaths...)
+	if err != nil {
+		return Result{}, err
+	}
+	defer unlock()
+
+	credentialsFile, err := readCredentialsFileOrEmpty(globalArguments.CredentialsFilePath)
+	if err != nil {
+		return Result{}, err
+	}
+
+	// temporary credentials always have session token, profile without it has long-term credentials that can't be recovered
+	if existing, err := credentialsFile.GetSection(targetProfileName); err == nil &&
+		existing.HasKey("aws_access_key_id") &&
+		!existing.HasKey("aws_session_token") {
+		return Result{}, newError(CategoryUsage, "=== profile [%s] in credentials file has long-term credentials, refusing to overwrite it", targetProfileName)
+	}
+
+	setCredentialsFileKeys(credentialsFile.Section(targetProfileName), awsCredentials)
+
+	if !*handler.Arguments.SetDefault {
+		if err := handler.CredentialsGetter.WriteToFile(credentialsFile, globalArguments.CredentialsFilePath); err != nil {
+			return Result{}, err
+		}
+
+		return Result{Output: fmt.Sprintf("=== [%s] -> [%s] (%s)", profile.ProfileName, targetProfileName, globalArguments.CredentialsFilePath)}, nil
+	}
+
+	configFile, err := io.ReadFile(globalArguments.ConfigFilePath)
+	if err != nil {
+		return Result{}, fileReadError("AWS config file", err)
+	}
+
+	awsconfig.SetSelectedProfileAsDefault(targetProfileName, credentialsFile, configFile)
+	if profile.Region != "" {
+		awsconfig.SetSelectedRegionAsDefault(profile.Region, configFile)
+	}
+
+	if err := handler.CredentialsGetter.WriteToFile(credentialsFile, globalArguments.CredentialsFilePath); err != nil {
+		return Result{}, err
+	}
+
+	if err := handler.CredentialsGetter.WriteToFile(configFile, globalArguments.ConfigFilePath); err != nil {
+		return Result{}, err
 	}
 
-	return fmt.Sprintf("%s AWS_REGION='%s' AWS_DEFAULT_REGION='%s'",
-		output,
-		profile.Region,
-		profile.Region)
+	return Result{Output: fmt.Sprintf("=== [%s] -> [%s] -> [default] (%s)", profile.ProfileName, targetProfileName, globalArguments.CredentialsFilePath)}, nil
 }
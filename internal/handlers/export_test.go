@@ -1,49 +1,89 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/hpcsc/aws-profile/internal/aws"
 	"github.com/hpcsc/aws-profile/internal/awsconfig"
+	"github.com/hpcsc/aws-profile/internal/cache"
 	"github.com/hpcsc/aws-profile/internal/config"
 	"github.com/stretchr/testify/require"
 	"gopkg.in/alecthomas/kingpin.v2"
+	"gopkg.in/ini.v1"
 	"os"
 	"path/filepath"
+	"runtime"
 	"testing"
 	"time"
 )
 
-func stubAWSCredentials() credentials.Value {
-	return credentials.Value{
-		AccessKeyID:     "access-key-id",
-		SecretAccessKey: "secret-access-key",
-		SessionToken:    "session-token",
-		ProviderName:    "stubbed-provider",
+func stubAWSCredentials() aws.Credentials {
+	return aws.Credentials{
+		Value: credentials.Value{
+			AccessKeyID:     "access-key-id",
+			SecretAccessKey: "secret-access-key",
+			SessionToken:    "session-token",
+			ProviderName:    "stubbed-provider",
+		},
+		Expiration: time.Now().Add(time.Hour),
 	}
 }
 
-func stubGetAWSCredentials(_ *awsconfig.Profile, _ time.Duration) (credentials.Value, error) {
+func stubGetAWSCredentials(_ []awsconfig.Profile, _ time.Duration, _ aws.MFATokenProviderFn) (aws.Credentials, error) {
 	return stubAWSCredentials(), nil
 }
 
+func noopReadCachedCredentials(_ string, _ time.Duration) (*aws.Credentials, error) {
+	return nil, nil
+}
+
+func noopWriteCachedCredentials(_ cache.Entry) error {
+	return nil
+}
+
+// stubCredentialsGetter neither caches credentials nor writes or locks files
+func stubCredentialsGetter(getAWSCredentialsFn GetAWSCredentialsFn) CredentialsGetter {
+	return CredentialsGetter{
+		GetAWSCredentials:      getAWSCredentialsFn,
+		ReadCachedCredentials:  noopReadCachedCredentials,
+		WriteCachedCredentials: noopWriteCachedCredentials,
+		WriteToFile:            noopWriteToFileMock,
+		LockFile:               noopLockFile,
+	}
+}
+
+func stubDetectShell(isWindows bool) DetectShellFn {
+	return func() string {
+		if isWindows {
+			return "powershell"
+		}
+
+		return "sh"
+	}
+}
+
 func stubGlobalArgumentsForExport(configName string) GlobalArguments {
+	testCredentialsPath, _ := filepath.Abs("./test_data/export-credentials")
 	testConfigPath, _ := filepath.Abs("./test_data/" + configName)
 
 	return GlobalArguments{
-		ConfigFilePath: testConfigPath,
+		CredentialsFilePath: testCredentialsPath,
+		ConfigFilePath:      testConfigPath,
 	}
 }
 
 func stubConfig() *config.Config {
 	return &config.Config{
-		HighlightColor: config.DefaultHighlightColor(),
-		Regions:        config.DefaultRegions(),
+		HighlightColor:     config.DefaultHighlightColor(),
+		Regions:            config.DefaultRegions(),
+		CacheRefreshWindow: config.DefaultCacheRefreshWindow(),
 	}
 }
 
 func setupExportHandler(isWindows bool, selectProfileFn SelectProfileFn, getAWSCredentialsFn GetAWSCredentialsFn) ExportHandler {
 	app := kingpin.New("some-app", "some description")
-	exportHandler := NewExportHandler(app, stubConfig(), isWindows, selectProfileFn, getAWSCredentialsFn)
+	exportHandler := NewExportHandler(app, stubConfig(), stubDetectShell(isWindows), selectProfileFn, stubCredentialsGetter(getAWSCredentialsFn), noopRecordHistory)
 
 	if _, err := app.Parse([]string{"export"}); err != nil {
 		fmt.Printf("failed to setup test export handler: %v\n", err)
@@ -62,10 +102,10 @@ func TestExportHandler(t *testing.T) {
 		)
 		globalArguments := stubGlobalArgumentsForExport("config_not_exists")
 
-		success, output := exportHandler.Handle(globalArguments)
+		_, err := exportHandler.Handle(globalArguments)
 
-		require.False(t, success)
-		require.Contains(t, output, "Fail to read AWS config file")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "Fail to read AWS config file")
 	})
 
 	t.Run("invoke SelectProfile with profile names from config file only", func(t *testing.T) {
@@ -78,6 +118,9 @@ func TestExportHandler(t *testing.T) {
 				[]string{
 					"assume profile config_profile_1",
 					"assume profile config_profile_2",
+					"sso profile sso_profile_1",
+					"process profile process_profile_1",
+					"web-identity profile web_identity_profile_1",
 				},
 			)
 
@@ -92,17 +135,233 @@ func TestExportHandler(t *testing.T) {
 		)
 		globalArguments := stubGlobalArgumentsForExport("set-config")
 
-		success, _ := exportHandler.Handle(globalArguments)
+		_, err := exportHandler.Handle(globalArguments)
 
-		require.True(t, success)
+		require.NoError(t, err)
 		if !called {
 			t.Errorf("selectProfileFn is not invoked")
 		}
 	})
 
+	var profileKinds = []struct {
+		name            string
+		configName      string
+		selectedProfile string
+		assert          func(t *testing.T, profile awsconfig.Profile, output string)
+	}{
+		{
+			"sso profile",
+			"set-config",
+			"profile sso_profile_1",
+			func(t *testing.T, profile awsconfig.Profile, output string) {
+				require.True(t, profile.IsSSO())
+				require.Equal(t, "123456789012", profile.SSOAccountId)
+				require.Equal(t, "export AWS_ACCESS_KEY_ID='access-key-id' AWS_SECRET_ACCESS_KEY='secret-access-key' AWS_SESSION_TOKEN='session-token' AWS_REGION='ap-southeast-2' AWS_DEFAULT_REGION='ap-southeast-2'", output)
+			},
+		},
+		{
+			"credential source profile",
+			"chain-config",
+			"profile credential_source_profile",
+			func(t *testing.T, profile awsconfig.Profile, output string) {
+				require.Equal(t, "Ec2InstanceMetadata", profile.CredentialSource)
+			},
+		},
+		{
+			"credential process profile",
+			"chain-config",
+			"profile process_profile",
+			func(t *testing.T, profile awsconfig.Profile, output string) {
+				require.Equal(t, "vault-creds --role admin", profile.CredentialProcess)
+				require.Contains(t, output, "AWS_REGION='eu-west-1'")
+			},
+		},
+		{
+			"web identity profile",
+			"chain-config",
+			"profile web_identity_profile",
+			func(t *testing.T, profile awsconfig.Profile, output string) {
+				require.Equal(t, "/var/run/secrets/eks.amazonaws.com/serviceaccount/token", profile.WebIdentityTokenFile)
+			},
+		},
+	}
+
+	for _, tt := range profileKinds {
+		t.Run("call GetAWSCredentials with selected "+tt.name, func(t *testing.T) {
+			selectProfileMock := func(profiles awsconfig.Profiles, pattern string, c *config.Config) ([]byte, error) {
+				return []byte(tt.selectedProfile), nil
+			}
+
+			var calledChain []awsconfig.Profile
+			getAWSCredentialsMock := func(chain []awsconfig.Profile, _ time.Duration, _ aws.MFATokenProviderFn) (aws.Credentials, error) {
+				calledChain = chain
+				return stubAWSCredentials(), nil
+			}
+
+			exportHandler := setupExportHandler(
+				false,
+				selectProfileMock,
+				getAWSCredentialsMock,
+			)
+			globalArguments := stubGlobalArgumentsForExport(tt.configName)
+
+			result, err := exportHandler.Handle(globalArguments)
+
+			require.NoError(t, err)
+			require.Equal(t, 1, len(calledChain))
+			tt.assert(t, calledChain[0], result.Output)
+		})
+	}
+
+	t.Run("call GetAWSCredentials with source profile chain of selected profile", func(t *testing.T) {
+		selectProfileMock := func(profiles awsconfig.Profiles, pattern string, c *config.Config) ([]byte, error) {
+			return []byte("profile chained_profile"), nil
+		}
+
+		var calledChain []awsconfig.Profile
+		getAWSCredentialsMock := func(chain []awsconfig.Profile, _ time.Duration, _ aws.MFATokenProviderFn) (aws.Credentials, error) {
+			calledChain = chain
+			return stubAWSCredentials(), nil
+		}
+
+		exportHandler := setupExportHandler(
+			false,
+			selectProfileMock,
+			getAWSCredentialsMock,
+		)
+		globalArguments := stubGlobalArgumentsForExport("chain-config")
+
+		_, err := exportHandler.Handle(globalArguments)
+
+		require.NoError(t, err)
+		require.Equal(t, 4, len(calledChain))
+		require.Equal(t, "base", calledChain[0].ProfileName)
+		require.Equal(t, "profile hub", calledChain[1].ProfileName)
+		require.Equal(t, "hub-external-id", calledChain[1].ExternalId)
+		require.Equal(t, "profile spoke", calledChain[2].ProfileName)
+		require.Equal(t, "spoke-mfa-serial", calledChain[2].MFASerialNumber)
+		require.Equal(t, "profile chained_profile", calledChain[3].ProfileName)
+	})
+
+	t.Run("return error if source profile chain contains a cycle", func(t *testing.T) {
+		selectProfileMock := func(profiles awsconfig.Profiles, pattern string, c *config.Config) ([]byte, error) {
+			return []byte("profile cycle_a"), nil
+		}
+
+		exportHandler := setupExportHandler(
+			false,
+			selectProfileMock,
+			stubGetAWSCredentials,
+		)
+		globalArguments := stubGlobalArgumentsForExport("chain-config")
+
+		_, err := exportHandler.Handle(globalArguments)
+
+		require.Error(t, err)
+		require.Equal(t, "source profile cycle detected: profile cycle_a -> profile cycle_b -> profile cycle_a", err.Error())
+		require.Equal(t, CategoryConfigInvalid, CategoryOf(err))
+	})
+
+	t.Run("return config invalid error if duration seconds of a profile is invalid", func(t *testing.T) {
+		exportHandler := setupExportHandler(
+			false,
+			nil,
+			stubGetAWSCredentials,
+		)
+		globalArguments := stubGlobalArgumentsForExport("invalid-duration-config")
+
+		_, err := exportHandler.Handle(globalArguments)
+
+		require.Error(t, err)
+		require.Equal(t, "invalid duration_seconds [1h] of [profile invalid_duration], it must be a number of seconds", err.Error())
+		require.Equal(t, CategoryConfigInvalid, CategoryOf(err))
+	})
+
+	t.Run("return error if source profile in chain is missing", func(t *testing.T) {
+		selectProfileMock := func(profiles awsconfig.Profiles, pattern string, c *config.Config) ([]byte, error) {
+			return []byte("profile missing_link"), nil
+		}
+
+		exportHandler := setupExportHandler(
+			false,
+			selectProfileMock,
+			stubGetAWSCredentials,
+		)
+		globalArguments := stubGlobalArgumentsForExport("chain-config")
+
+		_, err := exportHandler.Handle(globalArguments)
+
+		require.Error(t, err)
+		require.Equal(t, "source profile [not_exists] of [profile missing_link] not found in config or credentials file", err.Error())
+	})
+
+	t.Run("return error if selected profile is not found in config file", func(t *testing.T) {
+		selectProfileMock := func(profiles awsconfig.Profiles, pattern string, c *config.Config) ([]byte, error) {
+			return []byte("a_random_profile"), nil
+		}
+
+		exportHandler := setupExportHandler(
+			false,
+			selectProfileMock,
+			stubGetAWSCredentials,
+		)
+		globalArguments := stubGlobalArgumentsForExport("set-config")
+
+		_, err := exportHandler.Handle(globalArguments)
+
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "not found in config file")
+	})
+
+	t.Run("return error from GetAWSCredentials", func(t *testing.T) {
+		selectProfileMock := func(profiles awsconfig.Profiles, pattern string, c *config.Config) ([]byte, error) {
+			return []byte("profile sso_profile_1"), nil
+		}
+
+		getAWSCredentialsStub := func(_ []awsconfig.Profile, _ time.Duration, _ aws.MFATokenProviderFn) (aws.Credentials, error) {
+			return aws.Credentials{}, errors.New("sso token is missing or expired")
+		}
+
+		exportHandler := setupExportHandler(
+			false,
+			selectProfileMock,
+			getAWSCredentialsStub,
+		)
+		globalArguments := stubGlobalArgumentsForExport("set-config")
+
+		_, err := exportHandler.Handle(globalArguments)
+
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "sso token is missing or expired")
+		require.Equal(t, CategoryAuthFailed, CategoryOf(err))
+	})
+
+	t.Run("return config invalid error when credentials can't be requested because of profile configuration", func(t *testing.T) {
+		selectProfileMock := func(profiles awsconfig.Profiles, pattern string, c *config.Config) ([]byte, error) {
+			return []byte("profile credential_source_profile"), nil
+		}
+
+		getAWSCredentialsStub := func(_ []awsconfig.Profile, _ time.Duration, _ aws.MFATokenProviderFn) (aws.Credentials, error) {
+			return aws.Credentials{}, fmt.Errorf("failed to get credentials: %w", &aws.ConfigError{Err: errors.New("unsupported credential_source [Unknown]")})
+		}
+
+		exportHandler := setupExportHandler(
+			false,
+			selectProfileMock,
+			getAWSCredentialsStub,
+		)
+		globalArguments := stubGlobalArgumentsForExport("chain-config")
+
+		_, err := exportHandler.Handle(globalArguments)
+
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unsupported credential_source [Unknown]")
+		require.Equal(t, CategoryConfigInvalid, CategoryOf(err))
+	})
+
 	t.Run("return error if duration is invalid", func(t *testing.T) {
 		app := kingpin.New("some-app", "some description")
-		exportHandler := NewExportHandler(app, stubConfig(), false, nil, nil)
+		exportHandler := NewExportHandler(app, stubConfig(), stubDetectShell(false), nil, stubCredentialsGetter(nil), nil)
 
 		if _, err := app.Parse([]string{"export", "-d", "5"}); err != nil {
 			t.Fatalf("failed to setup test export handler: %v\n", err)
@@ -110,15 +369,15 @@ func TestExportHandler(t *testing.T) {
 
 		globalArguments := stubGlobalArgumentsForExport("set-config")
 
-		success, err := exportHandler.Handle(globalArguments)
+		_, err := exportHandler.Handle(globalArguments)
 
-		require.False(t, success)
-		require.Contains(t, err, "missing unit in duration")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "missing unit in duration")
 	})
 
 	t.Run("return error if duration is lower than minimum duration allowed", func(t *testing.T) {
 		app := kingpin.New("some-app", "some description")
-		exportHandler := NewExportHandler(app, stubConfig(), false, nil, nil)
+		exportHandler := NewExportHandler(app, stubConfig(), stubDetectShell(false), nil, stubCredentialsGetter(nil), nil)
 
 		if _, err := app.Parse([]string{"export", "-d", "5m"}); err != nil {
 			t.Fatalf("failed to setup test export handler: %v\n", err)
@@ -126,21 +385,22 @@ func TestExportHandler(t *testing.T) {
 
 		globalArguments := stubGlobalArgumentsForExport("set-config")
 
-		success, err := exportHandler.Handle(globalArguments)
+		_, err := exportHandler.Handle(globalArguments)
 
-		require.False(t, success)
-		require.Contains(t, err, "Minimum duration is 15 minutes")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "Minimum duration is 15 minutes")
+		require.Equal(t, CategoryUsage, CategoryOf(err))
 	})
 
-	t.Run("call GetAWSCredentials with default value when no duration given", func(t *testing.T) {
+	t.Run("call GetAWSCredentials with zero duration when no duration given", func(t *testing.T) {
 		selectProfileMock := func(profiles awsconfig.Profiles, pattern string, c *config.Config) ([]byte, error) {
 			return []byte("profile config_profile_1"), nil
 		}
 
 		called := false
 
-		getAWSCredentialsMock := func(_ *awsconfig.Profile, duration time.Duration) (credentials.Value, error) {
-			require.Equal(t, float64(15), duration.Minutes())
+		getAWSCredentialsMock := func(_ []awsconfig.Profile, duration time.Duration, _ aws.MFATokenProviderFn) (aws.Credentials, error) {
+			require.Equal(t, time.Duration(0), duration)
 			called = true
 			return stubAWSCredentials(), nil
 		}
@@ -165,14 +425,14 @@ func TestExportHandler(t *testing.T) {
 		called := false
 		mockDurationValue := "20m"
 
-		getAWSCredentialsMock := func(_ *awsconfig.Profile, duration time.Duration) (credentials.Value, error) {
+		getAWSCredentialsMock := func(_ []awsconfig.Profile, duration time.Duration, _ aws.MFATokenProviderFn) (aws.Credentials, error) {
 			require.Equal(t, float64(20), duration.Minutes())
 			called = true
 			return stubAWSCredentials(), nil
 		}
 
 		app := kingpin.New("some-app", "some description")
-		exportHandler := NewExportHandler(app, stubConfig(), false, selectProfileMock, getAWSCredentialsMock)
+		exportHandler := NewExportHandler(app, stubConfig(), stubDetectShell(false), selectProfileMock, stubCredentialsGetter(getAWSCredentialsMock), noopRecordHistory)
 
 		if _, err := app.Parse([]string{"export", "-d", mockDurationValue}); err != nil {
 			t.Fatalf("failed to setup test export handler: %v\n", err)
@@ -197,10 +457,10 @@ func TestExportHandler(t *testing.T) {
 		)
 		globalArguments := stubGlobalArgumentsForExport("set-config")
 
-		success, output := exportHandler.Handle(globalArguments)
+		result, err := exportHandler.Handle(globalArguments)
 
-		require.True(t, success)
-		require.Equal(t, output, "export AWS_ACCESS_KEY_ID='access-key-id' AWS_SECRET_ACCESS_KEY='secret-access-key' AWS_SESSION_TOKEN='session-token'")
+		require.NoError(t, err)
+		require.Equal(t, result.Output, "export AWS_ACCESS_KEY_ID='access-key-id' AWS_SECRET_ACCESS_KEY='secret-access-key' AWS_SESSION_TOKEN='session-token'")
 	})
 
 	t.Run("contains export region for Linux and MacOS in output", func(t *testing.T) {
@@ -215,10 +475,10 @@ func TestExportHandler(t *testing.T) {
 		)
 		globalArguments := stubGlobalArgumentsForExport("set-config")
 
-		success, output := exportHandler.Handle(globalArguments)
+		result, err := exportHandler.Handle(globalArguments)
 
-		require.True(t, success)
-		require.Equal(t, output, "export AWS_ACCESS_KEY_ID='access-key-id' AWS_SECRET_ACCESS_KEY='secret-access-key' AWS_SESSION_TOKEN='session-token' AWS_REGION='us-west-2' AWS_DEFAULT_REGION='us-west-2'")
+		require.NoError(t, err)
+		require.Equal(t, result.Output, "export AWS_ACCESS_KEY_ID='access-key-id' AWS_SECRET_ACCESS_KEY='secret-access-key' AWS_SESSION_TOKEN='session-token' AWS_REGION='us-west-2' AWS_DEFAULT_REGION='us-west-2'")
 	})
 
 	t.Run("contains export command for Windows in output", func(t *testing.T) {
@@ -233,10 +493,10 @@ func TestExportHandler(t *testing.T) {
 		)
 		globalArguments := stubGlobalArgumentsForExport("set-config")
 
-		success, output := exportHandler.Handle(globalArguments)
+		result, err := exportHandler.Handle(globalArguments)
 
-		require.True(t, success)
-		require.Equal(t, output, "$env:AWS_ACCESS_KEY_ID = 'access-key-id'; $env:AWS_SECRET_ACCESS_KEY = 'secret-access-key'; $env:AWS_SESSION_TOKEN = 'session-token'")
+		require.NoError(t, err)
+		require.Equal(t, result.Output, "$env:AWS_ACCESS_KEY_ID = 'access-key-id'; $env:AWS_SECRET_ACCESS_KEY = 'secret-access-key'; $env:AWS_SESSION_TOKEN = 'session-token'")
 	})
 
 	t.Run("contains export region for Windows in output", func(t *testing.T) {
@@ -251,9 +511,504 @@ func TestExportHandler(t *testing.T) {
 		)
 		globalArguments := stubGlobalArgumentsForExport("set-config")
 
-		success, output := exportHandler.Handle(globalArguments)
+		result, err := exportHandler.Handle(globalArguments)
+
+		require.NoError(t, err)
+		require.Equal(t, result.Output, "$env:AWS_ACCESS_KEY_ID = 'access-key-id'; $env:AWS_SECRET_ACCESS_KEY = 'secret-access-key'; $env:AWS_SESSION_TOKEN = 'session-token'; $env:AWS_REGION = 'us-west-2'; $env:AWS_DEFAULT_REGION = 'us-west-2'")
+	})
+
+	t.Run("contains export command for shell given by --shell instead of detected shell", func(t *testing.T) {
+		selectProfileMock := func(profiles awsconfig.Profiles, pattern string, c *config.Config) ([]byte, error) {
+			return []byte("profile config_profile_2"), nil
+		}
+
+		app := kingpin.New("some-app", "some description")
+		exportHandler := NewExportHandler(app, stubConfig(), stubDetectShell(false), selectProfileMock, stubCredentialsGetter(stubGetAWSCredentials), noopRecordHistory)
+		_, err := app.Parse([]string{"export", "--shell", "fish"})
+		require.NoError(t, err)
+
+		result, err := exportHandler.Handle(stubGlobalArgumentsForExport("set-config"))
+
+		require.NoError(t, err)
+		require.Equal(t, "set -gx AWS_ACCESS_KEY_ID 'access-key-id'; set -gx AWS_SECRET_ACCESS_KEY 'secret-access-key'; set -gx AWS_SESSION_TOKEN 'session-token'; set -gx AWS_REGION 'us-west-2'; set -gx AWS_DEFAULT_REGION 'us-west-2'", result.Output)
+	})
+
+	t.Run("contains export command for detected shell", func(t *testing.T) {
+		selectProfileMock := func(profiles awsconfig.Profiles, pattern string, c *config.Config) ([]byte, error) {
+			return []byte("profile config_profile_1"), nil
+		}
+
+		app := kingpin.New("some-app", "some description")
+		exportHandler := NewExportHandler(app, stubConfig(), func() string { return "csh" }, selectProfileMock, stubCredentialsGetter(stubGetAWSCredentials), noopRecordHistory)
+		_, err := app.Parse([]string{"export"})
+		require.NoError(t, err)
+
+		result, err := exportHandler.Handle(stubGlobalArgumentsForExport("set-config"))
+
+		require.NoError(t, err)
+		require.Equal(t, "setenv AWS_ACCESS_KEY_ID 'access-key-id'; setenv AWS_SECRET_ACCESS_KEY 'secret-access-key'; setenv AWS_SESSION_TOKEN 'session-token'", result.Output)
+	})
+}
+
+func TestExportHandler_Cache(t *testing.T) {
+	selectProfileMock := func(profiles awsconfig.Profiles, pattern string, c *config.Config) ([]byte, error) {
+		return []byte("profile config_profile_1"), nil
+	}
+
+	setupHandler := func(t *testing.T, arguments []string, getAWSCredentialsFn GetAWSCredentialsFn, readCachedCredentialsFn ReadCachedCredentialsFn, writeCachedCredentialsFn WriteCachedCredentialsFn) ExportHandler {
+		app := kingpin.New("some-app", "some description")
+		credentialsGetter := stubCredentialsGetter(getAWSCredentialsFn)
+		credentialsGetter.ReadCachedCredentials = readCachedCredentialsFn
+		credentialsGetter.WriteCachedCredentials = writeCachedCredentialsFn
+		exportHandler := NewExportHandler(app, stubConfig(), stubDetectShell(false), selectProfileMock, credentialsGetter, noopRecordHistory)
+
+		if _, err := app.Parse(append([]string{"export"}, arguments...)); err != nil {
+			t.Fatalf("failed to setup test export handler: %v\n", err)
+		}
+
+		return exportHandler
+	}
+
+	t.Run("return cached credentials without calling GetAWSCredentials", func(t *testing.T) {
+		getAWSCredentialsMock := func(_ []awsconfig.Profile, _ time.Duration, _ aws.MFATokenProviderFn) (aws.Credentials, error) {
+			require.Fail(t, "unexpected call to GetAWSCredentials")
+			return aws.Credentials{}, nil
+		}
+
+		readCachedCredentialsMock := func(key string, refreshWindow time.Duration) (*aws.Credentials, error) {
+			require.NotEmpty(t, key)
+			require.Equal(t, 5*time.Minute, refreshWindow)
+			cached := stubAWSCredentials()
+			cached.AccessKeyID = "cached-access-key-id"
+			return &cached, nil
+		}
+
+		exportHandler := setupHandler(t, nil, getAWSCredentialsMock, readCachedCredentialsMock, noopWriteCachedCredentials)
+
+		result, err := exportHandler.Handle(stubGlobalArgumentsForExport("set-config"))
+
+		require.NoError(t, err)
+		require.Contains(t, result.Output, "AWS_ACCESS_KEY_ID='cached-access-key-id'")
+	})
+
+	t.Run("write new credentials to cache when credentials are not cached", func(t *testing.T) {
+		var writtenEntry *cache.Entry
+		writeCachedCredentialsMock := func(entry cache.Entry) error {
+			writtenEntry = &entry
+			return nil
+		}
+
+		exportHandler := setupHandler(t, nil, stubGetAWSCredentials, noopReadCachedCredentials, writeCachedCredentialsMock)
+
+		result, err := exportHandler.Handle(stubGlobalArgumentsForExport("set-config"))
+
+		require.NoError(t, err)
+		require.Contains(t, result.Output, "AWS_ACCESS_KEY_ID='access-key-id'")
+		require.NotNil(t, writtenEntry)
+		require.NotEmpty(t, writtenEntry.Key)
+		require.Equal(t, "profile config_profile_1", writtenEntry.ProfileName)
+		require.Equal(t, "1", writtenEntry.RoleArn)
+		require.Equal(t, "access-key-id", writtenEntry.Credentials.AccessKeyID)
+	})
+
+	t.Run("get new credentials when failed to read cache", func(t *testing.T) {
+		readCachedCredentialsMock := func(_ string, _ time.Duration) (*aws.Credentials, error) {
+			return nil, errors.New("failed to decrypt cached credentials")
+		}
+
+		exportHandler := setupHandler(t, nil, stubGetAWSCredentials, readCachedCredentialsMock, noopWriteCachedCredentials)
+
+		result, err := exportHandler.Handle(stubGlobalArgumentsForExport("set-config"))
+
+		require.NoError(t, err)
+		require.Contains(t, result.Output, "AWS_ACCESS_KEY_ID='access-key-id'")
+	})
+
+	t.Run("not write credentials without expiration to cache", func(t *testing.T) {
+		getAWSCredentialsMock := func(_ []awsconfig.Profile, _ time.Duration, _ aws.MFATokenProviderFn) (aws.Credentials, error) {
+			return aws.Credentials{Value: stubAWSCredentials().Value}, nil
+		}
+
+		writeCachedCredentialsMock := func(_ cache.Entry) error {
+			require.Fail(t, "unexpected call to WriteCachedCredentials")
+			return nil
+		}
+
+		exportHandler := setupHandler(t, nil, getAWSCredentialsMock, noopReadCachedCredentials, writeCachedCredentialsMock)
+
+		_, err := exportHandler.Handle(stubGlobalArgumentsForExport("set-config"))
+
+		require.NoError(t, err)
+	})
+
+	t.Run("neither read nor write cache when no-cache flag is given", func(t *testing.T) {
+		readCachedCredentialsMock := func(_ string, _ time.Duration) (*aws.Credentials, error) {
+			require.Fail(t, "unexpected call to ReadCachedCredentials")
+			return nil, nil
+		}
+
+		writeCachedCredentialsMock := func(_ cache.Entry) error {
+			require.Fail(t, "unexpected call to WriteCachedCredentials")
+			return nil
+		}
+
+		exportHandler := setupHandler(t, []string{"--no-cache"}, stubGetAWSCredentials, readCachedCredentialsMock, writeCachedCredentialsMock)
+
+		result, err := exportHandler.Handle(stubGlobalArgumentsForExport("set-config"))
+
+		require.NoError(t, err)
+		require.Contains(t, result.Output, "AWS_ACCESS_KEY_ID='access-key-id'")
+	})
+}
+
+func TestExportHandler_Format(t *testing.T) {
+	selectProfileMock := func(profiles awsconfig.Profiles, pattern string, c *config.Config) ([]byte, error) {
+		return []byte("profile config_profile_2"), nil
+	}
+
+	getAWSCredentialsStub := func(_ []awsconfig.Profile, _ time.Duration, _ aws.MFATokenProviderFn) (aws.Credentials, error) {
+		awsCredentials := stubAWSCredentials()
+		awsCredentials.Expiration = time.Date(2020, 1, 1, 1, 0, 0, 0, time.UTC)
+		return awsCredentials, nil
+	}
+
+	setupExportHandlerWithFormat := func(t *testing.T, format string) ExportHandler {
+		app := kingpin.New("some-app", "some description")
+		exportHandler := NewExportHandler(app, stubConfig(), stubDetectShell(false), selectProfileMock, stubCredentialsGetter(getAWSCredentialsStub), noopRecordHistory)
+		_, err := app.Parse([]string{"export", "--format", format})
+		require.NoError(t, err)
+
+		return exportHandler
+	}
+
+	t.Run("print credentials with expiration and region in json format", func(t *testing.T) {
+		result, err := setupExportHandlerWithFormat(t, "json").Handle(stubGlobalArgumentsForExport("set-config"))
+
+		require.NoError(t, err)
+		require.JSONEq(t, `{
+			"AccessKeyId": "access-key-id",
+			"SecretAccessKey": "secret-access-key",
+			"SessionToken": "session-token",
+			"Expiration": "2020-01-01T01:00:00Z",
+			"Region": "us-west-2"
+		}`, result.Output)
+	})
+
+	t.Run("print environment variables without quotes in dotenv format", func(t *testing.T) {
+		result, err := setupExportHandlerWithFormat(t, "dotenv").Handle(stubGlobalArgumentsForExport("set-config"))
+
+		require.NoError(t, err)
+		require.Equal(t, `AWS_ACCESS_KEY_ID=access-key-id
+AWS_SECRET_ACCESS_KEY=secret-access-key
+AWS_SESSION_TOKEN=session-token
+AWS_REGION=us-west-2
+AWS_DEFAULT_REGION=us-west-2`, result.Output)
+	})
+
+	t.Run("print credentials file profile named after selected profile in ini format", func(t *testing.T) {
+		result, err := setupExportHandlerWithFormat(t, "ini").Handle(stubGlobalArgumentsForExport("set-config"))
+
+		require.NoError(t, err)
+
+		file, err := ini.Load([]byte(result.Output))
+		require.NoError(t, err)
+		require.Equal(t, []string{"DEFAULT", "config_profile_2"}, file.SectionStrings())
+		section := file.Section("config_profile_2")
+		require.Equal(t, "access-key-id", section.Key("aws_access_key_id").Value())
+		require.Equal(t, "secret-access-key", section.Key("aws_secret_access_key").Value())
+		require.Equal(t, "session-token", section.Key("aws_session_token").Value())
+		require.Equal(t, "2020-01-01T01:00:00Z", section.Key("expiration").Value())
+	})
+
+	t.Run("reject unsupported format", func(t *testing.T) {
+		app := kingpin.New("some-app", "some description")
+		NewExportHandler(app, stubConfig(), stubDetectShell(false), selectProfileMock, stubCredentialsGetter(getAWSCredentialsStub), noopRecordHistory)
+		_, err := app.Parse([]string{"export", "--format", "yaml"})
+
+		require.Error(t, err)
+	})
+}
+
+func TestExportHandler_ToProfile(t *testing.T) {
+	selectProfileMock := func(profiles awsconfig.Profiles, pattern string, c *config.Config) ([]byte, error) {
+		return []byte("profile config_profile_2"), nil
+	}
+
+	getAWSCredentialsStub := func(_ []awsconfig.Profile, _ time.Duration, _ aws.MFATokenProviderFn) (aws.Credentials, error) {
+		awsCredentials := stubAWSCredentials()
+		awsCredentials.Expiration = time.Date(2020, 1, 1, 1, 0, 0, 0, time.UTC)
+		return awsCredentials, nil
+	}
+
+	stubGlobalArgumentsForToProfile := func(credentialsName string) GlobalArguments {
+		globalArguments := stubGlobalArgumentsForExport("set-config")
+		globalArguments.CredentialsFilePath, _ = filepath.Abs("./test_data/" + credentialsName)
+		return globalArguments
+	}
+
+	setupExportHandlerToProfile := func(t *testing.T, writeToFileFn WriteToFileFn, arguments ...string) ExportHandler {
+		app := kingpin.New("some-app", "some description")
+		credentialsGetter := stubCredentialsGetter(getAWSCredentialsStub)
+		credentialsGetter.WriteToFile = writeToFileFn
+		exportHandler := NewExportHandler(app, stubConfig(), stubDetectShell(false), selectProfileMock, credentialsGetter, noopRecordHistory)
+		_, err := app.Parse(append([]string{"export"}, arguments...))
+		require.NoError(t, err)
+
+		return exportHandler
+	}
+
+	t.Run("write credentials and expiration to new profile in credentials file", func(t *testing.T) {
+		writtenFiles := map[string]*ini.File{}
+		writeToFileMock := func(file *ini.File, filePath string) error {
+			writtenFiles[filePath] = file
+			return nil
+		}
+
+		globalArguments := stubGlobalArgumentsForToProfile("export-to-profile-credentials")
+		exportHandler := setupExportHandlerToProfile(t, writeToFileMock, "--to-profile", "tmp-prod")
+
+		result, err := exportHandler.Handle(globalArguments)
+
+		require.NoError(t, err)
+		require.Equal(t, fmt.Sprintf("=== [profile config_profile_2] -> [tmp-prod] (%s)", globalArguments.CredentialsFilePath), result.Output)
+		require.Len(t, writtenFiles, 1)
+
+		credentialsFile := writtenFiles[globalArguments.CredentialsFilePath]
+		require.NotNil(t, credentialsFile)
+		section := credentialsFile.Section("tmp-prod")
+		require.Equal(t, "access-key-id", section.Key("aws_access_key_id").Value())
+		require.Equal(t, "secret-access-key", section.Key("aws_secret_access_key").Value())
+		require.Equal(t, "session-token", section.Key("aws_session_token").Value())
+		require.Equal(t, "2020-01-01T01:00:00Z", section.Key("expiration").Value())
+		require.Equal(t, "long-term-key-id", credentialsFile.Section("long_term").Key("aws_access_key_id").Value())
+	})
+
+	t.Run("overwrite profile with temporary credentials", func(t *testing.T) {
+		var writtenFile *ini.File
+		writeToFileMock := func(file *ini.File, _ string) error {
+			writtenFile = file
+			return nil
+		}
+
+		exportHandler := setupExportHandlerToProfile(t, writeToFileMock, "--to-profile", "tmp")
+
+		_, err := exportHandler.Handle(stubGlobalArgumentsForToProfile("export-to-profile-credentials"))
+
+		require.NoError(t, err)
+		require.Equal(t, "access-key-id", writtenFile.Section("tmp").Key("aws_access_key_id").Value())
+		require.Equal(t, "session-token", writtenFile.Section("tmp").Key("aws_session_token").Value())
+		require.Equal(t, "2020-01-01T01:00:00Z", writtenFile.Section("tmp").Key("expiration").Value())
+	})
+
+	t.Run("lock credentials and config files while writing to profile and setting it as default", func(t *testing.T) {
+		var events []string
+		writeToFileMock := func(_ *ini.File, filePath string) error {
+			events = append(events, "write "+filepath.Base(filePath))
+			return nil
+		}
+
+		app := kingpin.New("some-app", "some description")
+		credentialsGetter := stubCredentialsGetter(getAWSCredentialsStub)
+		credentialsGetter.WriteToFile = writeToFileMock
+		credentialsGetter.LockFile = recordingLockFile(&events)
+		exportHandler := NewExportHandler(app, stubConfig(), stubDetectShell(false), selectProfileMock, credentialsGetter, noopRecordHistory)
+		_, err := app.Parse([]string{"export", "--to-profile", "tmp", "--set-default"})
+		require.NoError(t, err)
+
+		_, err = exportHandler.Handle(stubGlobalArgumentsForToProfile("export-to-profile-credentials"))
+
+		require.NoError(t, err)
+		require.Equal(t, []string{
+			"lock export-to-profile-credentials",
+			"lock set-config",
+			"write export-to-profile-credentials",
+			"write set-config",
+			"unlock set-config",
+			"unlock export-to-profile-credentials",
+		}, events)
+	})
+
+	t.Run("refuse to overwrite profile with long-term credentials", func(t *testing.T) {
+		writeToFileMock := func(_ *ini.File, _ string) error {
+			require.Fail(t, "unexpected call to WriteToFile")
+			return nil
+		}
+
+		exportHandler := setupExportHandlerToProfile(t, writeToFileMock, "--to-profile", "long_term")
+
+		_, err := exportHandler.Handle(stubGlobalArgumentsForToProfile("export-to-profile-credentials"))
+
+		require.Error(t, err)
+		require.Equal(t, "=== profile [long_term] in credentials file has long-term credentials, refusing to overwrite it", err.Error())
+	})
+
+	t.Run("create credentials file if it does not exist", func(t *testing.T) {
+		var writtenFile *ini.File
+		writeToFileMock := func(file *ini.File, _ string) error {
+			writtenFile = file
+			return nil
+		}
+
+		// credential process profile doesn't need source profile from credentials file
+		selectProcessProfileMock := func(profiles awsconfig.Profiles, pattern string, c *config.Config) ([]byte, error) {
+			return []byte("profile process_profile_1"), nil
+		}
+
+		app := kingpin.New("some-app", "some description")
+		credentialsGetter := stubCredentialsGetter(getAWSCredentialsStub)
+		credentialsGetter.WriteToFile = writeToFileMock
+		exportHandler := NewExportHandler(app, stubConfig(), stubDetectShell(false), selectProcessProfileMock, credentialsGetter, noopRecordHistory)
+		_, err := app.Parse([]string{"export", "--to-profile", "tmp-prod"})
+		require.NoError(t, err)
+
+		_, err = exportHandler.Handle(stubGlobalArgumentsForToProfile("credentials_not_exists"))
+
+		require.NoError(t, err)
+		require.Equal(t, "access-key-id", writtenFile.Section("tmp-prod").Key("aws_access_key_id").Value())
+	})
+
+	t.Run("set profile as default with --set-default", func(t *testing.T) {
+		writtenFiles := map[string]*ini.File{}
+		writeToFileMock := func(file *ini.File, filePath string) error {
+			writtenFiles[filePath] = file
+			return nil
+		}
+
+		globalArguments := stubGlobalArgumentsForToProfile("export-to-profile-credentials")
+		exportHandler := setupExportHandlerToProfile(t, writeToFileMock, "--to-profile", "tmp-prod", "--set-default")
+
+		result, err := exportHandler.Handle(globalArguments)
+
+		require.NoError(t, err)
+		require.Equal(t, fmt.Sprintf("=== [profile config_profile_2] -> [tmp-prod] -> [default] (%s)", globalArguments.CredentialsFilePath), result.Output)
+
+		defaultInCredentials := writtenFiles[globalArguments.CredentialsFilePath].Section("default")
+		require.Equal(t, "access-key-id", defaultInCredentials.Key("aws_access_key_id").Value())
+		require.Equal(t, "secret-access-key", defaultInCredentials.Key("aws_secret_access_key").Value())
+		require.Equal(t, "session-token", defaultInCredentials.Key("aws_session_token").Value())
+
+		defaultInConfig := writtenFiles[globalArguments.ConfigFilePath].Section("default")
+		require.Equal(t, "us-west-2", defaultInConfig.Key("region").Value())
+		require.False(t, defaultInConfig.HasKey("role_arn"))
+	})
+
+	t.Run("return error if --set-default is given without --to-profile", func(t *testing.T) {
+		exportHandler := setupExportHandlerToProfile(t, noopWriteToFileMock, "--set-default")
+
+		_, err := exportHandler.Handle(stubGlobalArgumentsForExport("set-config"))
+
+		require.Error(t, err)
+		require.Equal(t, "--set-default requires --to-profile", err.Error())
+	})
+
+	t.Run("return usage error if --format is given with --to-profile", func(t *testing.T) {
+		exportHandler := setupExportHandlerToProfile(t, noopWriteToFileMock, "--to-profile", "tmp-prod", "--format", "json")
+
+		_, err := exportHandler.Handle(stubGlobalArgumentsForToProfile("export-to-profile-credentials"))
+
+		require.Error(t, err)
+		require.Equal(t, CategoryUsage, CategoryOf(err))
+		require.Equal(t, "--format can't be used with --to-profile", err.Error())
+	})
+}
+
+func TestExportHandler_RecordHistory(t *testing.T) {
+	selectProfileMock := func(profiles awsconfig.Profiles, pattern string, c *config.Config) ([]byte, error) {
+		return []byte("profile config_profile_2"), nil
+	}
+
+	t.Run("record exported profile", func(t *testing.T) {
+		var recorded []string
+		recordHistoryMock := func(profileName string) error {
+			recorded = append(recorded, profileName)
+			return nil
+		}
+
+		app := kingpin.New("some-app", "some description")
+		exportHandler := NewExportHandler(app, stubConfig(), stubDetectShell(false), selectProfileMock, stubCredentialsGetter(stubGetAWSCredentials), recordHistoryMock)
+		_, _ = app.Parse([]string{"export"})
+
+		_, err := exportHandler.Handle(stubGlobalArgumentsForExport("set-config"))
+
+		require.NoError(t, err)
+		require.Equal(t, []string{"profile config_profile_2"}, recorded)
+	})
+
+	t.Run("not record profile when credentials can't be retrieved", func(t *testing.T) {
+		var recorded []string
+		recordHistoryMock := func(profileName string) error {
+			recorded = append(recorded, profileName)
+			return nil
+		}
+		getAWSCredentialsFailure := func(_ []awsconfig.Profile, _ time.Duration, _ aws.MFATokenProviderFn) (aws.Credentials, error) {
+			return aws.Credentials{}, errors.New("some error")
+		}
+
+		app := kingpin.New("some-app", "some description")
+		exportHandler := NewExportHandler(app, stubConfig(), stubDetectShell(false), selectProfileMock, stubCredentialsGetter(getAWSCredentialsFailure), recordHistoryMock)
+		_, _ = app.Parse([]string{"export"})
+
+		_, err := exportHandler.Handle(stubGlobalArgumentsForExport("set-config"))
+
+		require.Error(t, err)
+		require.Empty(t, recorded)
+	})
+}
+
+func TestExportHandler_MFAToken(t *testing.T) {
+	selectProfileMock := func(profiles awsconfig.Profiles, pattern string, c *config.Config) ([]byte, error) {
+		return []byte("profile config_profile_2"), nil
+	}
+
+	exportWithMFATokenProvider := func(t *testing.T, c *config.Config, args []string) string {
+		var token string
+		getAWSCredentialsMock := func(chain []awsconfig.Profile, _ time.Duration, mfaTokenProvider aws.MFATokenProviderFn) (aws.Credentials, error) {
+			var err error
+			token, err = mfaTokenProvider(chain[len(chain)-1])()
+			require.NoError(t, err)
+			return stubAWSCredentials(), nil
+		}
+
+		app := kingpin.New("some-app", "some description")
+		exportHandler := NewExportHandler(app, c, stubDetectShell(false), selectProfileMock, stubCredentialsGetter(getAWSCredentialsMock), noopRecordHistory)
+		_, err := app.Parse(args)
+		require.NoError(t, err)
+
+		_, err = exportHandler.Handle(stubGlobalArgumentsForExport("set-config"))
+		require.NoError(t, err)
+
+		return token
+	}
+
+	t.Run("provide token given by mfa-token flag", func(t *testing.T) {
+		token := exportWithMFATokenProvider(t, stubConfig(), []string{"export", "--mfa-token", "123456"})
+
+		require.Equal(t, "123456", token)
+	})
+
+	t.Run("provide token printed by process configured for selected profile", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("test command requires sh")
+		}
+
+		c := stubConfig()
+		c.MFAProviders = map[string]config.MFAProvider{
+			"config_profile_2": {Type: config.MFAProviderProcess, Command: "echo 654321"},
+		}
+
+		token := exportWithMFATokenProvider(t, c, []string{"export"})
+
+		require.Equal(t, "654321", token)
+	})
+
+	t.Run("prefer mfa-token flag over provider configured for selected profile", func(t *testing.T) {
+		c := stubConfig()
+		c.MFAProviders = map[string]config.MFAProvider{
+			"config_profile_2": {Type: config.MFAProviderProcess, Command: "exit 1"},
+		}
+
+		token := exportWithMFATokenProvider(t, c, []string{"export", "--mfa-token", "123456"})
 
-		require.True(t, success)
-		require.Equal(t, output, "$env:AWS_ACCESS_KEY_ID = 'access-key-id'; $env:AWS_SECRET_ACCESS_KEY = 'secret-access-key'; $env:AWS_SESSION_TOKEN = 'session-token'; $env:AWS_REGION = 'us-west-2'; $env:AWS_DEFAULT_REGION = 'us-west-2'")
+		require.Equal(t, "123456", token)
 	})
 }
@@ -5,6 +5,7 @@ import (
 	"github.com/hpcsc/aws-profile/internal/io"
 	"github.com/hpcsc/aws-profile/internal/log"
 	"gopkg.in/alecthomas/kingpin.v2"
+	"gopkg.in/ini.v1"
 	"os"
 	"regexp"
 	"strings"
@@ -14,6 +15,15 @@ type ReadCachedCallerIdentityFn func() (string, error)
 type WriteCachedCallerIdentityFn func(string) error
 type GetAWSCallerIdentityFn func() (string, error)
 
+// keys identifying config file profile set as default for each kind of profile, in order of precedence
+var configDefaultIdentifyingKeys = [][]string{
+	{"role_arn", "source_profile"},
+	{"role_arn", "credential_source"},
+	{"sso_account_id", "sso_role_name"},
+	{"role_arn", "web_identity_token_file"},
+	{"credential_process"},
+}
+
 type GetHandler struct {
 	SubCommand                  *kingpin.CmdClause
 	GetAWSCallerIdentityFn      GetAWSCallerIdentityFn
@@ -48,17 +58,17 @@ func awsCredentialsEnvironmentVariablesSet() bool {
 	return accessKeyIdExists && secretAccessKeyExists && sessionTokenExists
 }
 
-func (handler GetHandler) Handle(globalArguments GlobalArguments) (bool, string) {
+func (handler GetHandler) Handle(globalArguments GlobalArguments) (Result, error) {
 	if awsCredentialsEnvironmentVariablesSet() {
 		cachedCallerIdentity, readCachedCallerIdentityErr := handler.ReadCachedCallerIdentityFn()
 		if readCachedCallerIdentityErr == nil && cachedCallerIdentity != "" {
-			return true, cachedCallerIdentity
+			return Result{Output: cachedCallerIdentity}, nil
 		}
 
 		var callerIdentityProfile, getCallerIdentityErr = handler.GetAWSCallerIdentityFn()
 		if getCallerIdentityErr != nil {
 			if strings.Contains(getCallerIdentityErr.Error(), "ExpiredToken") {
-				return true, "error: ExpiredToken"
+				return Result{Output: "error: ExpiredToken"}, nil
 			}
 
 			// error returned by aws sometimes has format: "xxx (ErrorCode) yyy"
@@ -68,17 +78,17 @@ func (handler GetHandler) Handle(globalArguments GlobalArguments) (bool, string)
 			errorMatch := errorRegex.FindStringSubmatch(getCallerIdentityErr.Error())
 			if len(errorMatch) < 2 {
 				handler.Logger.Errorf("failed to get caller identity with error: %s", getCallerIdentityErr.Error())
-				return true, "unknown"
+				return Result{Output: "unknown"}, nil
 			}
 
-			return true, fmt.Sprintf("error: %s", strings.Trim(errorMatch[1], "()"))
+			return Result{Output: fmt.Sprintf("error: %s", strings.Trim(errorMatch[1], "()"))}, nil
 		}
 
 		writeError := handler.WriteCachedCallerIdentityFn(callerIdentityProfile)
 		if writeError != nil {
 			handler.Logger.Errorf("failed to write caller identity [%s] to cached file", callerIdentityProfile)
 		}
-		return true, callerIdentityProfile
+		return Result{Output: callerIdentityProfile}, nil
 	} else {
 		writeError := handler.WriteCachedCallerIdentityFn("")
 		if writeError != nil {
@@ -88,47 +98,60 @@ func (handler GetHandler) Handle(globalArguments GlobalArguments) (bool, string)
 
 	configFile, err := io.ReadFile(globalArguments.ConfigFilePath)
 	if err != nil {
-		return false, fmt.Sprintf("Fail to read AWS config file: %v", err)
+		return Result{}, fileReadError("AWS config file", err)
 	}
 
-	configDefaultSection, err := configFile.GetSection("default")
-	if err == nil &&
-		configDefaultSection.HasKey("role_arn") &&
-		configDefaultSection.HasKey("source_profile") {
-
-		defaultRoleArn := configDefaultSection.Key("role_arn").Value()
-		defaultSourceProfile := configDefaultSection.Key("source_profile").Value()
-
-		for _, section := range configFile.Sections() {
-			if strings.Compare(section.Name(), "default") != 0 &&
-				section.HasKey("role_arn") &&
-				section.HasKey("source_profile") &&
-				strings.Compare(section.Key("role_arn").Value(), defaultRoleArn) == 0 &&
-				strings.Compare(section.Key("source_profile").Value(), defaultSourceProfile) == 0 {
-				return true, section.Name()
-			}
+	for _, keys := range configDefaultIdentifyingKeys {
+		if name := findSectionMatchingDefault(configFile, keys); name != "" {
+			return Result{Output: name}, nil
 		}
 	}
 
 	credentialsFile, err := io.ReadFile(globalArguments.CredentialsFilePath)
 	if err != nil {
-		return false, fmt.Sprintf("Fail to read AWS credentials file: %v", err)
+		return Result{}, fileReadError("AWS credentials file", err)
 	}
 
-	credentialsDefaultSection, err := credentialsFile.GetSection("default")
-	if err == nil &&
-		credentialsDefaultSection.HasKey("aws_access_key_id") {
+	if name := findSectionMatchingDefault(credentialsFile, []string{"aws_access_key_id"}); name != "" {
+		return Result{Output: fmt.Sprintf("%s\n", name)}, nil
+	}
 
-		defaultAWSAccessKeyId := credentialsDefaultSection.Key("aws_access_key_id").Value()
+	return Result{}, nil
+}
 
-		for _, section := range credentialsFile.Sections() {
-			if strings.Compare(section.Name(), "default") != 0 &&
-				section.HasKey("aws_access_key_id") &&
-				strings.Compare(section.Key("aws_access_key_id").Value(), defaultAWSAccessKeyId) == 0 {
-				return true, fmt.Sprintf("%s\n", section.Name())
-			}
+// findSectionMatchingDefault returns name of the first section with the same values of given keys as default section,
+// empty if default section doesn't have all of them
+func findSectionMatchingDefault(file *ini.File, keys []string) string {
+	defaultSection, err := file.GetSection("default")
+	if err != nil || !hasKeys(defaultSection, keys) {
+		return ""
+	}
+
+	for _, section := range file.Sections() {
+		if section.Name() != "default" && hasKeys(section, keys) && haveSameValues(section, defaultSection, keys) {
+			return section.Name()
+		}
+	}
+
+	return ""
+}
+
+func hasKeys(section *ini.Section, keys []string) bool {
+	for _, key := range keys {
+		if !section.HasKey(key) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func haveSameValues(section *ini.Section, other *ini.Section, keys []string) bool {
+	for _, key := range keys {
+		if section.Key(key).Value() != other.Key(key).Value() {
+			return false
 		}
 	}
 
-	return true, ""
+	return true
 }
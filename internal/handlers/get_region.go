@@ -1,7 +1,6 @@
 package handlers
 
 import (
-	"fmt"
 	"github.com/hpcsc/aws-profile/internal/io"
 	"gopkg.in/alecthomas/kingpin.v2"
 )
@@ -18,16 +17,16 @@ func NewGetRegionHandler(app *kingpin.Application) GetRegionHandler {
 	}
 }
 
-func (handler GetRegionHandler) Handle(globalArguments GlobalArguments) (bool, string) {
+func (handler GetRegionHandler) Handle(globalArguments GlobalArguments) (Result, error) {
 	configFile, err := io.ReadFile(globalArguments.ConfigFilePath)
 	if err != nil {
-		return false, fmt.Sprintf("Fail to read AWS config file: %v", err)
+		return Result{}, fileReadError("AWS config file", err)
 	}
 
 	defaultProfileInConfig := configFile.Section("default")
 	if defaultProfileInConfig.HasKey("region") && defaultProfileInConfig.Key("region").Value() != "" {
-		return true, defaultProfileInConfig.Key("region").Value()
+		return Result{Output: defaultProfileInConfig.Key("region").Value()}, nil
 	}
 
-	return true, "no region set"
+	return Result{Output: "no region set"}, nil
 }
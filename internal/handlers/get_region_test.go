@@ -35,39 +35,39 @@ func TestGetRegionHandler(t *testing.T) {
 		handler := setupGetRegionHandler()
 		globalArguments := stubGlobalArgumentsForGetRegion("config_not_exists")
 
-		success, output := handler.Handle(globalArguments)
+		_, err := handler.Handle(globalArguments)
 
-		require.False(t, success)
-		require.Contains(t, output, "Fail to read AWS config file")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "Fail to read AWS config file")
 	})
 
 	t.Run("return 'no region set' when no region set in default profile", func(t *testing.T) {
 		handler := setupGetRegionHandler()
 		globalArguments := stubGlobalArgumentsForGetRegion("set-config")
 
-		success, output := handler.Handle(globalArguments)
+		result, err := handler.Handle(globalArguments)
 
-		require.True(t, success)
-		require.Equal(t, "no region set", output)
+		require.NoError(t, err)
+		require.Equal(t, "no region set", result.Output)
 	})
 
 	t.Run("return 'no region set' when region is set with empty value in default profile", func(t *testing.T) {
 		handler := setupGetRegionHandler()
 		globalArguments := stubGlobalArgumentsForGetRegion("get-region-empty-region-key-config")
 
-		success, output := handler.Handle(globalArguments)
+		result, err := handler.Handle(globalArguments)
 
-		require.True(t, success)
-		require.Equal(t, "no region set", output)
+		require.NoError(t, err)
+		require.Equal(t, "no region set", result.Output)
 	})
 
 	t.Run("return region set in default profile", func(t *testing.T) {
 		handler := setupGetRegionHandler()
 		globalArguments := stubGlobalArgumentsForGetRegion("get-region-config")
 
-		success, output := handler.Handle(globalArguments)
+		result, err := handler.Handle(globalArguments)
 
-		require.True(t, success)
-		require.Equal(t, "us-east-1", output)
+		require.NoError(t, err)
+		require.Equal(t, "us-east-1", result.Output)
 	})
 }
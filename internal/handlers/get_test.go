@@ -50,10 +50,10 @@ func TestGetHandler(t *testing.T) {
 		getHandler := setupHandler()
 		globalArguments := stubGlobalArgumentsForGet("credentials_not_exists", "get_profile_in_neither_file-config")
 
-		success, output := getHandler.Handle(globalArguments)
+		_, err := getHandler.Handle(globalArguments)
 
-		require.False(t, success)
-		require.Contains(t, output, "Fail to read AWS credentials file")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "Fail to read AWS credentials file")
 
 	})
 
@@ -61,10 +61,10 @@ func TestGetHandler(t *testing.T) {
 		getHandler := setupHandler()
 		globalArguments := stubGlobalArgumentsForGet("get_profile_in_neither_file-credentials", "config_not_exists")
 
-		success, output := getHandler.Handle(globalArguments)
+		_, err := getHandler.Handle(globalArguments)
 
-		require.False(t, success)
-		require.Contains(t, output, "Fail to read AWS config file")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "Fail to read AWS config file")
 
 	})
 
@@ -72,10 +72,10 @@ func TestGetHandler(t *testing.T) {
 		getHandler := setupHandler()
 		globalArguments := stubGlobalArgumentsForGet("get_config_priority_over_credentials-credentials", "get_config_priority_over_credentials-config")
 
-		success, output := getHandler.Handle(globalArguments)
+		result, err := getHandler.Handle(globalArguments)
 
-		require.True(t, success)
-		require.Contains(t, output, "profile two")
+		require.NoError(t, err)
+		require.Contains(t, result.Output, "profile two")
 
 	})
 
@@ -83,21 +83,61 @@ func TestGetHandler(t *testing.T) {
 		getHandler := setupHandler()
 		globalArguments := stubGlobalArgumentsForGet("get_profile_in_neither_file-credentials", "get_profile_in_neither_file-config")
 
-		success, output := getHandler.Handle(globalArguments)
+		result, err := getHandler.Handle(globalArguments)
 
-		require.True(t, success)
-		require.Equal(t, "", output)
+		require.NoError(t, err)
+		require.Equal(t, "", result.Output)
 
 	})
 
+	t.Run("return assumed profile if default profile in config uses credential_source", func(t *testing.T) {
+		getHandler := setupHandler()
+		globalArguments := stubGlobalArgumentsForGet("get_profile_in_neither_file-credentials", "get_credential_source_profile-config")
+
+		result, err := getHandler.Handle(globalArguments)
+
+		require.NoError(t, err)
+		require.Equal(t, "profile two", result.Output)
+	})
+
+	t.Run("return sso profile if default profile in config is an sso profile", func(t *testing.T) {
+		getHandler := setupHandler()
+		globalArguments := stubGlobalArgumentsForGet("get_profile_in_neither_file-credentials", "get_sso_profile-config")
+
+		result, err := getHandler.Handle(globalArguments)
+
+		require.NoError(t, err)
+		require.Equal(t, "profile two", result.Output)
+	})
+
+	t.Run("return web identity profile if default profile in config is a web identity profile", func(t *testing.T) {
+		getHandler := setupHandler()
+		globalArguments := stubGlobalArgumentsForGet("get_profile_in_neither_file-credentials", "get_web_identity_profile-config")
+
+		result, err := getHandler.Handle(globalArguments)
+
+		require.NoError(t, err)
+		require.Equal(t, "profile two", result.Output)
+	})
+
+	t.Run("return credential process profile if default profile in config is a credential process profile", func(t *testing.T) {
+		getHandler := setupHandler()
+		globalArguments := stubGlobalArgumentsForGet("get_profile_in_neither_file-credentials", "get_process_profile-config")
+
+		result, err := getHandler.Handle(globalArguments)
+
+		require.NoError(t, err)
+		require.Equal(t, "profile two", result.Output)
+	})
+
 	t.Run("return profile from credentials file if config profile is not set", func(t *testing.T) {
 		getHandler := setupHandler()
 		globalArguments := stubGlobalArgumentsForGet("get_profile_not_in_config-credentials", "get_profile_not_in_config-config")
 
-		success, output := getHandler.Handle(globalArguments)
+		result, err := getHandler.Handle(globalArguments)
 
-		require.True(t, success)
-		require.Contains(t, output, "two_credentials")
+		require.NoError(t, err)
+		require.Contains(t, result.Output, "two_credentials")
 
 	})
 
@@ -108,10 +148,10 @@ func TestGetHandler(t *testing.T) {
 		os.Setenv("AWS_SESSION_TOKEN", "aws-session-key")
 		globalArguments := stubGlobalArgumentsForGet("get_profile_not_in_config-credentials", "get_profile_not_in_config-config")
 
-		success, output := getHandler.Handle(globalArguments)
+		result, err := getHandler.Handle(globalArguments)
 
-		require.True(t, success)
-		require.Contains(t, output, "caller-identity-profile")
+		require.NoError(t, err)
+		require.Contains(t, result.Output, "caller-identity-profile")
 
 		os.Unsetenv("AWS_ACCESS_KEY_ID")
 		os.Unsetenv("AWS_SECRET_ACCESS_KEY")
@@ -153,10 +193,10 @@ func TestGetHandler(t *testing.T) {
 				os.Setenv("AWS_SESSION_TOKEN", "aws-session-key")
 				globalArguments := stubGlobalArgumentsForGet("get_profile_not_in_config-credentials", "get_profile_not_in_config-config")
 
-				success, output := getHandler.Handle(globalArguments)
+				result, err := getHandler.Handle(globalArguments)
 
-				require.True(t, success)
-				require.Contains(t, output, tt.expectedOutput)
+				require.NoError(t, err)
+				require.Contains(t, result.Output, tt.expectedOutput)
 
 				os.Unsetenv("AWS_ACCESS_KEY_ID")
 				os.Unsetenv("AWS_SECRET_ACCESS_KEY")
@@ -179,10 +219,10 @@ func TestGetHandler(t *testing.T) {
 		os.Setenv("AWS_SESSION_TOKEN", "aws-session-key")
 		globalArguments := stubGlobalArgumentsForGet("get_profile_not_in_config-credentials", "get_profile_not_in_config-config")
 
-		success, output := getHandler.Handle(globalArguments)
+		result, err := getHandler.Handle(globalArguments)
 
-		require.True(t, success)
-		require.Contains(t, output, "unknown")
+		require.NoError(t, err)
+		require.Contains(t, result.Output, "unknown")
 
 		os.Unsetenv("AWS_ACCESS_KEY_ID")
 		os.Unsetenv("AWS_SECRET_ACCESS_KEY")
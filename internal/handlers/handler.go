@@ -5,6 +5,14 @@ type GlobalArguments struct {
 	ConfigFilePath      string
 }
 
+// Result is printed to stdout when a command succeeds
+type Result struct {
+	Output string
+	// ExitCode is only set by commands exiting with exit code of another process, e.g. exec
+	ExitCode int
+}
+
+// Handler runs a command, errors are printed to stderr and exit with exit code of their category
 type Handler interface {
-	Handle(globalArguments GlobalArguments) (bool, string)
+	Handle(globalArguments GlobalArguments) (Result, error)
 }
@@ -0,0 +1,24 @@
+package handlers
+
+type LockFileFn func(string) (func() error, error)
+
+// lockFiles locks given files in order, returned function releases all of them
+func lockFiles(lockFile LockFileFn, filePaths ...string) (func(), error) {
+	var unlockFns []func() error
+	unlockAll := func() {
+		for i := len(unlockFns) - 1; i >= 0; i-- {
+			_ = unlockFns[i]()
+		}
+	}
+
+	for _, filePath := range filePaths {
+		unlock, err := lockFile(filePath)
+		if err != nil {
+			unlockAll()
+			return nil, err
+		}
+		unlockFns = append(unlockFns, unlock)
+	}
+
+	return unlockAll, nil
+}
@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"github.com/hpcsc/aws-profile/internal/aws"
+	"github.com/hpcsc/aws-profile/internal/awsconfig"
+	"github.com/hpcsc/aws-profile/internal/config"
+	"github.com/hpcsc/aws-profile/internal/mfa"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+func mfaTokenFlag(subCommand *kingpin.CmdClause) *string {
+	return subCommand.Flag("mfa-token", "MFA token code for profiles with mfa_serial, instead of provider configured in aws-profile config").String()
+}
+
+// mfaTokenProvider uses token given by --mfa-token if any, otherwise MFA provider configured for each profile
+func mfaTokenProvider(c *config.Config, mfaToken string) aws.MFATokenProviderFn {
+	return func(profile awsconfig.Profile) mfa.TokenProvider {
+		if mfaToken != "" {
+			return mfa.Static(mfaToken)
+		}
+
+		provider := c.MFAProviderOf(profile.ProfileName)
+		switch provider.Type {
+		case config.MFAProviderTOTP:
+			return mfa.TOTP(mfa.NewDefaultSecretStore(), profile.MFASerialNumber)
+		case config.MFAProviderProcess:
+			return mfa.Process(provider.Command)
+		default:
+			return mfa.Prompt(profile.MFASerialNumber)
+		}
+	}
+}
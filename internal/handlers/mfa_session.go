@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"github.com/hpcsc/aws-profile/internal/aws"
+	"github.com/hpcsc/aws-profile/internal/awsconfig"
+	"github.com/hpcsc/aws-profile/internal/io"
+	"gopkg.in/ini.v1"
+	"os"
+	"time"
+)
+
+type GetSessionTokenFn func(awsconfig.Profile, aws.MFATokenProviderFn) (aws.Credentials, error)
+
+// newMFASession gets new MFA session credentials of given credentials file profile, or returns nil when its MFA session
+// section has credentials not expiring within refresh window
+func newMFASession(
+	credentialsFile *ini.File,
+	profile awsconfig.Profile,
+	getSessionToken GetSessionTokenFn,
+	mfaTokenProvider aws.MFATokenProviderFn,
+	refreshWindow time.Duration,
+	now time.Time,
+) (*aws.Credentials, error) {
+	sessionProfileName := awsconfig.MFASessionProfileName(profile.ProfileName)
+
+	if section, err := credentialsFile.GetSection(sessionProfileName); err == nil && section.HasKey("aws_session_token") {
+		expiration, err := time.Parse(time.RFC3339, section.Key("expiration").Value())
+		if err == nil && expiration.After(now.Add(refreshWindow)) {
+			return nil, nil
+		}
+	}
+
+	sessionCredentials, err := getSessionToken(profile, mfaTokenProvider)
+	if err != nil {
+		return nil, credentialsError(err)
+	}
+
+	return &sessionCredentials, nil
+}
+
+// setMFASession keeps MFA session credentials of given profile in its MFA session section
+func setMFASession(credentialsFile *ini.File, profileName string, sessionCredentials aws.Credentials) {
+	setCredentialsFileKeys(credentialsFile.Section(awsconfig.MFASessionProfileName(profileName)), sessionCredentials)
+}
+
+// useMFASession replaces credentials file profile with mfa_serial at the base of chain with its MFA session profile,
+// refreshing MFA session in credentials file when needed. Access key id of MFA session is returned to tell sessions
+// apart, empty if chain doesn't use MFA session
+func (getter CredentialsGetter) useMFASession(chain []awsconfig.Profile, options credentialsOptions) ([]awsconfig.Profile, string, error) {
+	if len(chain) == 0 || !chain[0].UsesMFASession() {
+		return chain, "", nil
+	}
+
+	credentialsFile, err := readCredentialsFileOrEmpty(options.credentialsFilePath)
+	if err != nil {
+		return nil, "", err
+	}
+
+	sessionCredentials, err := newMFASession(credentialsFile, chain[0], getter.GetSessionToken, options.mfaTokenProvider, options.refreshWindow, time.Now())
+	if err != nil {
+		return nil, "", err
+	}
+
+	sessionProfileName := awsconfig.MFASessionProfileName(chain[0].ProfileName)
+	var sessionAccessKeyId string
+	if sessionCredentials != nil {
+		if err := getter.saveMFASession(options.credentialsFilePath, chain[0].ProfileName, *sessionCredentials); err != nil {
+			return nil, "", err
+		}
+
+		sessionAccessKeyId = sessionCredentials.AccessKeyID
+	} else {
+		sessionAccessKeyId = credentialsFile.Section(sessionProfileName).Key("aws_access_key_id").Value()
+	}
+
+	sessionProfile := awsconfig.Profile{
+		ProfileName:        sessionProfileName,
+		DisplayProfileName: sessionProfileName,
+		Region:             chain[0].Region,
+	}
+
+	return append([]awsconfig.Profile{sessionProfile}, chain[1:]...), sessionAccessKeyId, nil
+}
+
+// credentials file is only locked once MFA token is entered, then read again to keep changes made while waiting for it
+func (getter CredentialsGetter) saveMFASession(credentialsFilePath string, profileName string, sessionCredentials aws.Credentials) error {
+	unlock, err := lockFiles(getter.LockFile, credentialsFilePath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	credentialsFile, err := readCredentialsFileOrEmpty(credentialsFilePath)
+	if err != nil {
+		return err
+	}
+
+	setMFASession(credentialsFile, profileName, sessionCredentials)
+	return getter.WriteToFile(credentialsFile, credentialsFilePath)
+}
+
+// readCredentialsFileOrEmpty reads credentials file that is created when it's written if it doesn't exist yet
+func readCredentialsFileOrEmpty(credentialsFilePath string) (*ini.File, error) {
+	credentialsFile, err := io.ReadFile(credentialsFilePath)
+	if os.IsNotExist(err) {
+		return ini.Empty(), nil
+	}
+
+	if err != nil {
+		return nil, fileReadError("AWS credentials file", err)
+	}
+
+	return credentialsFile, nil
+}
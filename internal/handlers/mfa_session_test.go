@@ -0,0 +1,318 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/hpcsc/aws-profile/internal/aws"
+	"github.com/hpcsc/aws-profile/internal/awsconfig"
+	"github.com/hpcsc/aws-profile/internal/config"
+	"github.com/hpcsc/aws-profile/internal/mfa"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/alecthomas/kingpin.v2"
+	"gopkg.in/ini.v1"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func stubMFASessionCredentials() aws.Credentials {
+	return aws.Credentials{
+		Value: credentials.Value{
+			AccessKeyID:     "session-key-id",
+			SecretAccessKey: "session-secret",
+			SessionToken:    "session-token",
+		},
+		Expiration: time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC),
+	}
+}
+
+func stubGetSessionToken(_ awsconfig.Profile, _ aws.MFATokenProviderFn) (aws.Credentials, error) {
+	return stubMFASessionCredentials(), nil
+}
+
+func stubGlobalArgumentsForMFASession() GlobalArguments {
+	credentialsPath, _ := filepath.Abs("./test_data/mfa-session-credentials")
+	configPath, _ := filepath.Abs("./test_data/mfa-session-config")
+
+	return GlobalArguments{
+		CredentialsFilePath: credentialsPath,
+		ConfigFilePath:      configPath,
+	}
+}
+
+func TestNewMFASession(t *testing.T) {
+	now := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	profile := awsconfig.Profile{
+		ProfileName:     "iam_user",
+		MFASerialNumber: "arn:aws:iam::123456789012:mfa/user",
+	}
+
+	credentialsFileWithSession := func(expiration time.Time) *ini.File {
+		credentialsFile := ini.Empty()
+		section, _ := credentialsFile.NewSection("iam_user-mfa")
+		section.Key("aws_access_key_id").SetValue("existing-key-id")
+		section.Key("aws_secret_access_key").SetValue("existing-secret")
+		section.Key("aws_session_token").SetValue("existing-token")
+		section.Key("expiration").SetValue(expiration.Format(time.RFC3339))
+		return credentialsFile
+	}
+
+	t.Run("keep session not expiring within refresh window", func(t *testing.T) {
+		getSessionTokenMock := func(_ awsconfig.Profile, _ aws.MFATokenProviderFn) (aws.Credentials, error) {
+			require.Fail(t, "unexpected call to GetSessionToken")
+			return aws.Credentials{}, nil
+		}
+		credentialsFile := credentialsFileWithSession(now.Add(time.Hour))
+
+		sessionCredentials, err := newMFASession(credentialsFile, profile, getSessionTokenMock, nil, 10*time.Minute, now)
+
+		require.NoError(t, err)
+		require.Nil(t, sessionCredentials)
+	})
+
+	t.Run("get new session when session expires within refresh window", func(t *testing.T) {
+		credentialsFile := credentialsFileWithSession(now.Add(5 * time.Minute))
+
+		sessionCredentials, err := newMFASession(credentialsFile, profile, stubGetSessionToken, nil, 10*time.Minute, now)
+
+		require.NoError(t, err)
+		require.Equal(t, stubMFASessionCredentials(), *sessionCredentials)
+	})
+
+	t.Run("get new session with MFA token provider when session does not exist", func(t *testing.T) {
+		var requestedProfile awsconfig.Profile
+		var requestedTokenProvider aws.MFATokenProviderFn
+		getSessionTokenMock := func(profile awsconfig.Profile, mfaTokenProvider aws.MFATokenProviderFn) (aws.Credentials, error) {
+			requestedProfile = profile
+			requestedTokenProvider = mfaTokenProvider
+			return stubMFASessionCredentials(), nil
+		}
+		tokenProvider := func(_ awsconfig.Profile) mfa.TokenProvider {
+			return mfa.Static("123456")
+		}
+		credentialsFile := ini.Empty()
+
+		sessionCredentials, err := newMFASession(credentialsFile, profile, getSessionTokenMock, tokenProvider, 10*time.Minute, now)
+
+		require.NoError(t, err)
+		require.Equal(t, "session-key-id", sessionCredentials.AccessKeyID)
+		require.Equal(t, "iam_user", requestedProfile.ProfileName)
+		token, _ := requestedTokenProvider(profile)()
+		require.Equal(t, "123456", token)
+	})
+
+	t.Run("return error from GetSessionToken", func(t *testing.T) {
+		getSessionTokenFailure := func(_ awsconfig.Profile, _ aws.MFATokenProviderFn) (aws.Credentials, error) {
+			return aws.Credentials{}, errors.New("invalid MFA one time pass code")
+		}
+
+		_, err := newMFASession(ini.Empty(), profile, getSessionTokenFailure, nil, 10*time.Minute, now)
+
+		require.EqualError(t, err, "invalid MFA one time pass code")
+	})
+}
+
+func TestExportHandler_MFASession(t *testing.T) {
+	selectProfileMock := func(_ awsconfig.Profiles, _ string, _ *config.Config) ([]byte, error) {
+		return []byte("profile mfa_role"), nil
+	}
+
+	t.Run("use refreshed MFA session as source of selected profile", func(t *testing.T) {
+		var requestedSessionProfile awsconfig.Profile
+		getSessionTokenMock := func(profile awsconfig.Profile, _ aws.MFATokenProviderFn) (aws.Credentials, error) {
+			requestedSessionProfile = profile
+			return stubMFASessionCredentials(), nil
+		}
+
+		var requestedChain []awsconfig.Profile
+		getAWSCredentialsMock := func(chain []awsconfig.Profile, _ time.Duration, _ aws.MFATokenProviderFn) (aws.Credentials, error) {
+			requestedChain = chain
+			return stubAWSCredentials(), nil
+		}
+
+		writtenFiles := map[string]*ini.File{}
+		writeToFileMock := func(file *ini.File, filePath string) error {
+			writtenFiles[filePath] = file
+			return nil
+		}
+
+		app := kingpin.New("some-app", "some description")
+		credentialsGetter := stubCredentialsGetter(getAWSCredentialsMock)
+		credentialsGetter.GetSessionToken = getSessionTokenMock
+		credentialsGetter.WriteToFile = writeToFileMock
+		exportHandler := NewExportHandler(app, stubConfig(), stubDetectShell(false), selectProfileMock, credentialsGetter, noopRecordHistory)
+		_, err := app.Parse([]string{"export"})
+		require.NoError(t, err)
+		globalArguments := stubGlobalArgumentsForMFASession()
+
+		_, err = exportHandler.Handle(globalArguments)
+
+		require.NoError(t, err)
+		require.Equal(t, "iam_user", requestedSessionProfile.ProfileName)
+		require.Equal(t, "arn:aws:iam::123456789012:mfa/user", requestedSessionProfile.MFASerialNumber)
+
+		require.Len(t, requestedChain, 2)
+		require.Equal(t, "iam_user-mfa", requestedChain[0].ProfileName)
+		require.Equal(t, "profile mfa_role", requestedChain[1].ProfileName)
+
+		credentialsFile := writtenFiles[globalArguments.CredentialsFilePath]
+		require.NotNil(t, credentialsFile)
+		require.Equal(t, "session-key-id", credentialsFile.Section("iam_user-mfa").Key("aws_access_key_id").Value())
+		require.Equal(t, "long-term-key-id", credentialsFile.Section("iam_user").Key("aws_access_key_id").Value())
+	})
+}
+
+func TestExportHandler_MFASessionCache(t *testing.T) {
+	t.Run("not reuse cached credentials retrieved with previous MFA session", func(t *testing.T) {
+		selectProfileMock := func(_ awsconfig.Profiles, _ string, _ *config.Config) ([]byte, error) {
+			return []byte("profile mfa_role"), nil
+		}
+
+		exportWithSession := func(sessionAccessKeyId string) string {
+			getSessionTokenStub := func(_ awsconfig.Profile, _ aws.MFATokenProviderFn) (aws.Credentials, error) {
+				sessionCredentials := stubMFASessionCredentials()
+				sessionCredentials.AccessKeyID = sessionAccessKeyId
+				return sessionCredentials, nil
+			}
+
+			var readKey string
+			readCachedCredentialsMock := func(key string, _ time.Duration) (*aws.Credentials, error) {
+				readKey = key
+				return nil, nil
+			}
+
+			app := kingpin.New("some-app", "some description")
+			credentialsGetter := stubCredentialsGetter(stubGetAWSCredentials)
+			credentialsGetter.GetSessionToken = getSessionTokenStub
+			credentialsGetter.ReadCachedCredentials = readCachedCredentialsMock
+			exportHandler := NewExportHandler(app, stubConfig(), stubDetectShell(false), selectProfileMock, credentialsGetter, noopRecordHistory)
+			_, err := app.Parse([]string{"export"})
+			require.NoError(t, err)
+
+			_, err = exportHandler.Handle(stubGlobalArgumentsForMFASession())
+			require.NoError(t, err)
+
+			return readKey
+		}
+
+		require.NotEqual(t, exportWithSession("session-1-key-id"), exportWithSession("session-2-key-id"))
+	})
+}
+
+func TestExportHandler_MFASessionLock(t *testing.T) {
+	t.Run("lock credentials file only after MFA session is received", func(t *testing.T) {
+		var events []string
+		selectProfileMock := func(_ awsconfig.Profiles, _ string, _ *config.Config) ([]byte, error) {
+			return []byte("profile mfa_role"), nil
+		}
+		getSessionTokenMock := func(_ awsconfig.Profile, _ aws.MFATokenProviderFn) (aws.Credentials, error) {
+			events = append(events, "get session token")
+			return stubMFASessionCredentials(), nil
+		}
+		writeToFileMock := func(_ *ini.File, filePath string) error {
+			events = append(events, "write "+filepath.Base(filePath))
+			return nil
+		}
+
+		app := kingpin.New("some-app", "some description")
+		credentialsGetter := stubCredentialsGetter(stubGetAWSCredentials)
+		credentialsGetter.GetSessionToken = getSessionTokenMock
+		credentialsGetter.WriteToFile = writeToFileMock
+		credentialsGetter.LockFile = recordingLockFile(&events)
+		exportHandler := NewExportHandler(app, stubConfig(), stubDetectShell(false), selectProfileMock, credentialsGetter, noopRecordHistory)
+		_, err := app.Parse([]string{"export"})
+		require.NoError(t, err)
+
+		_, err = exportHandler.Handle(stubGlobalArgumentsForMFASession())
+
+		require.NoError(t, err)
+		require.Equal(t, []string{
+			"get session token",
+			"lock mfa-session-credentials",
+			"write mfa-session-credentials",
+			"unlock mfa-session-credentials",
+		}, events)
+	})
+}
+
+func TestSetHandler_MFASession(t *testing.T) {
+	t.Run("get MFA session before locking files", func(t *testing.T) {
+		var events []string
+		selectProfileMock := func(_ awsconfig.Profiles, _ string, _ *config.Config) ([]byte, error) {
+			return []byte("iam_user"), nil
+		}
+		getSessionTokenMock := func(_ awsconfig.Profile, _ aws.MFATokenProviderFn) (aws.Credentials, error) {
+			events = append(events, "get session token")
+			return stubMFASessionCredentials(), nil
+		}
+
+		app := kingpin.New("some-app", "some description")
+		setHandler := NewSetHandler(app, stubConfig(), selectProfileMock, stubIsInteractive(true), noopWriteToFileMock, recordingLockFile(&events), noopRecordHistory, getSessionTokenMock)
+		_, err := app.Parse([]string{"set"})
+		require.NoError(t, err)
+
+		_, err = setHandler.Handle(stubGlobalArgumentsForMFASession())
+
+		require.NoError(t, err)
+		require.Equal(t, []string{
+			"get session token",
+			"lock mfa-session-credentials",
+			"lock mfa-session-config",
+			"unlock mfa-session-config",
+			"unlock mfa-session-credentials",
+		}, events)
+	})
+
+	t.Run("set default profile with refreshed MFA session of selected credentials profile", func(t *testing.T) {
+		selectProfileMock := func(_ awsconfig.Profiles, _ string, _ *config.Config) ([]byte, error) {
+			return []byte("iam_user"), nil
+		}
+
+		writtenFiles := map[string]*ini.File{}
+		writeToFileMock := func(file *ini.File, filePath string) error {
+			writtenFiles[filePath] = file
+			return nil
+		}
+
+		app := kingpin.New("some-app", "some description")
+		setHandler := NewSetHandler(app, stubConfig(), selectProfileMock, stubIsInteractive(true), writeToFileMock, noopLockFile, noopRecordHistory, stubGetSessionToken)
+		_, err := app.Parse([]string{"set"})
+		require.NoError(t, err)
+		globalArguments := stubGlobalArgumentsForMFASession()
+
+		result, err := setHandler.Handle(globalArguments)
+
+		require.NoError(t, err)
+		require.Contains(t, result.Output, "[iam_user] -> [default]")
+
+		credentialsFile := writtenFiles[globalArguments.CredentialsFilePath]
+		require.NotNil(t, credentialsFile)
+		defaultSection := credentialsFile.Section("default")
+		require.Equal(t, "session-key-id", defaultSection.Key("aws_access_key_id").Value())
+		require.Equal(t, "session-secret", defaultSection.Key("aws_secret_access_key").Value())
+		require.Equal(t, "session-token", defaultSection.Key("aws_session_token").Value())
+		require.Equal(t, "session-key-id", credentialsFile.Section("iam_user-mfa").Key("aws_access_key_id").Value())
+
+		configFile := writtenFiles[globalArguments.ConfigFilePath]
+		require.NotNil(t, configFile)
+		require.Equal(t, "ap-southeast-2", configFile.Section("default").Key("region").Value())
+	})
+
+	t.Run("not list MFA session section as a profile", func(t *testing.T) {
+		var listedProfiles []string
+		selectProfileMock := func(profiles awsconfig.Profiles, _ string, _ *config.Config) ([]byte, error) {
+			listedProfiles = profiles.GetAllDisplayProfileNames()
+			return nil, errors.New("not selected")
+		}
+
+		app := kingpin.New("some-app", "some description")
+		setHandler := NewSetHandler(app, stubConfig(), selectProfileMock, stubIsInteractive(true), noopWriteToFileMock, noopLockFile, noopRecordHistory, stubGetSessionToken)
+		_, err := app.Parse([]string{"set"})
+		require.NoError(t, err)
+
+		setHandler.Handle(stubGlobalArgumentsForMFASession())
+
+		require.Contains(t, listedProfiles, "iam_user")
+		require.NotContains(t, listedProfiles, "iam_user-mfa")
+	})
+}
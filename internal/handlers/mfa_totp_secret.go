@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+type WriteTOTPSecretFn func(string, string) error
+
+type MFASetTOTPSecretHandler struct {
+	SubCommand      *kingpin.CmdClause
+	Arguments       MFASetTOTPSecretCommandArguments
+	WriteTOTPSecret WriteTOTPSecretFn
+	Input           io.Reader
+}
+
+type MFASetTOTPSecretCommandArguments struct {
+	SerialNumber *string
+}
+
+func NewMFASetTOTPSecretHandler(app *kingpin.Application, writeTOTPSecretFn WriteTOTPSecretFn, input io.Reader) MFASetTOTPSecretHandler {
+	mfaCommand := app.Command("mfa", "manage MFA token providers")
+	subCommand := mfaCommand.Command("set-totp-secret", `store TOTP secret of a virtual MFA device read from stdin, encrypted, for profiles using totp MFA provider
+
+Example: "op read op://aws/mfa/secret | aws-profile mfa set-totp-secret arn:aws:iam::123456789012:mfa/user"`)
+
+	serialNumber := subCommand.Arg("mfa-serial", "mfa_serial of profiles using the MFA device").Required().String()
+
+	return MFASetTOTPSecretHandler{
+		SubCommand: subCommand,
+		Arguments: MFASetTOTPSecretCommandArguments{
+			SerialNumber: serialNumber,
+		},
+		WriteTOTPSecret: writeTOTPSecretFn,
+		Input:           input,
+	}
+}
+
+func (handler MFASetTOTPSecretHandler) Handle(globalArguments GlobalArguments) (Result, error) {
+	line, err := bufio.NewReader(handler.Input).ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return Result{}, fmt.Errorf("Failed to read TOTP secret: %v", err)
+	}
+
+	if err := handler.WriteTOTPSecret(*handler.Arguments.SerialNumber, strings.TrimSpace(line)); err != nil {
+		return Result{}, fmt.Errorf("Failed to store TOTP secret: %v", err)
+	}
+
+	return Result{Output: fmt.Sprintf("=== TOTP secret of [%s] stored", *handler.Arguments.SerialNumber)}, nil
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+func setupMFASetTOTPSecretHandler(t *testing.T, writeTOTPSecretFn WriteTOTPSecretFn, input string) MFASetTOTPSecretHandler {
+	app := kingpin.New("some-app", "some description")
+	handler := NewMFASetTOTPSecretHandler(app, writeTOTPSecretFn, strings.NewReader(input))
+	_, err := app.Parse([]string{"mfa", "set-totp-secret", "arn:aws:iam::123456789012:mfa/user"})
+	require.NoError(t, err)
+
+	return handler
+}
+
+func TestMFASetTOTPSecretHandler(t *testing.T) {
+	t.Run("store secret read from input for given mfa serial", func(t *testing.T) {
+		var storedSerialNumber, storedSecret string
+		writeTOTPSecretMock := func(serialNumber string, secret string) error {
+			storedSerialNumber, storedSecret = serialNumber, secret
+			return nil
+		}
+
+		result, err := setupMFASetTOTPSecretHandler(t, writeTOTPSecretMock, "GEZDGNBVGY3TQOJQ\n").Handle(GlobalArguments{})
+
+		require.NoError(t, err)
+		require.Equal(t, "=== TOTP secret of [arn:aws:iam::123456789012:mfa/user] stored", result.Output)
+		require.Equal(t, "arn:aws:iam::123456789012:mfa/user", storedSerialNumber)
+		require.Equal(t, "GEZDGNBVGY3TQOJQ", storedSecret)
+	})
+
+	t.Run("return error if input is empty", func(t *testing.T) {
+		_, err := setupMFASetTOTPSecretHandler(t, nil, "").Handle(GlobalArguments{})
+
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "Failed to read TOTP secret")
+	})
+
+	t.Run("return error if secret can't be stored", func(t *testing.T) {
+		writeTOTPSecretFailure := func(_ string, _ string) error {
+			return errors.New("TOTP secret is not valid base32")
+		}
+
+		_, err := setupMFASetTOTPSecretHandler(t, writeTOTPSecretFailure, "invalid\n").Handle(GlobalArguments{})
+
+		require.Error(t, err)
+		require.Equal(t, "Failed to store TOTP secret: TOTP secret is not valid base32", err.Error())
+	})
+}
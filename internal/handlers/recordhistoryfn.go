@@ -0,0 +1,9 @@
+package handlers
+
+type RecordHistoryFn func(string) error
+
+// recordHistory records given profile as recently used. History only affects order of profiles in the picker, failing
+// to record it doesn't fail the command
+func recordHistory(record RecordHistoryFn, profileName string) {
+	_ = record(profileName)
+}
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"github.com/hpcsc/aws-profile/internal/awsconfig"
+	"github.com/hpcsc/aws-profile/internal/config"
+	"strings"
+)
+
+type IsInteractiveFn func() bool
+
+// selectProfileNonInteractively has the same signature as profile picker. It selects profile with name matching
+// pattern exactly ignoring case, with or without "profile " prefix, or the only profile containing pattern
+func selectProfileNonInteractively(profiles awsconfig.Profiles, pattern string, _ *config.Config) ([]byte, error) {
+	if pattern == "" {
+		return nil, newError(CategoryUsage, "profile name is required when not running interactively")
+	}
+
+	var exactMatches []awsconfig.Profile
+	for _, profile := range profiles.Filter("") {
+		if strings.EqualFold(profile.ProfileName, pattern) || strings.EqualFold(profile.ProfileName, "profile "+pattern) {
+			exactMatches = append(exactMatches, profile)
+		}
+	}
+
+	matches := exactMatches
+	if len(matches) == 0 {
+		matches = profiles.Filter(pattern)
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, newError(CategoryNotFound, "no profile matches [%s]", pattern)
+	case 1:
+		return []byte(matches[0].ProfileName), nil
+	default:
+		var names []string
+		for _, profile := range matches {
+			names = append(names, "  "+profile.ProfileName)
+		}
+
+		return nil, newError(CategoryUsage, "multiple profiles match [%s]:\n%s", pattern, strings.Join(names, "\n"))
+	}
+}
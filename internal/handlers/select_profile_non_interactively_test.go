@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"github.com/hpcsc/aws-profile/internal/awsconfig"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestSelectProfileNonInteractively(t *testing.T) {
+	profiles := awsconfig.Profiles{
+		CredentialsProfiles: []awsconfig.Profile{
+			{ProfileName: "dev", DisplayProfileName: "dev"},
+		},
+		ConfigAssumedProfiles: []awsconfig.Profile{
+			{ProfileName: "profile dev-admin", DisplayProfileName: "assume dev-admin"},
+			{ProfileName: "profile prod", DisplayProfileName: "assume prod"},
+		},
+	}
+
+	t.Run("select profile matching pattern exactly even if other profiles contain pattern", func(t *testing.T) {
+		selected, err := selectProfileNonInteractively(profiles, "dev", nil)
+
+		require.NoError(t, err)
+		require.Equal(t, "dev", string(selected))
+	})
+
+	t.Run("select config file profile matching pattern without profile prefix", func(t *testing.T) {
+		selected, err := selectProfileNonInteractively(profiles, "prod", nil)
+
+		require.NoError(t, err)
+		require.Equal(t, "profile prod", string(selected))
+	})
+
+	t.Run("select profile matching pattern exactly ignoring case", func(t *testing.T) {
+		selected, err := selectProfileNonInteractively(profiles, "PROD", nil)
+
+		require.NoError(t, err)
+		require.Equal(t, "profile prod", string(selected))
+	})
+
+	t.Run("return usage error if multiple profiles match pattern exactly ignoring case", func(t *testing.T) {
+		profilesDifferentInCase := awsconfig.Profiles{
+			CredentialsProfiles: []awsconfig.Profile{
+				{ProfileName: "prod"},
+				{ProfileName: "Prod"},
+			},
+		}
+
+		_, err := selectProfileNonInteractively(profilesDifferentInCase, "prod", nil)
+
+		require.Error(t, err)
+		require.Equal(t, CategoryUsage, CategoryOf(err))
+	})
+
+	t.Run("select the only profile containing pattern", func(t *testing.T) {
+		selected, err := selectProfileNonInteractively(profiles, "admin", nil)
+
+		require.NoError(t, err)
+		require.Equal(t, "profile dev-admin", string(selected))
+	})
+}
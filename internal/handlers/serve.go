@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/hpcsc/aws-profile/internal/aws"
+	"github.com/hpcsc/aws-profile/internal/awsconfig"
+	"github.com/hpcsc/aws-profile/internal/config"
+	"github.com/hpcsc/aws-profile/internal/server"
+	"github.com/hpcsc/aws-profile/internal/shell"
+	"gopkg.in/alecthomas/kingpin.v2"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type ServeFn func(net.Listener, http.Handler) error
+type GenerateTokenFn func() (string, error)
+
+type ServeHandler struct {
+	SubCommand        *kingpin.CmdClause
+	Arguments         ServeCommandArguments
+	DetectShell       DetectShellFn
+	SelectProfile     SelectProfileFn
+	CredentialsGetter CredentialsGetter
+	GenerateToken     GenerateTokenFn
+	Serve             ServeFn
+	Output            io.Writer
+	Config            *config.Config
+}
+
+type ServeCommandArguments struct {
+	Profile *string
+	Address *string
+	Port    *int
+	IMDS    *bool
+	Shell   *string
+	CredentialsArguments
+}
+
+func NewServeHandler(
+	app *kingpin.Application,
+	config *config.Config,
+	detectShellFn DetectShellFn,
+	selectProfileFn SelectProfileFn,
+	credentialsGetter CredentialsGetter,
+	generateTokenFn GenerateTokenFn,
+	serveFn ServeFn,
+	output io.Writer,
+) ServeHandler {
+	subCommand := app.Command("serve", `run a local server returning credentials of given profile in the same format as ECS container credentials endpoint
+
+Credentials are refreshed before they expire. Set the environment variables printed on startup for AWS SDKs and tools to use the server
+
+With --imds, the server emulates EC2 Instance Metadata Service (IMDSv2) instead`)
+
+	profile := subCommand.Flag("profile", "Name of profile in config file, profile is selected from list filtered by this name if it doesn't match exactly").Short('p').String()
+	address := subCommand.Flag("address", "Address to listen on. AWS SDKs only accept ECS credentials endpoint on loopback address, other addresses are only useful with --imds").Default("127.0.0.1").String()
+	port := subCommand.Flag("port", "Port to listen on, a random port is used if not set").Int()
+	imds := subCommand.Flag("imds", "Emulate EC2 Instance Metadata Service (IMDSv2) instead of ECS container credentials endpoint").Bool()
+	shellName := shellFlag(subCommand)
+	credentialsArguments := credentialsFlags(subCommand)
+
+	return ServeHandler{
+		SubCommand: subCommand,
+		Arguments: ServeCommandArguments{
+			Profile:              profile,
+			Address:              address,
+			Port:                 port,
+			IMDS:                 imds,
+			Shell:                shellName,
+			CredentialsArguments: credentialsArguments,
+		},
+		DetectShell:       detectShellFn,
+		SelectProfile:     selectProfileFn,
+		CredentialsGetter: credentialsGetter,
+		GenerateToken:     generateTokenFn,
+		Serve:             serveFn,
+		Output:            output,
+		Config:            config,
+	}
+}
+
+func (handler ServeHandler) Handle(globalArguments GlobalArguments) (Result, error) {
+	profiles, err := loadProfilesForCredentials(globalArguments)
+	if err != nil {
+		return Result{}, err
+	}
+
+	options, err := newCredentialsOptions(handler.Arguments.CredentialsArguments, handler.Config, "", globalArguments)
+	if err != nil {
+		return Result{}, err
+	}
+
+	profile, err := findConfigFileProfileByName(profiles, *handler.Arguments.Profile, handler.SelectProfile, handler.Config)
+	if err != nil {
+		return Result{}, err
+	}
+
+	chain, err := profiles.ResolveSourceChain(profile)
+	if err != nil {
+		return Result{}, withCategory(CategoryConfigInvalid, err)
+	}
+
+	formatter, err := shellFormatter(*handler.Arguments.Shell, handler.DetectShell)
+	if err != nil {
+		return Result{}, err
+	}
+
+	credentials := server.NewRefreshingCredentials(func() (aws.Credentials, error) {
+		return handler.CredentialsGetter.get(chain, options)
+	}, handler.Config.CacheRefreshWindowDuration())
+
+	// get credentials before serving so that errors and prompts (e.g. MFA) happen at startup
+	if _, err := credentials.Get(); err != nil {
+		return Result{}, err
+	}
+
+	listener, err := net.Listen("tcp", net.JoinHostPort(*handler.Arguments.Address, strconv.Itoa(*handler.Arguments.Port)))
+	if err != nil {
+		return Result{}, fmt.Errorf("Failed to start credential server: %v", err)
+	}
+	defer func() { _ = listener.Close() }()
+
+	url := fmt.Sprintf("http://%s/", listener.Addr().String())
+
+	var httpHandler http.Handler
+	var environmentVariables []shell.Variable
+	if *handler.Arguments.IMDS {
+		// IMDS has no authorization, any process able to reach the address can get credentials
+		httpHandler = server.NewIMDSHandler(imdsRoleName(profile), credentials)
+		environmentVariables = []shell.Variable{
+			{Name: "AWS_EC2_METADATA_SERVICE_ENDPOINT", Value: url},
+		}
+	} else {
+		token, err := handler.GenerateToken()
+		if err != nil {
+			return Result{}, err
+		}
+
+		httpHandler = server.NewECSHandler(token, credentials)
+		environmentVariables = []shell.Variable{
+			{Name: "AWS_CONTAINER_CREDENTIALS_FULL_URI", Value: url},
+			{Name: "AWS_CONTAINER_AUTHORIZATION_TOKEN", Value: token},
+		}
+	}
+
+	_, _ = fmt.Fprintf(handler.Output, "=== serving credentials of [%s] on %s, press Ctrl+C to stop\n%s\n",
+		profile.ProfileName,
+		listener.Addr().String(),
+		formatter.Set(environmentVariables),
+	)
+
+	if err := handler.Serve(listener, httpHandler); err != nil {
+		return Result{}, err
+	}
+
+	return Result{}, nil
+}
+
+// role name reported by IMDS, taken from role arn if profile assumes a role
+func imdsRoleName(profile *awsconfig.Profile) string {
+	if profile.RoleArn != "" {
+		return profile.RoleArn[strings.LastIndex(profile.RoleArn, "/")+1:]
+	}
+
+	return strings.TrimPrefix(profile.ProfileName, "profile ")
+}
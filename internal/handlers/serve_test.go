@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"github.com/hpcsc/aws-profile/internal/aws"
+	"github.com/hpcsc/aws-profile/internal/awsconfig"
+	"github.com/hpcsc/aws-profile/internal/config"
+	"github.com/hpcsc/aws-profile/internal/utils"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/alecthomas/kingpin.v2"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func stubGenerateToken() (string, error) {
+	return "token", nil
+}
+
+func setupServeHandler(t *testing.T, isWindows bool, arguments []string, selectProfileFn SelectProfileFn, getAWSCredentialsFn GetAWSCredentialsFn, serveFn ServeFn) (ServeHandler, *bytes.Buffer) {
+	app := kingpin.New("some-app", "some description")
+	output := &bytes.Buffer{}
+	serveHandler := NewServeHandler(app, stubConfig(), stubDetectShell(isWindows), selectProfileFn, stubCredentialsGetter(getAWSCredentialsFn), stubGenerateToken, serveFn, output)
+
+	if _, err := app.Parse(append([]string{"serve"}, arguments...)); err != nil {
+		t.Fatalf("failed to setup test serve handler: %v\n", err)
+	}
+
+	return serveHandler, output
+}
+
+func TestServeHandler(t *testing.T) {
+	noopServe := func(_ net.Listener, _ http.Handler) error {
+		return nil
+	}
+
+	t.Run("return error if config file is not found", func(t *testing.T) {
+		serveHandler, _ := setupServeHandler(t, false, []string{"--profile", "config_profile_1"}, nil, nil, noopServe)
+
+		_, err := serveHandler.Handle(stubGlobalArgumentsForExport("config_not_exists"))
+
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "Fail to read AWS config file")
+	})
+
+	t.Run("serve credentials of given profile to requests with authorization token", func(t *testing.T) {
+		var responseCode int
+		var responseBody string
+		serveMock := func(listener net.Listener, handler http.Handler) error {
+			require.Equal(t, "127.0.0.1", listener.Addr().(*net.TCPAddr).IP.String())
+
+			recorder := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodGet, "/", nil)
+			request.Header.Set("Authorization", "token")
+			handler.ServeHTTP(recorder, request)
+
+			responseCode = recorder.Code
+			responseBody = recorder.Body.String()
+			return nil
+		}
+
+		serveHandler, output := setupServeHandler(t, false, []string{"--profile", "config_profile_2"}, nil, stubGetAWSCredentials, serveMock)
+
+		result, err := serveHandler.Handle(stubGlobalArgumentsForExport("set-config"))
+
+		require.NoError(t, err)
+		require.Empty(t, result.Output)
+		require.Equal(t, http.StatusOK, responseCode)
+		require.Contains(t, responseBody, `"AccessKeyId":"access-key-id"`)
+		require.Contains(t, responseBody, `"Token":"session-token"`)
+		require.Contains(t, output.String(), "=== serving credentials of [profile config_profile_2]")
+		require.Regexp(t, `export AWS_CONTAINER_CREDENTIALS_FULL_URI='http://127\.0\.0\.1:\d+/' AWS_CONTAINER_AUTHORIZATION_TOKEN='token'`, output.String())
+	})
+
+	t.Run("print powershell commands to set environment variables on windows", func(t *testing.T) {
+		serveHandler, output := setupServeHandler(t, true, []string{"--profile", "config_profile_2"}, nil, stubGetAWSCredentials, noopServe)
+
+		_, err := serveHandler.Handle(stubGlobalArgumentsForExport("set-config"))
+
+		require.NoError(t, err)
+		require.Regexp(t, `\$env:AWS_CONTAINER_CREDENTIALS_FULL_URI = 'http://127\.0\.0\.1:\d+/'; \$env:AWS_CONTAINER_AUTHORIZATION_TOKEN = 'token'`, output.String())
+	})
+
+	t.Run("select profile if profile is not given", func(t *testing.T) {
+		selectProfileMock := func(profiles awsconfig.Profiles, pattern string, c *config.Config) ([]byte, error) {
+			require.Empty(t, pattern)
+			return []byte("profile config_profile_1"), nil
+		}
+
+		serveHandler, output := setupServeHandler(t, false, []string{}, selectProfileMock, stubGetAWSCredentials, noopServe)
+
+		_, err := serveHandler.Handle(stubGlobalArgumentsForExport("set-config"))
+
+		require.NoError(t, err)
+		require.Contains(t, output.String(), "[profile config_profile_1]")
+	})
+
+	t.Run("return cancelled error without serving if selecting profile is cancelled", func(t *testing.T) {
+		selectProfileMock := func(profiles awsconfig.Profiles, pattern string, c *config.Config) ([]byte, error) {
+			return nil, utils.NewCancelledError()
+		}
+
+		serveMock := func(_ net.Listener, _ http.Handler) error {
+			require.Fail(t, "unexpected call to Serve")
+			return nil
+		}
+
+		serveHandler, _ := setupServeHandler(t, false, []string{}, selectProfileMock, stubGetAWSCredentials, serveMock)
+
+		_, err := serveHandler.Handle(stubGlobalArgumentsForExport("set-config"))
+
+		require.Equal(t, CategoryCancelled, CategoryOf(err))
+	})
+
+	t.Run("return error without serving if failed to get credentials at startup", func(t *testing.T) {
+		getAWSCredentialsStub := func(_ []awsconfig.Profile, _ time.Duration, _ aws.MFATokenProviderFn) (aws.Credentials, error) {
+			return aws.Credentials{}, errors.New("AccessDenied")
+		}
+
+		serveMock := func(_ net.Listener, _ http.Handler) error {
+			require.Fail(t, "unexpected call to Serve")
+			return nil
+		}
+
+		serveHandler, _ := setupServeHandler(t, false, []string{"--profile", "config_profile_1"}, nil, getAWSCredentialsStub, serveMock)
+
+		_, err := serveHandler.Handle(stubGlobalArgumentsForExport("set-config"))
+
+		require.Error(t, err)
+		require.Equal(t, "AccessDenied", err.Error())
+	})
+
+	t.Run("return error if server stopped unexpectedly", func(t *testing.T) {
+		serveStub := func(_ net.Listener, _ http.Handler) error {
+			return errors.New("credential server stopped")
+		}
+
+		serveHandler, _ := setupServeHandler(t, false, []string{"--profile", "config_profile_1"}, nil, stubGetAWSCredentials, serveStub)
+
+		_, err := serveHandler.Handle(stubGlobalArgumentsForExport("set-config"))
+
+		require.Error(t, err)
+		require.Equal(t, "credential server stopped", err.Error())
+	})
+
+	t.Run("serve credentials in IMDS format with --imds", func(t *testing.T) {
+		var listResponse string
+		serveMock := func(_ net.Listener, handler http.Handler) error {
+			tokenRecorder := httptest.NewRecorder()
+			tokenRequest := httptest.NewRequest(http.MethodPut, "/latest/api/token", nil)
+			tokenRequest.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "60")
+			handler.ServeHTTP(tokenRecorder, tokenRequest)
+
+			listRecorder := httptest.NewRecorder()
+			listRequest := httptest.NewRequest(http.MethodGet, "/latest/meta-data/iam/security-credentials/", nil)
+			listRequest.Header.Set("X-aws-ec2-metadata-token", tokenRecorder.Body.String())
+			handler.ServeHTTP(listRecorder, listRequest)
+
+			listResponse = listRecorder.Body.String()
+			return nil
+		}
+
+		serveHandler, output := setupServeHandler(t, false, []string{"--profile", "config_profile_1", "--imds"}, nil, stubGetAWSCredentials, serveMock)
+
+		_, err := serveHandler.Handle(stubGlobalArgumentsForExport("set-config"))
+
+		require.NoError(t, err)
+		// role name is taken from role_arn of config_profile_1
+		require.Equal(t, "1", listResponse)
+		require.Regexp(t, `export AWS_EC2_METADATA_SERVICE_ENDPOINT='http://127\.0\.0\.1:\d+/'\n`, output.String())
+		require.NotContains(t, output.String(), "AWS_CONTAINER_AUTHORIZATION_TOKEN")
+	})
+
+	t.Run("return error if failed to listen on given address", func(t *testing.T) {
+		serveHandler, _ := setupServeHandler(t, false, []string{"--profile", "config_profile_1", "--address", "not-an-address"}, nil, stubGetAWSCredentials, noopServe)
+
+		_, err := serveHandler.Handle(stubGlobalArgumentsForExport("set-config"))
+
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "Failed to start credential server")
+	})
+}
+
+func TestIMDSRoleName(t *testing.T) {
+	t.Run("return role name from role arn", func(t *testing.T) {
+		require.Equal(t, "my-role", imdsRoleName(&awsconfig.Profile{ProfileName: "profile assume", RoleArn: "arn:aws:iam::123456789012:role/path/my-role"}))
+	})
+
+	t.Run("return profile name without profile prefix if profile has no role arn", func(t *testing.T) {
+		require.Equal(t, "my-profile", imdsRoleName(&awsconfig.Profile{ProfileName: "profile my-profile"}))
+	})
+}
@@ -1,90 +1,190 @@
 package handlers
 
 import (
-	"errors"
 	"fmt"
+	"github.com/hpcsc/aws-profile/internal/aws"
 	"github.com/hpcsc/aws-profile/internal/awsconfig"
 	"github.com/hpcsc/aws-profile/internal/config"
 	"github.com/hpcsc/aws-profile/internal/io"
-	"github.com/hpcsc/aws-profile/internal/utils"
 	"gopkg.in/alecthomas/kingpin.v2"
+	"gopkg.in/ini.v1"
 	"strings"
+	"time"
 )
 
 type SetHandler struct {
-	SubCommand    *kingpin.CmdClause
-	Arguments     SetCommandArguments
-	SelectProfile SelectProfileFn
-	WriteToFile   WriteToFileFn
-	Config        *config.Config
+	SubCommand      *kingpin.CmdClause
+	Arguments       SetCommandArguments
+	SelectProfile   SelectProfileFn
+	IsInteractive   IsInteractiveFn
+	WriteToFile     WriteToFileFn
+	LockFile        LockFileFn
+	RecordHistory   RecordHistoryFn
+	GetSessionToken GetSessionTokenFn
+	Config          *config.Config
 }
 
 type SetCommandArguments struct {
-	Pattern *string
+	Pattern        *string
+	NonInteractive *bool
+	Exact          *bool
+	MFAToken       *string
 }
 
-func NewSetHandler(app *kingpin.Application, config *config.Config, selectProfileFn SelectProfileFn, writeToFileFn WriteToFileFn) SetHandler {
-	subCommand := app.Command("set", "set default profile with credentials of selected profile")
+func NewSetHandler(app *kingpin.Application, config *config.Config, selectProfileFn SelectProfileFn, isInteractiveFn IsInteractiveFn, writeToFileFn WriteToFileFn, lockFileFn LockFileFn, recordHistoryFn RecordHistoryFn, getSessionTokenFn GetSessionTokenFn) SetHandler {
+	subCommand := app.Command("set", `set default profile with credentials of selected profile
+
+Profile is selected without showing the picker with --non-interactive, or when stdin or stdout is not a terminal
+
+Credentials profiles with mfa_serial are set with MFA session credentials, kept in <profile>-mfa section of credentials file and renewed when expired`)
 
 	pattern := subCommand.Arg("pattern", "Filter profiles by given pattern").String()
+	nonInteractive := subCommand.Flag("non-interactive", "Select profile matching pattern exactly, or the only profile containing pattern, instead of showing the picker. Fail if no profile or multiple profiles match").Bool()
+	exact := subCommand.Flag("exact", "Same as --non-interactive").Bool()
+	mfaToken := mfaTokenFlag(subCommand)
 
 	return SetHandler{
 		SubCommand: subCommand,
 		Arguments: SetCommandArguments{
-			Pattern: pattern,
+			Pattern:        pattern,
+			NonInteractive: nonInteractive,
+			Exact:          exact,
+			MFAToken:       mfaToken,
 		},
-		SelectProfile: selectProfileFn,
-		WriteToFile:   writeToFileFn,
-		Config:        config,
+		SelectProfile:   selectProfileFn,
+		IsInteractive:   isInteractiveFn,
+		WriteToFile:     writeToFileFn,
+		LockFile:        lockFileFn,
+		RecordHistory:   recordHistoryFn,
+		GetSessionToken: getSessionTokenFn,
+		Config:          config,
 	}
 }
 
-func (handler SetHandler) Handle(globalArguments GlobalArguments) (bool, string) {
-	credentialsFile, err := io.ReadFile(globalArguments.CredentialsFilePath)
+func (handler SetHandler) Handle(globalArguments GlobalArguments) (Result, error) {
+	credentialsFile, _, profiles, err := readAWSFiles(globalArguments)
 	if err != nil {
-		return false, fmt.Sprintf("Fail to read AWS credentials file: %v", err)
+		return Result{}, err
 	}
 
-	configFile, err := io.ReadFile(globalArguments.ConfigFilePath)
+	selectProfile := handler.SelectProfile
+	if *handler.Arguments.NonInteractive || *handler.Arguments.Exact || !handler.IsInteractive() {
+		selectProfile = selectProfileNonInteractively
+	}
+
+	selectProfileResult, err := selectProfile(profiles, *handler.Arguments.Pattern, handler.Config)
 	if err != nil {
-		return false, fmt.Sprintf("Fail to read AWS config file: %v", err)
+		return Result{}, fmt.Errorf("Failed to select profile: %w", err)
 	}
 
-	profiles := awsconfig.LoadProfilesFromConfigAndCredentials(credentialsFile, configFile)
+	trimmedSelectedProfileResult := strings.TrimSuffix(string(selectProfileResult), "\n")
 
-	selectProfileResult, err := handler.SelectProfile(profiles, *handler.Arguments.Pattern, handler.Config)
-	var cancelled *utils.CancelledError
-	if errors.As(err, &cancelled) {
-		return true, ""
+	mfaSession, err := handler.newMFASession(trimmedSelectedProfileResult, profiles, credentialsFile)
+	if err != nil {
+		return Result{}, err
 	}
 
+	unlock, err := lockFiles(handler.LockFile, globalArguments.CredentialsFilePath, globalArguments.ConfigFilePath)
 	if err != nil {
-		return false, fmt.Sprintf("Failed to select profile: %v", err)
+		return Result{}, err
 	}
+	defer unlock()
 
-	trimmedSelectedProfileResult := strings.TrimSuffix(string(selectProfileResult), "\n")
+	// files are read again while locked so that changes made by other processes during selection are not overwritten
+	credentialsFile, configFile, profiles, err := readAWSFiles(globalArguments)
+	if err != nil {
+		return Result{}, err
+	}
 
-	if profiles.FindProfileInCredentialsFile(trimmedSelectedProfileResult) != nil {
-		awsconfig.SetSelectedProfileAsDefault(trimmedSelectedProfileResult, credentialsFile, configFile)
+	message, err := handler.setAsDefault(globalArguments, trimmedSelectedProfileResult, profiles, credentialsFile, configFile, mfaSession)
+	if err != nil {
+		return Result{}, err
+	}
+
+	recordHistory(handler.RecordHistory, trimmedSelectedProfileResult)
+
+	return Result{Output: message}, nil
+}
+
+// MFA token may be prompted, so MFA session credentials are received before AWS files are locked
+func (handler SetHandler) newMFASession(profileName string, profiles awsconfig.Profiles, credentialsFile *ini.File) (*aws.Credentials, error) {
+	credentialsProfile := profiles.FindProfileInCredentialsFile(profileName)
+	if credentialsProfile == nil || !credentialsProfile.UsesMFASession() {
+		return nil, nil
+	}
+
+	return newMFASession(
+		credentialsFile,
+		*credentialsProfile,
+		handler.GetSessionToken,
+		mfaTokenProvider(handler.Config, *handler.Arguments.MFAToken),
+		handler.Config.CacheRefreshWindowDuration(),
+		time.Now(),
+	)
+}
+
+func readAWSFiles(globalArguments GlobalArguments) (*ini.File, *ini.File, awsconfig.Profiles, error) {
+	credentialsFile, err := io.ReadFile(globalArguments.CredentialsFilePath)
+	if err != nil {
+		return nil, nil, awsconfig.Profiles{}, fileReadError("AWS credentials file", err)
+	}
+
+	configFile, err := io.ReadFile(globalArguments.ConfigFilePath)
+	if err != nil {
+		return nil, nil, awsconfig.Profiles{}, fileReadError("AWS config file", err)
+	}
+
+	profiles, err := awsconfig.LoadProfilesFromConfigAndCredentials(credentialsFile, configFile)
+	if err != nil {
+		return nil, nil, awsconfig.Profiles{}, withCategory(CategoryConfigInvalid, err)
+	}
+
+	return credentialsFile, configFile, profiles.WithOriginFiles(globalArguments.CredentialsFilePath, globalArguments.ConfigFilePath), nil
+}
+
+func (handler SetHandler) setAsDefault(globalArguments GlobalArguments, trimmedSelectedProfileResult string, profiles awsconfig.Profiles, credentialsFile *ini.File, configFile *ini.File, mfaSession *aws.Credentials) (string, error) {
+	if credentialsProfile := profiles.FindProfileInCredentialsFile(trimmedSelectedProfileResult); credentialsProfile != nil {
+		if credentialsProfile.UsesMFASession() {
+			if mfaSession != nil {
+				setMFASession(credentialsFile, trimmedSelectedProfileResult, *mfaSession)
+			}
+
+			if err := awsconfig.SetSelectedMFASessionAsDefault(trimmedSelectedProfileResult, credentialsFile, configFile); err != nil {
+				return "", err
+			}
+		} else {
+			awsconfig.SetSelectedProfileAsDefault(trimmedSelectedProfileResult, credentialsFile, configFile)
+		}
 
 		if err := handler.WriteToFile(credentialsFile, globalArguments.CredentialsFilePath); err != nil {
-			return false, err.Error()
+			return "", err
 		}
 
 		if err := handler.WriteToFile(configFile, globalArguments.ConfigFilePath); err != nil {
-			return false, err.Error()
+			return "", err
 		}
 
-		return true, fmt.Sprintf("=== [%s] -> [default] (%s)", trimmedSelectedProfileResult, globalArguments.CredentialsFilePath)
-	} else if assumedProfile := profiles.FindProfileInConfigFile(trimmedSelectedProfileResult); assumedProfile != nil {
-		awsconfig.SetSelectedAssumedProfileAsDefault(assumedProfile.ProfileName, configFile)
+		return fmt.Sprintf("=== [%s] -> [default] (%s)", trimmedSelectedProfileResult, globalArguments.CredentialsFilePath), nil
+	}
 
-		if err := handler.WriteToFile(configFile, globalArguments.ConfigFilePath); err != nil {
-			return false, err.Error()
-		}
+	configProfile := profiles.FindConfigFileProfile(trimmedSelectedProfileResult)
+	if configProfile == nil {
+		return "", newError(CategoryNotFound, "=== profile [%s] not found in either credentials or config file", trimmedSelectedProfileResult)
+	}
+
+	if configProfile.IsCredentialProcess() {
+		awsconfig.SetSelectedProcessProfileAsDefault(configProfile.ProfileName, credentialsFile, configFile)
 
-		return true, fmt.Sprintf("=== [%s] -> [default] (%s)", assumedProfile.ProfileName, globalArguments.ConfigFilePath)
+		if err := handler.WriteToFile(credentialsFile, globalArguments.CredentialsFilePath); err != nil {
+			return "", err
+		}
 	} else {
-		return false, fmt.Sprintf("=== profile [%s] not found in either credentials or config file", trimmedSelectedProfileResult)
+		awsconfig.SetSelectedAssumedProfileAsDefault(configProfile.ProfileName, configFile)
 	}
+
+	if err := handler.WriteToFile(configFile, globalArguments.ConfigFilePath); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("=== [%s] -> [default] (%s)", configProfile.ProfileName, globalArguments.ConfigFilePath), nil
 }
@@ -1,10 +1,8 @@
 package handlers
 
 import (
-	"errors"
 	"fmt"
 	"github.com/hpcsc/aws-profile/internal/config"
-	"github.com/hpcsc/aws-profile/internal/utils"
 	"strings"
 
 	"github.com/hpcsc/aws-profile/internal/awsconfig"
@@ -18,42 +16,50 @@ type SetRegionHandler struct {
 	SubCommand   *kingpin.CmdClause
 	SelectRegion SelectRegionFn
 	WriteToFile  WriteToFileFn
+	LockFile     LockFileFn
 	Config       *config.Config
 }
 
-func NewSetRegionHandler(app *kingpin.Application, config *config.Config, selectRegionFn SelectRegionFn, writeToFileFn WriteToFileFn) SetRegionHandler {
+func NewSetRegionHandler(app *kingpin.Application, config *config.Config, selectRegionFn SelectRegionFn, writeToFileFn WriteToFileFn, lockFileFn LockFileFn) SetRegionHandler {
 	subCommand := app.Command("set-region", "set the region of the default profile")
 
 	return SetRegionHandler{
 		SubCommand:   subCommand,
 		SelectRegion: selectRegionFn,
 		WriteToFile:  writeToFileFn,
+		LockFile:     lockFileFn,
 		Config:       config,
 	}
 }
 
-func (handler SetRegionHandler) Handle(globalArguments GlobalArguments) (bool, string) {
-	configFile, err := io.ReadFile(globalArguments.ConfigFilePath)
-	if err != nil {
-		return false, fmt.Sprintf("Fail to read AWS config file: %v", err)
+func (handler SetRegionHandler) Handle(globalArguments GlobalArguments) (Result, error) {
+	if _, err := io.ReadFile(globalArguments.ConfigFilePath); err != nil {
+		return Result{}, fileReadError("AWS config file", err)
 	}
 
 	selectRegionResult, err := handler.SelectRegion(handler.Config.Regions, "Select an AWS region", handler.Config)
-	var cancelled *utils.CancelledError
-	if errors.As(err, &cancelled) {
-		return true, ""
+	if err != nil {
+		return Result{}, fmt.Errorf("Failed to select region: %w", err)
 	}
 
+	trimmedSelectedRegionResult := strings.TrimSuffix(string(selectRegionResult), "\n")
+
+	unlock, err := lockFiles(handler.LockFile, globalArguments.ConfigFilePath)
 	if err != nil {
-		return false, fmt.Sprintf("Failed to select region: %v", err)
+		return Result{}, err
 	}
+	defer unlock()
 
-	trimmedSelectedRegionResult := strings.TrimSuffix(string(selectRegionResult), "\n")
+	// config file is read again while locked so that changes made by other processes during selection are not overwritten
+	configFile, err := io.ReadFile(globalArguments.ConfigFilePath)
+	if err != nil {
+		return Result{}, fileReadError("AWS config file", err)
+	}
 
 	awsconfig.SetSelectedRegionAsDefault(trimmedSelectedRegionResult, configFile)
 	if err := handler.WriteToFile(configFile, globalArguments.ConfigFilePath); err != nil {
-		return false, err.Error()
+		return Result{}, err
 	}
 
-	return true, fmt.Sprintf("=== [region %s] -> [default.region] (%s)", trimmedSelectedRegionResult, globalArguments.ConfigFilePath)
+	return Result{Output: fmt.Sprintf("=== [region %s] -> [default.region] (%s)", trimmedSelectedRegionResult, globalArguments.ConfigFilePath)}, nil
 }
@@ -31,7 +31,7 @@ func setupSetRegionHandler(selectRegionFn SelectRegionFn, writeToFileFn WriteToF
 		HighlightColor: config.DefaultHighlightColor(),
 		Regions:        config.DefaultRegions(),
 	}
-	setRegionHandler := NewSetRegionHandler(app, config, selectRegionFn, writeToFileFn)
+	setRegionHandler := NewSetRegionHandler(app, config, selectRegionFn, writeToFileFn, noopLockFile)
 
 	if _, err := app.Parse([]string{"set-region"}); err != nil {
 		fmt.Printf("failed to setup test set region handler: %v\n", err)
@@ -46,10 +46,10 @@ func TestSetRegionHandler(t *testing.T) {
 		setRegionHandler := setupSetRegionHandler(nil, nil)
 		globalArguments := stubGlobalArgumentsForSetRegion("config_not_exists")
 
-		success, output := setRegionHandler.Handle(globalArguments)
+		_, err := setRegionHandler.Handle(globalArguments)
 
-		require.False(t, success)
-		require.Contains(t, output, "Fail to read AWS config file")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "Fail to read AWS config file")
 
 	})
 
@@ -67,9 +67,9 @@ func TestSetRegionHandler(t *testing.T) {
 		setRegionHandler := setupSetRegionHandler(selectRegionMock, noopWriteToFileMock)
 		globalArguments := stubGlobalArgumentsForSetRegion("set-config")
 
-		success, _ := setRegionHandler.Handle(globalArguments)
+		_, err := setRegionHandler.Handle(globalArguments)
 
-		require.True(t, success)
+		require.NoError(t, err)
 		if !called {
 			t.Errorf("selectRegionFn is not invoked")
 		}
@@ -102,15 +102,15 @@ func TestSetRegionHandler(t *testing.T) {
 		setRegionHandler := setupSetRegionHandler(selectRegionMock, writeToFileMock)
 		globalArguments := stubGlobalArgumentsForSetRegion("set-config")
 
-		success, message := setRegionHandler.Handle(globalArguments)
+		result, err := setRegionHandler.Handle(globalArguments)
 
-		assert.True(t, success)
-		assert.Contains(t, message, "[region ap-southeast-2] -> [default.region]")
-		assert.Contains(t, message, globalArguments.ConfigFilePath)
+		assert.NoError(t, err)
+		assert.Contains(t, result.Output, "[region ap-southeast-2] -> [default.region]")
+		assert.Contains(t, result.Output, globalArguments.ConfigFilePath)
 		assert.True(t, calledWriteToFile)
 	})
 
-	t.Run("return success when user cancels in the middle of selection", func(t *testing.T) {
+	t.Run("return cancelled error when user cancels in the middle of selection", func(t *testing.T) {
 		calledWriteToFile := false
 		selectRegionMock := func(regions []string, title string, c *config.Config) ([]byte, error) {
 			return nil, utils.NewCancelledError()
@@ -123,10 +123,9 @@ func TestSetRegionHandler(t *testing.T) {
 		setRegionHandler := setupSetRegionHandler(selectRegionMock, writeToFileMock)
 		globalArguments := stubGlobalArgumentsForSetRegion("set-config")
 
-		success, message := setRegionHandler.Handle(globalArguments)
+		_, err := setRegionHandler.Handle(globalArguments)
 
-		require.True(t, success)
-		require.Empty(t, message)
+		require.Equal(t, CategoryCancelled, CategoryOf(err))
 		require.False(t, calledWriteToFile)
 	})
 
@@ -143,10 +142,10 @@ func TestSetRegionHandler(t *testing.T) {
 		setRegionHandler := setupSetRegionHandler(selectRegionMock, writeToFileMock)
 		globalArguments := stubGlobalArgumentsForSetRegion("set-config")
 
-		success, message := setRegionHandler.Handle(globalArguments)
+		_, err := setRegionHandler.Handle(globalArguments)
 
-		require.False(t, success)
-		require.Contains(t, message, "some error")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "some error")
 		require.False(t, calledWriteToFile)
 	})
 
@@ -166,9 +165,31 @@ func TestSetRegionHandler(t *testing.T) {
 		setRegionHandler := setupSetRegionHandler(selectRegionMock, writeToFileMock)
 		globalArguments := stubGlobalArgumentsForSetRegion("set-config")
 
-		success, message := setRegionHandler.Handle(globalArguments)
+		_, err := setRegionHandler.Handle(globalArguments)
 
-		require.False(t, success)
-		require.Contains(t, message, "some error")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "some error")
+	})
+}
+
+func TestSetRegionHandler_LockFile(t *testing.T) {
+	t.Run("lock config file after selection until it is written", func(t *testing.T) {
+		var events []string
+		selectRegionMock := func(_ []string, _ string, _ *config.Config) ([]byte, error) {
+			events = append(events, "select")
+			return []byte("ap-southeast-2"), nil
+		}
+		writeToFileMock := func(_ *ini.File, filePath string) error {
+			events = append(events, "write "+filepath.Base(filePath))
+			return nil
+		}
+		app := kingpin.New("some-app", "some description")
+		setRegionHandler := NewSetRegionHandler(app, &config.Config{Regions: config.DefaultRegions()}, selectRegionMock, writeToFileMock, recordingLockFile(&events))
+		_, _ = app.Parse([]string{"set-region"})
+
+		_, err := setRegionHandler.Handle(stubGlobalArgumentsForSetRegion("set-config"))
+
+		require.NoError(t, err)
+		require.Equal(t, []string{"select", "lock set-config", "write set-config", "unlock set-config"}, events)
 	})
 }
@@ -30,13 +30,38 @@ func stubGlobalArgumentsForSet(credentialsName string, configName string) Global
 	}
 }
 
+func noopLockFile(_ string) (func() error, error) {
+	return func() error { return nil }, nil
+}
+
+// recordingLockFile appends locking and unlocking of files to events
+func recordingLockFile(events *[]string) LockFileFn {
+	return func(filePath string) (func() error, error) {
+		*events = append(*events, "lock "+filepath.Base(filePath))
+		return func() error {
+			*events = append(*events, "unlock "+filepath.Base(filePath))
+			return nil
+		}, nil
+	}
+}
+
+func noopRecordHistory(_ string) error {
+	return nil
+}
+
+func stubIsInteractive(isInteractive bool) IsInteractiveFn {
+	return func() bool {
+		return isInteractive
+	}
+}
+
 func setupSetHandler(selectProfileFn SelectProfileFn, writeToFileFn WriteToFileFn) SetHandler {
 	app := kingpin.New("some-app", "some description")
 	config := &config.Config{
 		HighlightColor: config.DefaultHighlightColor(),
 		Regions:        config.DefaultRegions(),
 	}
-	setHandler := NewSetHandler(app, config, selectProfileFn, writeToFileFn)
+	setHandler := NewSetHandler(app, config, selectProfileFn, stubIsInteractive(true), writeToFileFn, noopLockFile, noopRecordHistory, nil)
 
 	if _, err := app.Parse([]string{"set"}); err != nil {
 		fmt.Printf("failed to setup test set handler: %v\n", err)
@@ -51,10 +76,10 @@ func TestSetHandler(t *testing.T) {
 		setHandler := setupSetHandler(nil, nil)
 		globalArguments := stubGlobalArgumentsForSet("credentials_not_exists", "get_profile_in_neither_file-config")
 
-		success, output := setHandler.Handle(globalArguments)
+		_, err := setHandler.Handle(globalArguments)
 
-		require.False(t, success)
-		require.Contains(t, output, "Fail to read AWS credentials file")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "Fail to read AWS credentials file")
 
 	})
 
@@ -62,10 +87,10 @@ func TestSetHandler(t *testing.T) {
 		setHandler := setupSetHandler(nil, nil)
 		globalArguments := stubGlobalArgumentsForSet("get_profile_in_neither_file-credentials", "config_not_exists")
 
-		success, output := setHandler.Handle(globalArguments)
+		_, err := setHandler.Handle(globalArguments)
 
-		require.False(t, success)
-		require.Contains(t, output, "Fail to read AWS config file")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "Fail to read AWS config file")
 
 	})
 
@@ -81,6 +106,9 @@ func TestSetHandler(t *testing.T) {
 					"credentials_profile_2",
 					"assume profile config_profile_1",
 					"assume profile config_profile_2",
+					"sso profile sso_profile_1",
+					"process profile process_profile_1",
+					"web-identity profile web_identity_profile_1",
 				},
 			)
 
@@ -91,9 +119,9 @@ func TestSetHandler(t *testing.T) {
 		setHandler := setupSetHandler(selectProfileMock, noopWriteToFileMock)
 		globalArguments := stubGlobalArgumentsForSet("set-credentials", "set-config")
 
-		success, _ := setHandler.Handle(globalArguments)
+		_, err := setHandler.Handle(globalArguments)
 
-		require.True(t, success)
+		require.NoError(t, err)
 		if !called {
 			t.Errorf("selectProfileFn is not invoked")
 		}
@@ -108,13 +136,14 @@ func TestSetHandler(t *testing.T) {
 		setHandler := setupSetHandler(selectProfileMock, noopWriteToFileMock)
 		globalArguments := stubGlobalArgumentsForSet("set-credentials", "set-config")
 
-		success, message := setHandler.Handle(globalArguments)
+		_, err := setHandler.Handle(globalArguments)
 
-		require.False(t, success)
-		require.Contains(t, message, "not found in either credentials or config file")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "not found in either credentials or config file")
+		require.Equal(t, CategoryNotFound, CategoryOf(err))
 	})
 
-	t.Run("return success when user cancels in the middle of selection", func(t *testing.T) {
+	t.Run("return cancelled error when user cancels in the middle of selection", func(t *testing.T) {
 		selectProfileMock := func(profiles awsconfig.Profiles, pattern string, c *config.Config) ([]byte, error) {
 			return nil, utils.NewCancelledError()
 		}
@@ -122,10 +151,9 @@ func TestSetHandler(t *testing.T) {
 		setHandler := setupSetHandler(selectProfileMock, noopWriteToFileMock)
 		globalArguments := stubGlobalArgumentsForSet("set-credentials", "set-config")
 
-		success, message := setHandler.Handle(globalArguments)
+		_, err := setHandler.Handle(globalArguments)
 
-		require.True(t, success)
-		require.Empty(t, message)
+		require.Equal(t, CategoryCancelled, CategoryOf(err))
 	})
 
 	t.Run("return error when failed to do selection", func(t *testing.T) {
@@ -136,10 +164,10 @@ func TestSetHandler(t *testing.T) {
 		setHandler := setupSetHandler(selectProfileMock, noopWriteToFileMock)
 		globalArguments := stubGlobalArgumentsForSet("set-credentials", "set-config")
 
-		success, message := setHandler.Handle(globalArguments)
+		_, err := setHandler.Handle(globalArguments)
 
-		require.False(t, success)
-		require.Contains(t, message, "some error")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "some error")
 	})
 
 	t.Run("set default profile in credentials file when profile is in credentials file", func(t *testing.T) {
@@ -165,10 +193,10 @@ func TestSetHandler(t *testing.T) {
 		setHandler := setupSetHandler(selectProfileMock, writeToFileMock)
 		globalArguments := stubGlobalArgumentsForSet("set-credentials", "set-config")
 
-		success, message := setHandler.Handle(globalArguments)
+		result, err := setHandler.Handle(globalArguments)
 
-		require.True(t, success)
-		require.Contains(t, message, "[credentials_profile_2] -> [default]")
+		require.NoError(t, err)
+		require.Contains(t, result.Output, "[credentials_profile_2] -> [default]")
 	})
 
 	t.Run("return error when profile is in credentials file and failed to write updated credentials file", func(t *testing.T) {
@@ -188,10 +216,10 @@ func TestSetHandler(t *testing.T) {
 		setHandler := setupSetHandler(selectProfileStub, writeToFileStub)
 		globalArguments := stubGlobalArgumentsForSet("set-credentials", "set-config")
 
-		success, message := setHandler.Handle(globalArguments)
+		_, err := setHandler.Handle(globalArguments)
 
-		require.False(t, success)
-		require.Contains(t, message, "some error")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "some error")
 	})
 
 	t.Run("return error when profile is in credentials file and failed to write updated config file", func(t *testing.T) {
@@ -211,10 +239,10 @@ func TestSetHandler(t *testing.T) {
 		setHandler := setupSetHandler(selectProfileStub, writeToFileStub)
 		globalArguments := stubGlobalArgumentsForSet("set-credentials", "set-config")
 
-		success, message := setHandler.Handle(globalArguments)
+		_, err := setHandler.Handle(globalArguments)
 
-		require.False(t, success)
-		require.Contains(t, message, "some error")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "some error")
 	})
 
 	t.Run("set default profile in config file when profile is in config file", func(t *testing.T) {
@@ -237,10 +265,99 @@ func TestSetHandler(t *testing.T) {
 		setHandler := setupSetHandler(selectProfileMock, writeToFileMock)
 		globalArguments := stubGlobalArgumentsForSet("set-credentials", "set-config")
 
-		success, message := setHandler.Handle(globalArguments)
+		result, err := setHandler.Handle(globalArguments)
+
+		require.NoError(t, err)
+		require.Contains(t, result.Output, "[profile config_profile_2] -> [default]")
+	})
+
+	t.Run("set default profile in config file when profile is an sso profile", func(t *testing.T) {
+		selectProfileMock := func(profiles awsconfig.Profiles, pattern string, c *config.Config) ([]byte, error) {
+			return []byte("profile sso_profile_1"), nil
+		}
+
+		writeToFileMock := func(file *ini.File, unexpandedFilePath string) error {
+			if strings.Contains(unexpandedFilePath, "-config") {
+				defaultSection := file.Section("default")
+				require.Equal(t, "https://my-sso-portal.awsapps.com/start", defaultSection.Key("sso_start_url").Value())
+				require.Equal(t, "123456789012", defaultSection.Key("sso_account_id").Value())
+				require.Equal(t, "ReadOnly", defaultSection.Key("sso_role_name").Value())
+				require.False(t, defaultSection.HasKey("role_arn"))
+				require.False(t, defaultSection.HasKey("source_profile"))
+			} else {
+				require.Fail(t, "unexpected call to writeToFile")
+			}
+
+			return nil
+		}
+
+		setHandler := setupSetHandler(selectProfileMock, writeToFileMock)
+		globalArguments := stubGlobalArgumentsForSet("set-credentials", "set-config")
+
+		result, err := setHandler.Handle(globalArguments)
+
+		require.NoError(t, err)
+		require.Contains(t, result.Output, "[profile sso_profile_1] -> [default]")
+	})
+
+	t.Run("set default profile in config file and remove static credentials of default profile in credentials file when profile is a credential process profile", func(t *testing.T) {
+		selectProfileMock := func(profiles awsconfig.Profiles, pattern string, c *config.Config) ([]byte, error) {
+			return []byte("profile process_profile_1"), nil
+		}
+
+		var writtenFiles []string
+		writeToFileMock := func(file *ini.File, unexpandedFilePath string) error {
+			writtenFiles = append(writtenFiles, filepath.Base(unexpandedFilePath))
+			defaultSection := file.Section("default")
+
+			if strings.Contains(unexpandedFilePath, "-config") {
+				require.Equal(t, "/usr/local/bin/vault-creds --role admin", defaultSection.Key("credential_process").Value())
+				require.False(t, defaultSection.HasKey("role_arn"))
+				require.False(t, defaultSection.HasKey("source_profile"))
+			} else {
+				require.False(t, defaultSection.HasKey("aws_access_key_id"))
+				require.False(t, defaultSection.HasKey("aws_secret_access_key"))
+				require.Equal(t, "4", file.Section("credentials_profile_2").Key("aws_access_key_id").Value())
+			}
+
+			return nil
+		}
+
+		setHandler := setupSetHandler(selectProfileMock, writeToFileMock)
+		globalArguments := stubGlobalArgumentsForSet("set-credentials", "set-config")
+
+		result, err := setHandler.Handle(globalArguments)
+
+		require.NoError(t, err)
+		require.Contains(t, result.Output, "[profile process_profile_1] -> [default]")
+		require.Equal(t, []string{"set-credentials", "set-config"}, writtenFiles)
+	})
+
+	t.Run("set default profile in config file when profile is a web identity profile", func(t *testing.T) {
+		selectProfileMock := func(profiles awsconfig.Profiles, pattern string, c *config.Config) ([]byte, error) {
+			return []byte("profile web_identity_profile_1"), nil
+		}
+
+		writeToFileMock := func(file *ini.File, unexpandedFilePath string) error {
+			if strings.Contains(unexpandedFilePath, "-config") {
+				defaultSection := file.Section("default")
+				require.Equal(t, "arn:aws:iam::123456789012:role/eks", defaultSection.Key("role_arn").Value())
+				require.Equal(t, "/var/run/secrets/eks.amazonaws.com/serviceaccount/token", defaultSection.Key("web_identity_token_file").Value())
+				require.False(t, defaultSection.HasKey("source_profile"))
+			} else {
+				require.Fail(t, "unexpected call to writeToFile")
+			}
+
+			return nil
+		}
+
+		setHandler := setupSetHandler(selectProfileMock, writeToFileMock)
+		globalArguments := stubGlobalArgumentsForSet("set-credentials", "set-config")
+
+		result, err := setHandler.Handle(globalArguments)
 
-		require.True(t, success)
-		require.Contains(t, message, "[profile config_profile_2] -> [default]")
+		require.NoError(t, err)
+		require.Contains(t, result.Output, "[profile web_identity_profile_1] -> [default]")
 	})
 
 	t.Run("return error when profile is in config file and failed to write updated config file", func(t *testing.T) {
@@ -259,9 +376,185 @@ func TestSetHandler(t *testing.T) {
 		setHandler := setupSetHandler(selectProfileMock, writeToFileMock)
 		globalArguments := stubGlobalArgumentsForSet("set-credentials", "set-config")
 
-		success, message := setHandler.Handle(globalArguments)
+		_, err := setHandler.Handle(globalArguments)
+
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "some error")
+	})
+}
+
+func TestSetHandler_NonInteractive(t *testing.T) {
+	selectProfileNotExpected := func(profiles awsconfig.Profiles, pattern string, c *config.Config) ([]byte, error) {
+		require.Fail(t, "unexpected call to SelectProfile")
+		return nil, nil
+	}
+
+	setupNonInteractiveSetHandler := func(t *testing.T, isInteractive bool, arguments ...string) SetHandler {
+		app := kingpin.New("some-app", "some description")
+		setHandler := NewSetHandler(app, &config.Config{}, selectProfileNotExpected, stubIsInteractive(isInteractive), noopWriteToFileMock, noopLockFile, noopRecordHistory, nil)
+		_, err := app.Parse(append([]string{"set"}, arguments...))
+		require.NoError(t, err)
+
+		return setHandler
+	}
+
+	t.Run("set profile matching pattern exactly without showing picker with --non-interactive", func(t *testing.T) {
+		setHandler := setupNonInteractiveSetHandler(t, true, "--non-interactive", "config_profile_1")
+
+		result, err := setHandler.Handle(stubGlobalArgumentsForSet("set-credentials", "set-config"))
+
+		require.NoError(t, err)
+		require.Contains(t, result.Output, "[profile config_profile_1] -> [default]")
+	})
+
+	t.Run("set the only profile containing pattern with --exact", func(t *testing.T) {
+		setHandler := setupNonInteractiveSetHandler(t, true, "--exact", "sso_profile")
+
+		result, err := setHandler.Handle(stubGlobalArgumentsForSet("set-credentials", "set-config"))
+
+		require.NoError(t, err)
+		require.Contains(t, result.Output, "[profile sso_profile_1] -> [default]")
+	})
+
+	t.Run("set profile without showing picker when not running in a terminal", func(t *testing.T) {
+		setHandler := setupNonInteractiveSetHandler(t, false, "credentials_profile_2")
+
+		result, err := setHandler.Handle(stubGlobalArgumentsForSet("set-credentials", "set-config"))
+
+		require.NoError(t, err)
+		require.Contains(t, result.Output, "[credentials_profile_2] -> [default]")
+	})
+
+	t.Run("return error listing matching profiles if multiple profiles match pattern", func(t *testing.T) {
+		setHandler := setupNonInteractiveSetHandler(t, true, "--non-interactive", "config_profile")
+
+		_, err := setHandler.Handle(stubGlobalArgumentsForSet("set-credentials", "set-config"))
+
+		require.Error(t, err)
+		require.Equal(t, "Failed to select profile: multiple profiles match [config_profile]:\n  profile config_profile_1\n  profile config_profile_2", err.Error())
+		require.Equal(t, CategoryUsage, CategoryOf(err))
+	})
+
+	t.Run("return error if no profile matches pattern", func(t *testing.T) {
+		setHandler := setupNonInteractiveSetHandler(t, true, "--non-interactive", "not_exists")
+
+		_, err := setHandler.Handle(stubGlobalArgumentsForSet("set-credentials", "set-config"))
+
+		require.Error(t, err)
+		require.Equal(t, "Failed to select profile: no profile matches [not_exists]", err.Error())
+		require.Equal(t, CategoryNotFound, CategoryOf(err))
+	})
+
+	t.Run("return error if pattern is not given", func(t *testing.T) {
+		setHandler := setupNonInteractiveSetHandler(t, false)
+
+		_, err := setHandler.Handle(stubGlobalArgumentsForSet("set-credentials", "set-config"))
+
+		require.Error(t, err)
+		require.Equal(t, "Failed to select profile: profile name is required when not running interactively", err.Error())
+	})
+}
+
+func TestSetHandler_RecordHistory(t *testing.T) {
+	setupSetHandlerRecordingHistory := func(selectedProfile string, recorded *[]string) SetHandler {
+		app := kingpin.New("some-app", "some description")
+		selectProfileMock := func(_ awsconfig.Profiles, _ string, _ *config.Config) ([]byte, error) {
+			return []byte(selectedProfile), nil
+		}
+		recordHistoryMock := func(profileName string) error {
+			*recorded = append(*recorded, profileName)
+			return nil
+		}
+
+		setHandler := NewSetHandler(app, &config.Config{}, selectProfileMock, stubIsInteractive(true), noopWriteToFileMock, noopLockFile, recordHistoryMock, nil)
+		_, _ = app.Parse([]string{"set"})
+		return setHandler
+	}
+
+	t.Run("record selected profile when it is set as default", func(t *testing.T) {
+		var recorded []string
+
+		_, err := setupSetHandlerRecordingHistory("profile config_profile_1", &recorded).Handle(stubGlobalArgumentsForSet("set-credentials", "set-config"))
+
+		require.NoError(t, err)
+		require.Equal(t, []string{"profile config_profile_1"}, recorded)
+	})
+
+	t.Run("not record selected profile when it is not found", func(t *testing.T) {
+		var recorded []string
+
+		_, err := setupSetHandlerRecordingHistory("a_random_profile", &recorded).Handle(stubGlobalArgumentsForSet("set-credentials", "set-config"))
+
+		require.Error(t, err)
+		require.Empty(t, recorded)
+	})
+
+	t.Run("not fail when history can't be recorded", func(t *testing.T) {
+		app := kingpin.New("some-app", "some description")
+		selectProfileMock := func(_ awsconfig.Profiles, _ string, _ *config.Config) ([]byte, error) {
+			return []byte("credentials_profile_2"), nil
+		}
+		recordHistoryFailure := func(_ string) error {
+			return errors.New("some error")
+		}
+
+		setHandler := NewSetHandler(app, &config.Config{}, selectProfileMock, stubIsInteractive(true), noopWriteToFileMock, noopLockFile, recordHistoryFailure, nil)
+		_, _ = app.Parse([]string{"set"})
+
+		_, err := setHandler.Handle(stubGlobalArgumentsForSet("set-credentials", "set-config"))
+
+		require.NoError(t, err)
+	})
+}
+
+func TestSetHandler_LockFile(t *testing.T) {
+	setupSetHandlerLockingFiles := func(events *[]string, lockFileFn LockFileFn) SetHandler {
+		app := kingpin.New("some-app", "some description")
+		selectProfileMock := func(_ awsconfig.Profiles, _ string, _ *config.Config) ([]byte, error) {
+			*events = append(*events, "select")
+			return []byte("credentials_profile_2"), nil
+		}
+		writeToFileMock := func(_ *ini.File, filePath string) error {
+			*events = append(*events, "write "+filepath.Base(filePath))
+			return nil
+		}
+
+		setHandler := NewSetHandler(app, &config.Config{}, selectProfileMock, stubIsInteractive(true), writeToFileMock, lockFileFn, noopRecordHistory, nil)
+		_, _ = app.Parse([]string{"set"})
+		return setHandler
+	}
+
+	t.Run("lock credentials and config files after selection until files are written", func(t *testing.T) {
+		var events []string
+
+		_, err := setupSetHandlerLockingFiles(&events, recordingLockFile(&events)).Handle(stubGlobalArgumentsForSet("set-credentials", "set-config"))
+
+		require.NoError(t, err)
+		require.Equal(t, []string{
+			"select",
+			"lock set-credentials",
+			"lock set-config",
+			"write set-credentials",
+			"write set-config",
+			"unlock set-config",
+			"unlock set-credentials",
+		}, events)
+	})
+
+	t.Run("return error without writing files when failed to lock", func(t *testing.T) {
+		var events []string
+		lockFileMock := func(filePath string) (func() error, error) {
+			if strings.HasSuffix(filePath, "set-config") {
+				return nil, errors.New("timed out waiting for lock")
+			}
+
+			return recordingLockFile(&events)(filePath)
+		}
+
+		_, err := setupSetHandlerLockingFiles(&events, lockFileMock).Handle(stubGlobalArgumentsForSet("set-credentials", "set-config"))
 
-		require.False(t, success)
-		require.Contains(t, message, "some error")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "timed out waiting for lock")
+		require.Equal(t, []string{"select", "lock set-credentials", "unlock set-credentials"}, events)
 	})
 }
@@ -0,0 +1,22 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/hpcsc/aws-profile/internal/shell"
+	"gopkg.in/alecthomas/kingpin.v2"
+	"strings"
+)
+
+func shellFlag(subCommand *kingpin.CmdClause) *string {
+	help := fmt.Sprintf("Shell to print commands for, detected from parent process or $SHELL if not set. Supported shells: %s", strings.Join(shell.Names(), ", "))
+	return subCommand.Flag("shell", help).Enum(shell.Names()...)
+}
+
+func shellFormatter(shellName string, detectShell DetectShellFn) (shell.Formatter, error) {
+	if shellName == "" {
+		shellName = detectShell()
+	}
+
+	formatter, err := shell.Get(shellName)
+	return formatter, withCategory(CategoryUsage, err)
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/hpcsc/aws-profile/internal/awsconfig"
+	"github.com/hpcsc/aws-profile/internal/config"
+	"github.com/hpcsc/aws-profile/internal/io"
+	"gopkg.in/alecthomas/kingpin.v2"
+	"gopkg.in/ini.v1"
+	"strings"
+	"time"
+)
+
+type SSOLoginFn func(*awsconfig.Profile) (time.Time, error)
+
+type SSOLoginHandler struct {
+	SubCommand    *kingpin.CmdClause
+	Arguments     SSOLoginCommandArguments
+	SelectProfile SelectProfileFn
+	SSOLogin      SSOLoginFn
+	Config        *config.Config
+}
+
+type SSOLoginCommandArguments struct {
+	Pattern *string
+}
+
+func NewSSOLoginHandler(app *kingpin.Application, config *config.Config, selectProfileFn SelectProfileFn, ssoLoginFn SSOLoginFn) SSOLoginHandler {
+	ssoCommand := app.Command("sso", "AWS IAM Identity Center (SSO) commands")
+	subCommand := ssoCommand.Command("login", "login to AWS IAM Identity Center (SSO) using device authorization and cache the token for selected SSO profile")
+
+	pattern := subCommand.Arg("pattern", "Filter profiles by given pattern").String()
+
+	return SSOLoginHandler{
+		SubCommand: subCommand,
+		Arguments: SSOLoginCommandArguments{
+			Pattern: pattern,
+		},
+		SelectProfile: selectProfileFn,
+		SSOLogin:      ssoLoginFn,
+		Config:        config,
+	}
+}
+
+func (handler SSOLoginHandler) Handle(globalArguments GlobalArguments) (Result, error) {
+	configFile, err := io.ReadFile(globalArguments.ConfigFilePath)
+	if err != nil {
+		return Result{}, fileReadError("AWS config file", err)
+	}
+
+	loadedProfiles, err := awsconfig.LoadProfilesFromConfigAndCredentials(ini.Empty(), configFile)
+	if err != nil {
+		return Result{}, withCategory(CategoryConfigInvalid, err)
+	}
+
+	profiles := awsconfig.Profiles{
+		ConfigSSOProfiles: loadedProfiles.
+			WithOriginFiles(globalArguments.CredentialsFilePath, globalArguments.ConfigFilePath).ConfigSSOProfiles,
+	}
+
+	selectProfileResult, err := handler.SelectProfile(profiles, *handler.Arguments.Pattern, handler.Config)
+	if err != nil {
+		return Result{}, fmt.Errorf("Failed to select profile: %w", err)
+	}
+
+	trimmedSelectedProfileResult := strings.TrimSuffix(string(selectProfileResult), "\n")
+
+	profile := profiles.FindSSOProfileInConfigFile(trimmedSelectedProfileResult)
+	if profile == nil {
+		return Result{}, newError(CategoryNotFound, "=== sso profile [%s] not found in config file", trimmedSelectedProfileResult)
+	}
+
+	expiresAt, err := handler.SSOLogin(profile)
+	if err != nil {
+		return Result{}, credentialsError(err)
+	}
+
+	return Result{Output: fmt.Sprintf("=== logged in to [%s], token valid until %s", profile.SSOStartUrl, expiresAt.Local().Format(time.RFC1123))}, nil
+}
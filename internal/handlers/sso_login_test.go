@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"github.com/hpcsc/aws-profile/internal/awsconfig"
+	"github.com/hpcsc/aws-profile/internal/config"
+	"github.com/hpcsc/aws-profile/internal/utils"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/alecthomas/kingpin.v2"
+	"os"
+	"testing"
+	"time"
+)
+
+func stubSSOLogin(_ *awsconfig.Profile) (time.Time, error) {
+	return time.Date(2020, 1, 1, 8, 0, 0, 0, time.UTC), nil
+}
+
+func setupSSOLoginHandler(selectProfileFn SelectProfileFn, ssoLoginFn SSOLoginFn) SSOLoginHandler {
+	app := kingpin.New("some-app", "some description")
+	ssoLoginHandler := NewSSOLoginHandler(app, stubConfig(), selectProfileFn, ssoLoginFn)
+
+	if _, err := app.Parse([]string{"sso", "login"}); err != nil {
+		fmt.Printf("failed to setup test sso login handler: %v\n", err)
+		os.Exit(1)
+	}
+
+	return ssoLoginHandler
+}
+
+func TestSSOLoginHandler(t *testing.T) {
+	t.Run("return error if config file is not found", func(t *testing.T) {
+		ssoLoginHandler := setupSSOLoginHandler(nil, nil)
+		globalArguments := stubGlobalArgumentsForExport("config_not_exists")
+
+		_, err := ssoLoginHandler.Handle(globalArguments)
+
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "Fail to read AWS config file")
+	})
+
+	t.Run("invoke SelectProfile with sso profile names only", func(t *testing.T) {
+		called := false
+
+		selectProfileMock := func(profiles awsconfig.Profiles, pattern string, c *config.Config) ([]byte, error) {
+			require.ElementsMatch(
+				t,
+				profiles.GetAllDisplayProfileNames(),
+				[]string{
+					"sso profile sso_profile_1",
+					"sso profile sso_profile_2",
+				},
+			)
+
+			called = true
+			return []byte("profile sso_profile_1"), nil
+		}
+
+		ssoLoginHandler := setupSSOLoginHandler(selectProfileMock, stubSSOLogin)
+		globalArguments := stubGlobalArgumentsForExport("sso-config")
+
+		_, err := ssoLoginHandler.Handle(globalArguments)
+
+		require.NoError(t, err)
+		require.True(t, called)
+	})
+
+	t.Run("login with selected sso profile", func(t *testing.T) {
+		selectProfileStub := func(profiles awsconfig.Profiles, pattern string, c *config.Config) ([]byte, error) {
+			return []byte("profile sso_profile_2"), nil
+		}
+
+		var loggedInProfile *awsconfig.Profile
+		ssoLoginMock := func(profile *awsconfig.Profile) (time.Time, error) {
+			loggedInProfile = profile
+			return stubSSOLogin(profile)
+		}
+
+		ssoLoginHandler := setupSSOLoginHandler(selectProfileStub, ssoLoginMock)
+		globalArguments := stubGlobalArgumentsForExport("sso-config")
+
+		result, err := ssoLoginHandler.Handle(globalArguments)
+
+		require.NoError(t, err)
+		require.Contains(t, result.Output, "logged in to [https://my-sso.awsapps.com/start]")
+		require.Equal(t, "my-sso", loggedInProfile.SSOSession)
+		require.Equal(t, "ap-southeast-2", loggedInProfile.SSORegion)
+	})
+
+	t.Run("return cancelled error when user cancels in the middle of selection", func(t *testing.T) {
+		selectProfileStub := func(profiles awsconfig.Profiles, pattern string, c *config.Config) ([]byte, error) {
+			return nil, utils.NewCancelledError()
+		}
+
+		ssoLoginHandler := setupSSOLoginHandler(selectProfileStub, stubSSOLogin)
+		globalArguments := stubGlobalArgumentsForExport("sso-config")
+
+		_, err := ssoLoginHandler.Handle(globalArguments)
+
+		require.Equal(t, CategoryCancelled, CategoryOf(err))
+	})
+
+	t.Run("return error when failed to login", func(t *testing.T) {
+		selectProfileStub := func(profiles awsconfig.Profiles, pattern string, c *config.Config) ([]byte, error) {
+			return []byte("profile sso_profile_1"), nil
+		}
+
+		ssoLoginStub := func(profile *awsconfig.Profile) (time.Time, error) {
+			return time.Time{}, errors.New("some error")
+		}
+
+		ssoLoginHandler := setupSSOLoginHandler(selectProfileStub, ssoLoginStub)
+		globalArguments := stubGlobalArgumentsForExport("sso-config")
+
+		_, err := ssoLoginHandler.Handle(globalArguments)
+
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "some error")
+	})
+}
@@ -5,34 +5,52 @@ import (
 )
 
 type UnsetHandler struct {
-	SubCommand *kingpin.CmdClause
-	IsWindows  bool
+	SubCommand  *kingpin.CmdClause
+	DetectShell DetectShellFn
+	Arguments   UnsetCommandArguments
 }
 
-func NewUnsetHandler(app *kingpin.Application, isWindows bool) UnsetHandler {
+type UnsetCommandArguments struct {
+	Shell *string
+}
+
+var unsetVariableNames = []string{
+	"AWS_ACCESS_KEY_ID",
+	"AWS_SECRET_ACCESS_KEY",
+	"AWS_SESSION_TOKEN",
+	"AWS_REGION",
+	"AWS_DEFAULT_REGION",
+}
+
+func NewUnsetHandler(app *kingpin.Application, detectShellFn DetectShellFn) UnsetHandler {
 	subCommand := app.Command("unset", `print commands to unset AWS credentials environment variables
 
 To execute the command without printing it to console:
 
 - For Linux/MacOS, execute: "eval $(aws-profile unset)"
 
-- For Windows, execute: "Invoke-Expression (path\to\aws-profile.exe unset)"`)
+- For Windows, execute: "Invoke-Expression (path\to\aws-profile.exe unset)"
+
+- For fish, execute: "eval (aws-profile unset)"
+
+Commands are printed for the shell detected from parent process or $SHELL, use --shell to choose another shell`)
+
+	shellName := shellFlag(subCommand)
 
 	return UnsetHandler{
-		SubCommand: subCommand,
-		IsWindows:  isWindows,
+		SubCommand:  subCommand,
+		DetectShell: detectShellFn,
+		Arguments: UnsetCommandArguments{
+			Shell: shellName,
+		},
 	}
 }
 
-func (handler UnsetHandler) Handle(_ GlobalArguments) (bool, string) {
-	output := formatUnsetCommandByPlatform(handler.IsWindows)
-	return true, output
-}
-
-func formatUnsetCommandByPlatform(isWindows bool) string {
-	if isWindows {
-		return "Remove-Item Env:\\AWS_ACCESS_KEY_ID, Env:\\AWS_SECRET_ACCESS_KEY, Env:\\AWS_SESSION_TOKEN, Env:\\AWS_REGION, Env:\\AWS_DEFAULT_REGION"
+func (handler UnsetHandler) Handle(_ GlobalArguments) (Result, error) {
+	formatter, err := shellFormatter(*handler.Arguments.Shell, handler.DetectShell)
+	if err != nil {
+		return Result{}, err
 	}
 
-	return "unset AWS_ACCESS_KEY_ID AWS_SECRET_ACCESS_KEY AWS_SESSION_TOKEN AWS_REGION AWS_DEFAULT_REGION"
+	return Result{Output: formatter.Unset(unsetVariableNames)}, nil
 }
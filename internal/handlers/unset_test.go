@@ -10,7 +10,7 @@ import (
 
 func setupUnsetHandler(isWindows bool) UnsetHandler {
 	app := kingpin.New("some-app", "some description")
-	unsetHandler := NewUnsetHandler(app, isWindows)
+	unsetHandler := NewUnsetHandler(app, stubDetectShell(isWindows))
 
 	if _, err := app.Parse([]string{"unset"}); err != nil {
 		fmt.Printf("failed to setup test set handler: %v\n", err)
@@ -24,18 +24,38 @@ func TestUnsetHandler(t *testing.T) {
 	t.Run("contains unset command for Linux and MacOS in output", func(t *testing.T) {
 		unsetHandler := setupUnsetHandler(false)
 
-		success, output := unsetHandler.Handle(GlobalArguments{})
+		result, err := unsetHandler.Handle(GlobalArguments{})
 
-		require.True(t, success)
-		require.Equal(t, output, "unset AWS_ACCESS_KEY_ID AWS_SECRET_ACCESS_KEY AWS_SESSION_TOKEN AWS_REGION AWS_DEFAULT_REGION")
+		require.NoError(t, err)
+		require.Equal(t, result.Output, "unset AWS_ACCESS_KEY_ID AWS_SECRET_ACCESS_KEY AWS_SESSION_TOKEN AWS_REGION AWS_DEFAULT_REGION")
 	})
 
 	t.Run("contains unset command for Windows in output", func(t *testing.T) {
 		unsetHandler := setupUnsetHandler(true)
 
-		success, output := unsetHandler.Handle(GlobalArguments{})
+		result, err := unsetHandler.Handle(GlobalArguments{})
 
-		require.True(t, success)
-		require.Equal(t, output, "Remove-Item Env:\\AWS_ACCESS_KEY_ID, Env:\\AWS_SECRET_ACCESS_KEY, Env:\\AWS_SESSION_TOKEN, Env:\\AWS_REGION, Env:\\AWS_DEFAULT_REGION")
+		require.NoError(t, err)
+		require.Equal(t, result.Output, "Remove-Item Env:\\AWS_ACCESS_KEY_ID, Env:\\AWS_SECRET_ACCESS_KEY, Env:\\AWS_SESSION_TOKEN, Env:\\AWS_REGION, Env:\\AWS_DEFAULT_REGION")
+	})
+
+	t.Run("contains unset command for shell given by --shell", func(t *testing.T) {
+		app := kingpin.New("some-app", "some description")
+		unsetHandler := NewUnsetHandler(app, stubDetectShell(false))
+		_, err := app.Parse([]string{"unset", "--shell", "nu"})
+		require.NoError(t, err)
+
+		result, err := unsetHandler.Handle(GlobalArguments{})
+
+		require.NoError(t, err)
+		require.Equal(t, "hide-env -i AWS_ACCESS_KEY_ID AWS_SECRET_ACCESS_KEY AWS_SESSION_TOKEN AWS_REGION AWS_DEFAULT_REGION", result.Output)
+	})
+
+	t.Run("reject unsupported shell", func(t *testing.T) {
+		app := kingpin.New("some-app", "some description")
+		NewUnsetHandler(app, stubDetectShell(false))
+		_, err := app.Parse([]string{"unset", "--shell", "rc"})
+
+		require.Error(t, err)
 	})
 }
@@ -33,16 +33,16 @@ func NewUpgradeHandler(app *kingpin.Application, logger log.Logger) UpgradeHandl
 	}
 }
 
-func (handler UpgradeHandler) Handle(globalArguments GlobalArguments) (bool, string) {
+func (handler UpgradeHandler) Handle(globalArguments GlobalArguments) (Result, error) {
 	binaryPath, err := os.Executable()
 	if err != nil {
-		return false, fmt.Sprintf("failed to get current executable path: %v", err)
+		return Result{}, fmt.Errorf("failed to get current executable path: %v", err)
 	}
 
 	message, err := upgrade.ToLatest(binaryPath, *handler.Arguments.IncludePrerelease, version.Current())
 	if err != nil {
-		return false, err.Error()
+		return Result{}, err
 	}
 
-	return true, message
+	return Result{Output: message}, nil
 }
@@ -17,7 +17,6 @@ func NewVersionHandler(app *kingpin.Application) VersionHandler {
 	}
 }
 
-func (handler VersionHandler) Handle(globalArguments GlobalArguments) (bool, string) {
-	fmt.Printf("aws-profile (%s)", version.Current())
-	return true, ""
+func (handler VersionHandler) Handle(globalArguments GlobalArguments) (Result, error) {
+	return Result{Output: fmt.Sprintf("aws-profile (%s)", version.Current())}, nil
 }
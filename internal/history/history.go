@@ -0,0 +1,106 @@
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hpcsc/aws-profile/internal/utils"
+)
+
+const (
+	historyFile = "~/.aws-profile/history.json"
+	maxEntries  = 20
+)
+
+type Entry struct {
+	ProfileName string    `json:"profileName"`
+	LastUsed    time.Time `json:"lastUsed"`
+}
+
+// Store keeps profiles selected by set and export, the most recently used first
+type Store struct {
+	filePath string
+	now      func() time.Time
+}
+
+func NewStore(filePath string) *Store {
+	return &Store{
+		filePath: filePath,
+		now:      time.Now,
+	}
+}
+
+func NewDefaultStore() *Store {
+	return NewStore(utils.ExpandHomeDirectory(historyFile))
+}
+
+// Record moves given profile to the top of history, only the most recent entries are kept. History that can't be read
+// is replaced
+func (store *Store) Record(profileName string) error {
+	entries, _ := store.read()
+
+	updated := []Entry{{ProfileName: profileName, LastUsed: store.now().UTC()}}
+	for _, entry := range entries {
+		if !strings.EqualFold(entry.ProfileName, profileName) && len(updated) < maxEntries {
+			updated = append(updated, entry)
+		}
+	}
+
+	return store.write(updated)
+}
+
+// Recent returns names of recently used profiles, the most recent first. History that can't be read is treated as empty
+// so that it never prevents selecting a profile
+func (store *Store) Recent() []string {
+	entries, err := store.read()
+	if err != nil {
+		return nil
+	}
+
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.ProfileName)
+	}
+
+	return names
+}
+
+func (store *Store) read() ([]Entry, error) {
+	content, err := ioutil.ReadFile(filepath.Clean(store.filePath))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history file %s: %v", store.filePath, err)
+	}
+
+	var entries []Entry
+	if err := json.Unmarshal(content, &entries); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal history file %s: %v", store.filePath, err)
+	}
+
+	return entries, nil
+}
+
+func (store *Store) write(entries []Entry) error {
+	if err := os.MkdirAll(filepath.Dir(store.filePath), os.FileMode(0700)); err != nil {
+		return fmt.Errorf("failed to create history directory: %v", err)
+	}
+
+	content, err := json.Marshal(entries)
+	if err != nil {
+		return fmt.Errorf("failed to marshal history: %v", err)
+	}
+
+	if err := ioutil.WriteFile(store.filePath, content, os.FileMode(0600)); err != nil {
+		return fmt.Errorf("failed to write history file %s: %v", store.filePath, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,76 @@
+package history
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func setupStore(t *testing.T) (*Store, func()) {
+	directory, err := ioutil.TempDir("", "aws-profile-history")
+	require.NoError(t, err)
+
+	store := NewStore(filepath.Join(directory, "history.json"))
+	store.now = func() time.Time {
+		return time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+
+	return store, func() {
+		_ = os.RemoveAll(directory)
+	}
+}
+
+func TestStore(t *testing.T) {
+	t.Run("return no recent profiles when history file doesn't exist", func(t *testing.T) {
+		store, cleanup := setupStore(t)
+		defer cleanup()
+
+		require.Empty(t, store.Recent())
+	})
+
+	t.Run("return recorded profiles with the most recent first", func(t *testing.T) {
+		store, cleanup := setupStore(t)
+		defer cleanup()
+
+		require.NoError(t, store.Record("profile a"))
+		require.NoError(t, store.Record("profile b"))
+		require.NoError(t, store.Record("profile a"))
+
+		require.Equal(t, []string{"profile a", "profile b"}, store.Recent())
+	})
+
+	t.Run("keep only most recent entries", func(t *testing.T) {
+		store, cleanup := setupStore(t)
+		defer cleanup()
+
+		for i := 0; i < maxEntries+5; i++ {
+			require.NoError(t, store.Record(string(rune('a'+i))))
+		}
+
+		recent := store.Recent()
+		require.Len(t, recent, maxEntries)
+		require.Equal(t, string(rune('a'+maxEntries+4)), recent[0])
+	})
+
+	t.Run("return no recent profiles when history file is invalid", func(t *testing.T) {
+		store, cleanup := setupStore(t)
+		defer cleanup()
+		require.NoError(t, ioutil.WriteFile(store.filePath, []byte("invalid"), 0600))
+
+		require.Empty(t, store.Recent())
+	})
+
+	t.Run("replace invalid history file when recording", func(t *testing.T) {
+		store, cleanup := setupStore(t)
+		defer cleanup()
+		require.NoError(t, ioutil.WriteFile(store.filePath, []byte("invalid"), 0600))
+
+		require.NoError(t, store.Record("profile a"))
+
+		require.Equal(t, []string{"profile a"}, store.Recent())
+	})
+}
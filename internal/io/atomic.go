@@ -0,0 +1,89 @@
+package io
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+const defaultFileMode = os.FileMode(0600)
+
+// WriteFileAtomically replaces file at given path with content so that readers see either the old or the new content,
+// never a truncated file. Content is written to a temporary file in the same directory, flushed to disk and renamed
+// over the original file, keeping its permissions
+func WriteFileAtomically(filePath string, content []byte) error {
+	targetPath, mode, err := writeTarget(filePath)
+	if err != nil {
+		return err
+	}
+
+	temp, err := ioutil.TempFile(filepath.Dir(targetPath), "."+filepath.Base(targetPath)+".tmp")
+	if err != nil {
+		return fmt.Errorf("fail to create temporary file for %s: %v", filePath, err)
+	}
+
+	renamed := false
+	defer func() {
+		if !renamed {
+			_ = temp.Close()
+			_ = os.Remove(temp.Name())
+		}
+	}()
+
+	if _, err := temp.Write(content); err != nil {
+		return fmt.Errorf("fail to write to temporary file %s: %v", temp.Name(), err)
+	}
+
+	if err := temp.Chmod(mode); err != nil {
+		return fmt.Errorf("fail to set permissions of temporary file %s: %v", temp.Name(), err)
+	}
+
+	if err := temp.Sync(); err != nil {
+		return fmt.Errorf("fail to flush temporary file %s: %v", temp.Name(), err)
+	}
+
+	if err := temp.Close(); err != nil {
+		return fmt.Errorf("fail to close temporary file %s: %v", temp.Name(), err)
+	}
+
+	if err := os.Rename(temp.Name(), targetPath); err != nil {
+		return fmt.Errorf("fail to replace file %s: %v", filePath, err)
+	}
+	renamed = true
+
+	syncDirectory(filepath.Dir(targetPath))
+	return nil
+}
+
+// writeTarget returns the file that should be replaced and its permissions. Symlinked files (e.g. managed by dotfiles
+// tools) are resolved so that the link itself is kept
+func writeTarget(filePath string) (string, os.FileMode, error) {
+	targetPath, err := filepath.EvalSymlinks(filePath)
+	if os.IsNotExist(err) {
+		return filePath, defaultFileMode, nil
+	}
+
+	if err != nil {
+		return "", 0, fmt.Errorf("fail to resolve file %s: %v", filePath, err)
+	}
+
+	info, err := os.Stat(targetPath)
+	if err != nil {
+		return "", 0, fmt.Errorf("fail to read permissions of file %s: %v", filePath, err)
+	}
+
+	return targetPath, info.Mode().Perm(), nil
+}
+
+// syncDirectory makes the rename durable. Not all platforms support syncing directories (e.g. Windows), so this is best
+// effort only
+func syncDirectory(directory string) {
+	dir, err := os.Open(filepath.Clean(directory))
+	if err != nil {
+		return
+	}
+
+	_ = dir.Sync()
+	_ = dir.Close()
+}
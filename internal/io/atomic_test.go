@@ -0,0 +1,92 @@
+package io
+
+import (
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestWriteFileAtomically(t *testing.T) {
+	setupDirectory := func(t *testing.T) (string, func()) {
+		directory, err := ioutil.TempDir("", "aws-profile-io")
+		require.NoError(t, err)
+		return directory, func() { _ = os.RemoveAll(directory) }
+	}
+
+	t.Run("replace content without leaving temporary files", func(t *testing.T) {
+		directory, cleanup := setupDirectory(t)
+		defer cleanup()
+		filePath := filepath.Join(directory, "credentials")
+		require.NoError(t, ioutil.WriteFile(filePath, []byte("old"), 0600))
+
+		require.NoError(t, WriteFileAtomically(filePath, []byte("new")))
+
+		content, err := ioutil.ReadFile(filePath)
+		require.NoError(t, err)
+		require.Equal(t, "new", string(content))
+
+		entries, err := ioutil.ReadDir(directory)
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+	})
+
+	t.Run("keep permissions of existing file", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("file permissions are not supported on windows")
+		}
+
+		directory, cleanup := setupDirectory(t)
+		defer cleanup()
+		filePath := filepath.Join(directory, "config")
+		require.NoError(t, ioutil.WriteFile(filePath, []byte("old"), 0640))
+		require.NoError(t, os.Chmod(filePath, 0640))
+
+		require.NoError(t, WriteFileAtomically(filePath, []byte("new")))
+
+		info, err := os.Stat(filePath)
+		require.NoError(t, err)
+		require.Equal(t, os.FileMode(0640), info.Mode().Perm())
+	})
+
+	t.Run("create new file readable by owner only", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("file permissions are not supported on windows")
+		}
+
+		directory, cleanup := setupDirectory(t)
+		defer cleanup()
+		filePath := filepath.Join(directory, "credentials")
+
+		require.NoError(t, WriteFileAtomically(filePath, []byte("new")))
+
+		info, err := os.Stat(filePath)
+		require.NoError(t, err)
+		require.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	})
+
+	t.Run("replace target of symlink and keep the symlink", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("symlinks require elevated privileges on windows")
+		}
+
+		directory, cleanup := setupDirectory(t)
+		defer cleanup()
+		targetPath := filepath.Join(directory, "dotfiles-credentials")
+		linkPath := filepath.Join(directory, "credentials")
+		require.NoError(t, ioutil.WriteFile(targetPath, []byte("old"), 0600))
+		require.NoError(t, os.Symlink(targetPath, linkPath))
+
+		require.NoError(t, WriteFileAtomically(linkPath, []byte("new")))
+
+		info, err := os.Lstat(linkPath)
+		require.NoError(t, err)
+		require.True(t, info.Mode()&os.ModeSymlink != 0)
+
+		content, err := ioutil.ReadFile(targetPath)
+		require.NoError(t, err)
+		require.Equal(t, "new", string(content))
+	})
+}
@@ -0,0 +1,85 @@
+package io
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	backupsDirectory      = "~/.aws-profile/backups"
+	maxBackupsPerFile     = 10
+	backupTimestampFormat = "20060102-150405.000000000"
+)
+
+// backupFile copies content of a file about to be replaced to backup directory as <file name>-<path hash>.<timestamp>.
+// Only the most recent backups of each file are kept
+func backupFile(filePath string, content []byte, backupDirectory string, now time.Time) error {
+	if err := os.MkdirAll(backupDirectory, os.FileMode(0700)); err != nil {
+		return fmt.Errorf("fail to create backup directory %s: %v", backupDirectory, err)
+	}
+
+	name, err := backupName(filePath)
+	if err != nil {
+		return err
+	}
+
+	backupPath := filepath.Join(backupDirectory, name+"."+now.UTC().Format(backupTimestampFormat))
+	if err := ioutil.WriteFile(backupPath, content, defaultFileMode); err != nil {
+		return fmt.Errorf("fail to back up %s to %s: %v", filePath, backupPath, err)
+	}
+
+	return removeOldBackups(backupDirectory, name)
+}
+
+// backupName identifies backups of a file, files with the same name in different directories, e.g. config files of
+// AWS_CONFIG_FILE pointing to different projects, are kept apart by hash of their absolute path
+func backupName(filePath string) (string, error) {
+	absolutePath, err := filepath.Abs(filePath)
+	if err != nil {
+		return "", fmt.Errorf("fail to resolve absolute path of %s: %v", filePath, err)
+	}
+
+	hash := sha256.Sum256([]byte(absolutePath))
+	return filepath.Base(filePath) + "-" + hex.EncodeToString(hash[:4]), nil
+}
+
+func removeOldBackups(backupDirectory string, name string) error {
+	entries, err := ioutil.ReadDir(backupDirectory)
+	if err != nil {
+		return fmt.Errorf("fail to list backup directory %s: %v", backupDirectory, err)
+	}
+
+	var backups []string
+	for _, entry := range entries {
+		if isBackupOf(entry.Name(), name) {
+			backups = append(backups, entry.Name())
+		}
+	}
+
+	// timestamps have fixed width so backups sort from oldest to newest
+	sort.Strings(backups)
+	for len(backups) > maxBackupsPerFile {
+		if err := os.Remove(filepath.Join(backupDirectory, backups[0])); err != nil {
+			return fmt.Errorf("fail to remove old backup %s: %v", backups[0], err)
+		}
+		backups = backups[1:]
+	}
+
+	return nil
+}
+
+func isBackupOf(backupName string, name string) bool {
+	if !strings.HasPrefix(backupName, name+".") {
+		return false
+	}
+
+	_, err := time.Parse(backupTimestampFormat, strings.TrimPrefix(backupName, name+"."))
+	return err == nil
+}
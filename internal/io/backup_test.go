@@ -0,0 +1,105 @@
+package io
+
+import (
+	"github.com/stretchr/testify/require"
+	"gopkg.in/ini.v1"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBackupFile(t *testing.T) {
+	t.Run("keep only the most recent backups of each file", func(t *testing.T) {
+		directory, err := ioutil.TempDir("", "aws-profile-backups")
+		require.NoError(t, err)
+		defer func() { _ = os.RemoveAll(directory) }()
+		require.NoError(t, ioutil.WriteFile(filepath.Join(directory, "config.20200101-000000.000000000"), []byte("config"), 0600))
+
+		start := time.Date(2020, 6, 1, 10, 0, 0, 0, time.UTC)
+		for i := 0; i < maxBackupsPerFile+2; i++ {
+			require.NoError(t, backupFile("/home/user/.aws/credentials", []byte{byte('a' + i)}, directory, start.Add(time.Duration(i)*time.Second)))
+		}
+
+		entries, err := ioutil.ReadDir(directory)
+		require.NoError(t, err)
+		require.Len(t, entries, maxBackupsPerFile+1)
+		name, err := backupName("/home/user/.aws/credentials")
+		require.NoError(t, err)
+		require.Equal(t, "config.20200101-000000.000000000", entries[0].Name())
+		require.Equal(t, name+".20200601-100002.000000000", entries[1].Name())
+		require.Equal(t, name+".20200601-100011.000000000", entries[maxBackupsPerFile].Name())
+
+		content, err := ioutil.ReadFile(filepath.Join(directory, entries[maxBackupsPerFile].Name()))
+		require.NoError(t, err)
+		require.Equal(t, "l", string(content))
+	})
+
+	t.Run("keep backups of files with the same name in different directories apart", func(t *testing.T) {
+		directory, err := ioutil.TempDir("", "aws-profile-backups")
+		require.NoError(t, err)
+		defer func() { _ = os.RemoveAll(directory) }()
+
+		start := time.Date(2020, 6, 1, 10, 0, 0, 0, time.UTC)
+		require.NoError(t, backupFile("/home/user/project-a/config", []byte("a"), directory, start))
+		for i := 0; i < maxBackupsPerFile+2; i++ {
+			require.NoError(t, backupFile("/home/user/project-b/config", []byte("b"), directory, start.Add(time.Duration(i+1)*time.Second)))
+		}
+
+		name, err := backupName("/home/user/project-a/config")
+		require.NoError(t, err)
+		content, err := ioutil.ReadFile(filepath.Join(directory, name+".20200601-100000.000000000"))
+		require.NoError(t, err)
+		require.Equal(t, "a", string(content))
+	})
+}
+
+func TestWriteToFile_Backup(t *testing.T) {
+	setupFile := func(t *testing.T) (string, string, func()) {
+		directory, err := ioutil.TempDir("", "aws-profile-io")
+		require.NoError(t, err)
+		filePath := filepath.Join(directory, "config")
+		require.NoError(t, ioutil.WriteFile(filePath, []byte("[default]\nregion = us-east-1\n"), 0600))
+		return filePath, filepath.Join(directory, "backups"), func() { _ = os.RemoveAll(directory) }
+	}
+
+	t.Run("back up previous content before replacing file", func(t *testing.T) {
+		filePath, backupDirectory, cleanup := setupFile(t)
+		defer cleanup()
+		file, err := ReadFile(filePath)
+		require.NoError(t, err)
+		file.Section("default").Key("region").SetValue("us-west-2")
+
+		require.NoError(t, writeToFile(file, filePath, backupDirectory, time.Date(2020, 6, 1, 10, 0, 0, 0, time.UTC)))
+
+		name, err := backupName(filePath)
+		require.NoError(t, err)
+		backup, err := ioutil.ReadFile(filepath.Join(backupDirectory, name+".20200601-100000.000000000"))
+		require.NoError(t, err)
+		require.Equal(t, "[default]\nregion = us-east-1\n", string(backup))
+	})
+
+	t.Run("not back up or write file when content is unchanged", func(t *testing.T) {
+		filePath, backupDirectory, cleanup := setupFile(t)
+		defer cleanup()
+		file, err := ReadFile(filePath)
+		require.NoError(t, err)
+
+		require.NoError(t, writeToFile(file, filePath, backupDirectory, time.Now()))
+
+		_, err = os.Stat(backupDirectory)
+		require.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("not back up new file", func(t *testing.T) {
+		filePath, backupDirectory, cleanup := setupFile(t)
+		defer cleanup()
+		newFilePath := filepath.Join(filepath.Dir(filePath), "credentials")
+
+		require.NoError(t, writeToFile(ini.Empty(), newFilePath, backupDirectory, time.Now()))
+
+		_, err := os.Stat(backupDirectory)
+		require.True(t, os.IsNotExist(err))
+	})
+}
@@ -0,0 +1,298 @@
+package io
+
+import (
+	"fmt"
+	"gopkg.in/ini.v1"
+	"strings"
+)
+
+const byteOrderMark = "\uFEFF"
+
+// iniLine is a line of an ini file as written by users, a key with multi-line value spans several physical lines
+type iniLine struct {
+	text    string
+	section string
+	key     string
+	header  bool
+}
+
+// patchINI applies sections and keys of updated to original content of the file it was loaded from. Only lines of
+// changed keys are rewritten, comments, blank lines, ordering and spacing of everything else are kept as they are
+func patchINI(original []byte, updated *ini.File) ([]byte, error) {
+	originalFile, err := ini.Load(original)
+	if err != nil {
+		return nil, err
+	}
+
+	content := string(original)
+	lineBreak := "\n"
+	if strings.Contains(content, "\r\n") {
+		lineBreak = "\r\n"
+	}
+
+	lines := parseINILines(content, lineBreak)
+	replaced := map[int]string{}
+	deleted := map[int]bool{}
+	inserted := map[int][]string{}
+	var appended []string
+
+	for _, originalSection := range originalFile.Sections() {
+		if _, err := updated.GetSection(originalSection.Name()); err != nil {
+			if !hasSection(lines, originalSection.Name()) {
+				return nil, fmt.Errorf("section [%s] not found in original content", originalSection.Name())
+			}
+
+			for i, line := range lines {
+				if line.section == originalSection.Name() && (line.header || line.key != "") {
+					deleted[i] = true
+				}
+			}
+		}
+	}
+
+	for _, section := range updated.Sections() {
+		originalSection, err := originalFile.GetSection(section.Name())
+		if err != nil {
+			appended = append(appended, newSectionLines(section, lines)...)
+			continue
+		}
+
+		// names parsed from lines can differ from names parsed by ini package, e.g. quoted key names, then lines of
+		// the section can't be found and the file is written in full instead
+		if section.Name() != ini.DefaultSection && !hasSection(lines, section.Name()) {
+			return nil, fmt.Errorf("section [%s] not found in original content", section.Name())
+		}
+
+		for _, key := range section.Keys() {
+			if !originalSection.HasKey(key.Name()) {
+				position := insertPosition(lines, section.Name())
+				inserted[position] = append(inserted[position], newKeyLine(lines, section.Name(), key))
+			} else if originalSection.Key(key.Name()).Value() != key.Value() {
+				i := lastKeyLine(lines, section.Name(), key.Name())
+				if i < 0 {
+					return nil, fmt.Errorf("key %s of section [%s] not found in original content", key.Name(), section.Name())
+				}
+				replaced[i] = replaceValue(lines[i].text, key.Value())
+			}
+		}
+
+		for _, originalKey := range originalSection.Keys() {
+			if !section.HasKey(originalKey.Name()) {
+				if lastKeyLine(lines, section.Name(), originalKey.Name()) < 0 {
+					return nil, fmt.Errorf("key %s of section [%s] not found in original content", originalKey.Name(), section.Name())
+				}
+
+				for i, line := range lines {
+					if line.section == section.Name() && line.key == originalKey.Name() {
+						deleted[i] = true
+					}
+				}
+			}
+		}
+	}
+
+	var output []string
+	output = append(output, inserted[-1]...)
+	for i, line := range lines {
+		if replacement, ok := replaced[i]; ok {
+			output = append(output, replacement)
+		} else if !deleted[i] {
+			output = append(output, line.text)
+		}
+		output = append(output, inserted[i]...)
+	}
+
+	if len(appended) > 0 {
+		if len(output) > 0 && strings.TrimSpace(output[len(output)-1]) != "" {
+			output = append(output, "")
+		}
+		output = append(output, appended...)
+	}
+
+	result := strings.Join(output, lineBreak)
+	if result != "" && (strings.HasSuffix(content, "\n") || content == "" || len(appended) > 0) {
+		result += lineBreak
+	}
+
+	return []byte(result), nil
+}
+
+func parseINILines(content string, lineBreak string) []iniLine {
+	var lines []iniLine
+	if content == "" {
+		return lines
+	}
+
+	physicalLines := strings.Split(strings.TrimSuffix(content, lineBreak), lineBreak)
+	section := ini.DefaultSection
+	for i := 0; i < len(physicalLines); i++ {
+		text := physicalLines[i]
+		trimmed := strings.TrimSpace(strings.TrimPrefix(text, byteOrderMark))
+
+		switch {
+		case strings.HasPrefix(trimmed, "[") && strings.Contains(trimmed, "]"):
+			section = strings.TrimSpace(trimmed[1:strings.LastIndex(trimmed, "]")])
+			lines = append(lines, iniLine{text: text, section: section, header: true})
+		case trimmed == "" || strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, ";"):
+			lines = append(lines, iniLine{text: text, section: section})
+		default:
+			delimiter := strings.IndexAny(trimmed, "=:")
+			if delimiter < 0 {
+				lines = append(lines, iniLine{text: text, section: section})
+				continue
+			}
+
+			// values surrounded by """ can span several lines
+			value := strings.TrimSpace(trimmed[delimiter+1:])
+			if strings.HasPrefix(value, `"""`) && !strings.Contains(value[3:], `"""`) {
+				for i+1 < len(physicalLines) {
+					i++
+					text += lineBreak + physicalLines[i]
+					if strings.Contains(physicalLines[i], `"""`) {
+						break
+					}
+				}
+			}
+
+			lines = append(lines, iniLine{text: text, section: section, key: strings.TrimSpace(trimmed[:delimiter])})
+		}
+	}
+
+	return lines
+}
+
+// replaceValue replaces value of given key line, keeping indentation, spacing around delimiter and inline comment
+func replaceValue(text string, value string) string {
+	delimiter := strings.IndexAny(text, "=:")
+	valueStart := delimiter + 1
+	for valueStart < len(text) && (text[valueStart] == ' ' || text[valueStart] == '\t') {
+		valueStart++
+	}
+
+	return text[:valueStart] + formatValue(value) + text[valueEnd(text, valueStart):]
+}
+
+// valueEnd returns where value starting at valueStart ends, text after it is whitespace and inline comment
+func valueEnd(text string, valueStart int) int {
+	rest := text[valueStart:]
+
+	switch {
+	case strings.HasPrefix(rest, `"""`):
+		if end := strings.Index(rest[3:], `"""`); end >= 0 {
+			return valueStart + 3 + end + 3
+		}
+		return len(text)
+	case strings.HasPrefix(rest, "`"):
+		if end := strings.Index(rest[1:], "`"); end >= 0 {
+			return valueStart + 1 + end + 1
+		}
+		return len(text)
+	case strings.HasPrefix(rest, `"`):
+		if end := strings.LastIndex(rest, `"`); end > 0 {
+			return valueStart + end + 1
+		}
+		return len(text)
+	}
+
+	comment := strings.IndexAny(rest, "#;")
+	if comment < 0 {
+		comment = len(rest)
+	}
+
+	return valueStart + len(strings.TrimRight(rest[:comment], " \t"))
+}
+
+func hasSection(lines []iniLine, section string) bool {
+	for _, line := range lines {
+		if line.section == section && line.header {
+			return true
+		}
+	}
+
+	return false
+}
+
+// new keys go after the last key of their section, or right after section header if it has no key yet
+func insertPosition(lines []iniLine, section string) int {
+	position := -1
+	for i, line := range lines {
+		if line.section == section && (line.key != "" || line.header) {
+			position = i
+		}
+	}
+
+	return position
+}
+
+func lastKeyLine(lines []iniLine, section string, key string) int {
+	last := -1
+	for i, line := range lines {
+		if line.section == section && line.key == key {
+			last = i
+		}
+	}
+
+	return last
+}
+
+func newKeyLine(lines []iniLine, section string, key *ini.Key) string {
+	indent, separator := keyStyle(lines, section)
+	return indent + key.Name() + separator + formatValue(key.Value())
+}
+
+func newSectionLines(section *ini.Section, lines []iniLine) []string {
+	if section.Name() == ini.DefaultSection && len(section.Keys()) == 0 {
+		return nil
+	}
+
+	sectionLines := []string{"[" + section.Name() + "]"}
+	for _, key := range section.Keys() {
+		sectionLines = append(sectionLines, newKeyLine(lines, section.Name(), key))
+	}
+
+	return sectionLines
+}
+
+// keyStyle returns indentation and spacing around delimiter of the first key in given section, or in the file if the
+// section has no key. Spaces aligning delimiters of keys are not copied
+func keyStyle(lines []iniLine, section string) (string, string) {
+	reference := -1
+	for i, line := range lines {
+		if line.key != "" && (reference < 0 || (line.section == section && lines[reference].section != section)) {
+			reference = i
+		}
+	}
+
+	if reference < 0 {
+		return "", " = "
+	}
+
+	text := strings.TrimPrefix(lines[reference].text, byteOrderMark)
+	indent := text[:len(text)-len(strings.TrimLeft(text, " \t"))]
+	delimiter := strings.IndexAny(text, "=:")
+	before := text[len(indent)+len(lines[reference].key) : delimiter]
+	after := text[delimiter+1:]
+
+	separator := text[delimiter : delimiter+1]
+	if before != "" {
+		separator = " " + separator
+	}
+	if strings.TrimLeft(after, " \t") != after {
+		separator += " "
+	}
+
+	return indent, separator
+}
+
+// same quoting as ini.File.WriteTo
+func formatValue(value string) string {
+	if strings.ContainsAny(value, "\n`") {
+		return `"""` + value + `"""`
+	}
+
+	if strings.ContainsAny(value, "#;") {
+		return "`" + value + "`"
+	}
+
+	return value
+}
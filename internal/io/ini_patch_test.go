@@ -0,0 +1,170 @@
+package io
+
+import (
+	"flag"
+	"github.com/hpcsc/aws-profile/internal/awsconfig"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/ini.v1"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var updateGoldenFiles = flag.Bool("update", false, "update golden files in testdata")
+
+// writeAndCompareWithGolden copies input file to a temporary directory, updates it with given function and compares
+// written file with golden file
+func writeAndCompareWithGolden(t *testing.T, inputName string, goldenName string, update func(file *ini.File)) {
+	input, err := ioutil.ReadFile(filepath.Join("testdata", inputName))
+	require.NoError(t, err)
+
+	directory, err := ioutil.TempDir("", "aws-profile-io")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(directory) }()
+
+	filePath := filepath.Join(directory, inputName)
+	require.NoError(t, ioutil.WriteFile(filePath, input, 0600))
+
+	file, err := ReadFile(filePath)
+	require.NoError(t, err)
+
+	update(file)
+	require.NoError(t, writeToFile(file, filePath, filepath.Join(directory, "backups"), time.Now()))
+
+	written, err := ioutil.ReadFile(filePath)
+	require.NoError(t, err)
+
+	goldenPath := filepath.Join("testdata", goldenName)
+	if *updateGoldenFiles {
+		require.NoError(t, ioutil.WriteFile(goldenPath, written, 0644))
+	}
+
+	golden, err := ioutil.ReadFile(goldenPath)
+	require.NoError(t, err)
+	require.Equal(t, string(golden), string(written))
+}
+
+func TestWriteToFile(t *testing.T) {
+	t.Run("only change region line when setting region of default profile", func(t *testing.T) {
+		writeAndCompareWithGolden(t, "config-comments.ini", "config-comments-set-region.golden", func(file *ini.File) {
+			awsconfig.SetSelectedRegionAsDefault("us-west-2", file)
+		})
+	})
+
+	t.Run("keep comments, blank lines and spacing when setting assumed profile as default", func(t *testing.T) {
+		writeAndCompareWithGolden(t, "config-comments.ini", "config-comments-set-assumed-profile.golden", func(file *ini.File) {
+			awsconfig.SetSelectedAssumedProfileAsDefault("profile staging", file)
+		})
+	})
+
+	t.Run("keep comments, blank lines and spacing when setting credentials profile as default", func(t *testing.T) {
+		writeAndCompareWithGolden(t, "credentials-spacing.ini", "credentials-spacing-set-profile.golden", func(file *ini.File) {
+			awsconfig.SetSelectedProfileAsDefault("staging", file, ini.Empty())
+		})
+	})
+
+	t.Run("append default profile at the end of file when it does not exist", func(t *testing.T) {
+		writeAndCompareWithGolden(t, "credentials-no-default.ini", "credentials-no-default-set-profile.golden", func(file *ini.File) {
+			awsconfig.SetSelectedProfileAsDefault("base", file, ini.Empty())
+		})
+	})
+
+	t.Run("keep windows line breaks", func(t *testing.T) {
+		writeAndCompareWithGolden(t, "config-crlf.ini", "config-crlf-set-assumed-profile.golden", func(file *ini.File) {
+			awsconfig.SetSelectedAssumedProfileAsDefault("profile prod", file)
+		})
+	})
+
+	t.Run("write whole file when lines of changed keys can't be found", func(t *testing.T) {
+		writeAndCompareWithGolden(t, "config-quoted-key.ini", "config-quoted-key-set-region.golden", func(file *ini.File) {
+			awsconfig.SetSelectedRegionAsDefault("us-west-2", file)
+		})
+	})
+
+	t.Run("keep file unchanged when nothing is changed", func(t *testing.T) {
+		writeAndCompareWithGolden(t, "config-comments.ini", "config-comments.ini", func(_ *ini.File) {})
+	})
+
+	t.Run("write whole file when it does not exist", func(t *testing.T) {
+		directory, err := ioutil.TempDir("", "aws-profile-io")
+		require.NoError(t, err)
+		defer func() { _ = os.RemoveAll(directory) }()
+
+		file := ini.Empty()
+		file.Section("default").Key("region").SetValue("us-west-2")
+		filePath := filepath.Join(directory, "config")
+
+		require.NoError(t, writeToFile(file, filePath, filepath.Join(directory, "backups"), time.Now()))
+
+		written, err := ioutil.ReadFile(filePath)
+		require.NoError(t, err)
+		require.Equal(t, "[default]\nregion = us-west-2\n\n", string(written))
+	})
+}
+
+func TestPatchINI(t *testing.T) {
+	t.Run("remove lines of deleted keys and sections", func(t *testing.T) {
+		original := []byte("[default]\nregion = us-east-1\n; keep me\nmfa_serial = serial\n\n[old]\nkey = value\n")
+		updated, err := ini.Load(original)
+		require.NoError(t, err)
+		updated.Section("default").DeleteKey("mfa_serial")
+		updated.DeleteSection("old")
+
+		patched, err := patchINI(original, updated)
+
+		require.NoError(t, err)
+		require.Equal(t, "[default]\nregion = us-east-1\n; keep me\n\n", string(patched))
+	})
+
+	t.Run("quote values containing comment characters", func(t *testing.T) {
+		original := []byte("[default]\ncredential_process = old\n")
+		updated, err := ini.Load(original)
+		require.NoError(t, err)
+		updated.Section("default").Key("credential_process").SetValue("get-credentials --profile prod # admin")
+
+		patched, err := patchINI(original, updated)
+
+		require.NoError(t, err)
+		require.Equal(t, "[default]\ncredential_process = `get-credentials --profile prod # admin`\n", string(patched))
+
+		reloaded, err := ini.Load(patched)
+		require.NoError(t, err)
+		require.Equal(t, "get-credentials --profile prod # admin", reloaded.Section("default").Key("credential_process").Value())
+	})
+
+	t.Run("replace multi-line value", func(t *testing.T) {
+		original := []byte("[default]\nnote = \"\"\"first\nsecond\"\"\"\nregion = us-east-1\n")
+		updated, err := ini.Load(original)
+		require.NoError(t, err)
+		updated.Section("default").Key("note").SetValue("single")
+
+		patched, err := patchINI(original, updated)
+
+		require.NoError(t, err)
+		require.Equal(t, "[default]\nnote = single\nregion = us-east-1\n", string(patched))
+	})
+
+	t.Run("return error when changed key is not found in original lines", func(t *testing.T) {
+		original := []byte("[ prod ]\nregion = us-east-1\n")
+		updated, err := ini.Load(original)
+		require.NoError(t, err)
+		updated.Section(" prod ").Key("region").SetValue("us-west-2")
+
+		_, err = patchINI(original, updated)
+
+		require.Error(t, err)
+	})
+
+	t.Run("return error when section of new key is not found in original lines", func(t *testing.T) {
+		original := []byte("[ prod ]\nregion = us-east-1\n")
+		updated, err := ini.Load(original)
+		require.NoError(t, err)
+		updated.Section(" prod ").Key("output").SetValue("json")
+
+		_, err = patchINI(original, updated)
+
+		require.Error(t, err)
+	})
+}
@@ -9,22 +9,56 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"time"
 )
 
+// WriteToFile writes file to given path. When the file exists, only lines of keys changed since it was read are
+// rewritten so that comments and formatting of hand-edited files are kept. The file is replaced atomically and its
+// previous content is backed up to ~/.aws-profile/backups
 func WriteToFile(file *ini.File, unexpandedFilePath string) error {
-	var buffer bytes.Buffer
-	_, err := file.WriteTo(&buffer)
+	return writeToFile(file, utils.ExpandHomeDirectory(unexpandedFilePath), utils.ExpandHomeDirectory(backupsDirectory), time.Now())
+}
+
+func writeToFile(file *ini.File, filePath string, backupDirectory string, now time.Time) error {
+	original, err := ioutil.ReadFile(filepath.Clean(filePath))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("fail to read file %s: %v", filePath, err)
+	}
 
+	exists := err == nil
+
+	content, err := fileContent(file, original, exists)
 	if err != nil {
-		return fmt.Errorf("fail to write to buffer: %v", err)
+		return err
+	}
+
+	if exists {
+		if bytes.Equal(original, content) {
+			return nil
+		}
+
+		if err := backupFile(filePath, original, backupDirectory, now); err != nil {
+			return err
+		}
+	}
+
+	return WriteFileAtomically(filePath, content)
+}
+
+func fileContent(file *ini.File, original []byte, exists bool) ([]byte, error) {
+	if exists {
+		if patched, err := patchINI(original, file); err == nil {
+			return patched, nil
+		}
 	}
 
-	filePath := utils.ExpandHomeDirectory(unexpandedFilePath)
-	if err = ioutil.WriteFile(filePath, buffer.Bytes(), 0600); err != nil {
-		return fmt.Errorf("fail to write to file %s: %v", filePath, err)
+	// new files, or files that can't be parsed any more, are written in full
+	var buffer bytes.Buffer
+	if _, err := file.WriteTo(&buffer); err != nil {
+		return nil, fmt.Errorf("fail to write to buffer: %v", err)
 	}
 
-	return nil
+	return buffer.Bytes(), nil
 }
 
 const awsProfileHome = "~/.aws-profile"
@@ -0,0 +1,62 @@
+package io
+
+import (
+	"errors"
+	"fmt"
+	"github.com/hpcsc/aws-profile/internal/utils"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	lockTimeout      = 10 * time.Second
+	lockPollInterval = 100 * time.Millisecond
+)
+
+var errLocked = errors.New("file is locked")
+
+// LockFile takes an exclusive advisory lock on given file, waiting while another aws-profile process holds it. The lock
+// is taken on <file>.lock since the file itself is replaced when it's written. Returned function releases the lock
+func LockFile(unexpandedFilePath string) (func() error, error) {
+	return lockFile(utils.ExpandHomeDirectory(unexpandedFilePath), lockTimeout)
+}
+
+func lockFile(filePath string, timeout time.Duration) (func() error, error) {
+	// file may not exist yet, e.g. credentials file written by export --to-profile on a new machine
+	if err := os.MkdirAll(filepath.Dir(filePath), os.FileMode(0700)); err != nil {
+		return nil, fmt.Errorf("fail to create directory of %s: %v", filePath, err)
+	}
+
+	lockPath := filepath.Clean(filePath + ".lock")
+	file, err := os.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, defaultFileMode)
+	if err != nil {
+		return nil, fmt.Errorf("fail to open lock file %s: %v", lockPath, err)
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		err = tryLock(file)
+		if err == nil {
+			break
+		}
+
+		if err != errLocked || time.Now().After(deadline) {
+			_ = file.Close()
+			if err == errLocked {
+				return nil, fmt.Errorf("timed out waiting for lock on %s, another aws-profile process is updating it", filePath)
+			}
+			return nil, fmt.Errorf("fail to lock %s: %v", lockPath, err)
+		}
+
+		time.Sleep(lockPollInterval)
+	}
+
+	return func() error {
+		unlockErr := unlock(file)
+		if closeErr := file.Close(); unlockErr == nil {
+			unlockErr = closeErr
+		}
+		return unlockErr
+	}, nil
+}
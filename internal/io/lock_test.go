@@ -0,0 +1,67 @@
+package io
+
+import (
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestLockFile(t *testing.T) {
+	setupFilePath := func(t *testing.T) (string, func()) {
+		directory, err := ioutil.TempDir("", "aws-profile-io")
+		require.NoError(t, err)
+		return filepath.Join(directory, "credentials"), func() { _ = os.RemoveAll(directory) }
+	}
+
+	t.Run("time out when file is locked by another holder", func(t *testing.T) {
+		filePath, cleanup := setupFilePath(t)
+		defer cleanup()
+		unlock, err := lockFile(filePath, time.Second)
+		require.NoError(t, err)
+		defer func() { _ = unlock() }()
+
+		_, err = lockFile(filePath, 200*time.Millisecond)
+
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "timed out waiting for lock")
+	})
+
+	t.Run("wait until file is unlocked by another holder", func(t *testing.T) {
+		filePath, cleanup := setupFilePath(t)
+		defer cleanup()
+		unlock, err := lockFile(filePath, time.Second)
+		require.NoError(t, err)
+
+		go func() {
+			time.Sleep(200 * time.Millisecond)
+			_ = unlock()
+		}()
+
+		secondUnlock, err := lockFile(filePath, 5*time.Second)
+
+		require.NoError(t, err)
+		require.NoError(t, secondUnlock())
+	})
+
+	t.Run("create missing parent directory only readable by current user", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("file permissions are not supported on windows")
+		}
+
+		filePath, cleanup := setupFilePath(t)
+		defer cleanup()
+		filePath = filepath.Join(filepath.Dir(filePath), ".aws", "credentials")
+
+		unlock, err := lockFile(filePath, time.Second)
+
+		require.NoError(t, err)
+		require.NoError(t, unlock())
+		info, err := os.Stat(filepath.Dir(filePath))
+		require.NoError(t, err)
+		require.Equal(t, os.FileMode(0700), info.Mode().Perm())
+	})
+}
@@ -0,0 +1,68 @@
+package mfa
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/hpcsc/aws-profile/internal/process"
+)
+
+const processTimeout = time.Minute
+
+// TokenProvider returns MFA token code, it has the same signature as token provider of stscreds.AssumeRoleProvider
+type TokenProvider func() (string, error)
+
+// Static returns given token, e.g. token given by --mfa-token flag
+func Static(token string) TokenProvider {
+	return func() (string, error) {
+		return token, nil
+	}
+}
+
+// Process returns token printed to stdout by given command line, e.g. a password manager CLI
+func Process(command string) TokenProvider {
+	return func() (string, error) {
+		return runProcess(command, processTimeout)
+	}
+}
+
+// Prompt asks user for token on terminal instead of stdin/stdout, which are redirected when running inside
+// eval $(aws-profile export)
+func Prompt(serialNumber string) TokenProvider {
+	return func() (string, error) {
+		input, output, closeTerminal := openTerminal()
+		defer closeTerminal()
+
+		return promptToken(input, output, serialNumber)
+	}
+}
+
+func runProcess(command string, timeout time.Duration) (string, error) {
+	// stderr is passed through so that the process can prompt user, e.g. to unlock password manager
+	stdout, err := process.RunShellCommand("mfa process", command, nil, os.Stderr, timeout)
+	if err != nil {
+		return "", err
+	}
+
+	token := strings.TrimSpace(string(stdout))
+	if token == "" {
+		return "", fmt.Errorf("mfa process [%s] printed no token", command)
+	}
+
+	return token, nil
+}
+
+func promptToken(input io.Reader, output io.Writer, serialNumber string) (string, error) {
+	_, _ = fmt.Fprintf(output, "MFA token code for %s: ", serialNumber)
+
+	line, err := bufio.NewReader(input).ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", fmt.Errorf("failed to read MFA token code: %v", err)
+	}
+
+	return strings.TrimSpace(line), nil
+}
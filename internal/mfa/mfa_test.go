@@ -0,0 +1,79 @@
+package mfa
+
+import (
+	"bytes"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatic(t *testing.T) {
+	t.Run("return given token", func(t *testing.T) {
+		token, err := Static("123456")()
+
+		require.NoError(t, err)
+		require.Equal(t, "123456", token)
+	})
+}
+
+func TestRunProcess(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test commands require sh")
+	}
+
+	t.Run("return trimmed output of command", func(t *testing.T) {
+		token, err := runProcess("echo ' 123456 '", time.Minute)
+
+		require.NoError(t, err)
+		require.Equal(t, "123456", token)
+	})
+
+	t.Run("return error if command fails", func(t *testing.T) {
+		_, err := runProcess("exit 1", time.Minute)
+
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "mfa process [exit 1] failed")
+	})
+
+	t.Run("return error if command prints nothing", func(t *testing.T) {
+		_, err := runProcess("true", time.Minute)
+
+		require.Error(t, err)
+		require.Equal(t, "mfa process [true] printed no token", err.Error())
+	})
+
+	t.Run("return error if command times out", func(t *testing.T) {
+		_, err := runProcess("sleep 1", 10*time.Millisecond)
+
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "timed out")
+	})
+}
+
+func TestPromptToken(t *testing.T) {
+	t.Run("write prompt with serial number and return entered token", func(t *testing.T) {
+		var output bytes.Buffer
+
+		token, err := promptToken(strings.NewReader("123456\n"), &output, "arn:aws:iam::123456789012:mfa/user")
+
+		require.NoError(t, err)
+		require.Equal(t, "123456", token)
+		require.Equal(t, "MFA token code for arn:aws:iam::123456789012:mfa/user: ", output.String())
+	})
+
+	t.Run("return token entered without new line", func(t *testing.T) {
+		token, err := promptToken(strings.NewReader("123456"), &bytes.Buffer{}, "serial")
+
+		require.NoError(t, err)
+		require.Equal(t, "123456", token)
+	})
+
+	t.Run("return error if nothing is entered", func(t *testing.T) {
+		_, err := promptToken(strings.NewReader(""), &bytes.Buffer{}, "serial")
+
+		require.Error(t, err)
+	})
+}
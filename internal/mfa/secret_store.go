@@ -0,0 +1,97 @@
+package mfa
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/hpcsc/aws-profile/internal/encryption"
+	"github.com/hpcsc/aws-profile/internal/utils"
+)
+
+const (
+	secretDirectory     = "~/.aws-profile/mfa"
+	secretKeyFile       = "~/.aws-profile/mfa.key"
+	secretFileExtension = ".bin"
+)
+
+// SecretStore keeps TOTP secrets of virtual MFA devices encrypted, one file per MFA device
+type SecretStore struct {
+	directory   string
+	keyFilePath string
+}
+
+func NewSecretStore(directory string, keyFilePath string) *SecretStore {
+	return &SecretStore{
+		directory:   directory,
+		keyFilePath: keyFilePath,
+	}
+}
+
+func NewDefaultSecretStore() *SecretStore {
+	return NewSecretStore(utils.ExpandHomeDirectory(secretDirectory), utils.ExpandHomeDirectory(secretKeyFile))
+}
+
+func (store *SecretStore) Write(serialNumber string, secret string) error {
+	if _, err := decodeSecret(secret); err != nil {
+		return err
+	}
+
+	key, err := encryption.LoadOrCreateKey(store.keyFilePath)
+	if err != nil {
+		return err
+	}
+
+	encrypted, err := encryption.Encrypt(key, []byte(secret))
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(store.directory, os.FileMode(0700)); err != nil {
+		return fmt.Errorf("failed to create mfa secret directory: %v", err)
+	}
+
+	if err := ioutil.WriteFile(store.secretFilePath(serialNumber), encrypted, os.FileMode(0600)); err != nil {
+		return fmt.Errorf("failed to write TOTP secret of %s: %v", serialNumber, err)
+	}
+
+	return nil
+}
+
+func (store *SecretStore) Read(serialNumber string) (string, error) {
+	encrypted, err := ioutil.ReadFile(filepath.Clean(store.secretFilePath(serialNumber)))
+	if os.IsNotExist(err) {
+		return "", fmt.Errorf("no TOTP secret stored for %s, run \"aws-profile mfa set-totp-secret %s\" first", serialNumber, serialNumber)
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("failed to read TOTP secret of %s: %v", serialNumber, err)
+	}
+
+	// secret encrypted with a key that has been removed can't be read, it has to be stored again
+	key, err := encryption.LoadKey(store.keyFilePath)
+	if errors.Is(err, encryption.ErrKeyNotFound) {
+		return "", fmt.Errorf("encryption key of TOTP secret of %s not found, run \"aws-profile mfa set-totp-secret %s\" again", serialNumber, serialNumber)
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	secret, err := encryption.Decrypt(key, encrypted)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt TOTP secret of %s: %v", serialNumber, err)
+	}
+
+	return string(secret), nil
+}
+
+// serial numbers are arns containing characters not allowed in file names
+func (store *SecretStore) secretFilePath(serialNumber string) string {
+	hash := sha256.Sum256([]byte(serialNumber))
+	return filepath.Join(store.directory, hex.EncodeToString(hash[:])+secretFileExtension)
+}
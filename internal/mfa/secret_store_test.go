@@ -0,0 +1,92 @@
+package mfa
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const stubSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func setupSecretStore(t *testing.T) (*SecretStore, func()) {
+	directory, err := ioutil.TempDir("", "aws-profile-mfa")
+	require.NoError(t, err)
+
+	store := NewSecretStore(filepath.Join(directory, "mfa"), filepath.Join(directory, "mfa.key"))
+
+	return store, func() {
+		_ = os.RemoveAll(directory)
+	}
+}
+
+func TestSecretStore(t *testing.T) {
+	t.Run("return written secret", func(t *testing.T) {
+		store, cleanup := setupSecretStore(t)
+		defer cleanup()
+
+		require.NoError(t, store.Write("arn:aws:iam::123456789012:mfa/user", stubSecret))
+		secret, err := store.Read("arn:aws:iam::123456789012:mfa/user")
+
+		require.NoError(t, err)
+		require.Equal(t, stubSecret, secret)
+	})
+
+	t.Run("write secret encrypted", func(t *testing.T) {
+		store, cleanup := setupSecretStore(t)
+		defer cleanup()
+
+		require.NoError(t, store.Write("arn:aws:iam::123456789012:mfa/user", stubSecret))
+		content, err := ioutil.ReadFile(store.secretFilePath("arn:aws:iam::123456789012:mfa/user"))
+
+		require.NoError(t, err)
+		require.NotContains(t, string(content), stubSecret)
+	})
+
+	t.Run("return error asking to store secret if secret is not stored", func(t *testing.T) {
+		store, cleanup := setupSecretStore(t)
+		defer cleanup()
+
+		_, err := store.Read("arn:aws:iam::123456789012:mfa/user")
+
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "run \"aws-profile mfa set-totp-secret arn:aws:iam::123456789012:mfa/user\" first")
+	})
+
+	t.Run("return error without creating key if key does not exist", func(t *testing.T) {
+		store, cleanup := setupSecretStore(t)
+		defer cleanup()
+		require.NoError(t, store.Write("arn:aws:iam::123456789012:mfa/user", stubSecret))
+		require.NoError(t, os.Remove(store.keyFilePath))
+
+		_, err := store.Read("arn:aws:iam::123456789012:mfa/user")
+
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "run \"aws-profile mfa set-totp-secret arn:aws:iam::123456789012:mfa/user\" again")
+		_, err = os.Stat(store.keyFilePath)
+		require.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("refuse to write invalid secret", func(t *testing.T) {
+		store, cleanup := setupSecretStore(t)
+		defer cleanup()
+
+		require.Error(t, store.Write("arn:aws:iam::123456789012:mfa/user", "not-base32!"))
+	})
+}
+
+func TestTOTP(t *testing.T) {
+	t.Run("generate token from stored secret", func(t *testing.T) {
+		store, cleanup := setupSecretStore(t)
+		defer cleanup()
+		require.NoError(t, store.Write("arn:aws:iam::123456789012:mfa/user", stubSecret))
+
+		token, err := totp(store, "arn:aws:iam::123456789012:mfa/user", func() time.Time { return time.Unix(59, 0) })()
+
+		require.NoError(t, err)
+		require.Equal(t, "287082", token)
+	})
+}
@@ -0,0 +1,31 @@
+package mfa
+
+import (
+	"io"
+	"os"
+	"runtime"
+)
+
+// openTerminal opens controlling terminal for reading and writing, falling back to stdin and stderr when there is none
+func openTerminal() (io.Reader, io.Writer, func()) {
+	inputName, outputName := "/dev/tty", "/dev/tty"
+	if runtime.GOOS == "windows" {
+		inputName, outputName = "CONIN$", "CONOUT$"
+	}
+
+	input, err := os.OpenFile(inputName, os.O_RDWR, 0)
+	if err != nil {
+		return os.Stdin, os.Stderr, func() {}
+	}
+
+	output, err := os.OpenFile(outputName, os.O_RDWR, 0)
+	if err != nil {
+		_ = input.Close()
+		return os.Stdin, os.Stderr, func() {}
+	}
+
+	return input, output, func() {
+		_ = input.Close()
+		_ = output.Close()
+	}
+}
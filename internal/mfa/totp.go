@@ -0,0 +1,78 @@
+package mfa
+
+import (
+	"crypto/hmac"
+	"crypto/sha1" // #nosec
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// parameters used by virtual MFA devices supported by AWS
+const (
+	totpPeriod = 30
+	totpDigits = 6
+)
+
+// TOTP generates token from secret of given MFA device stored in secret store
+func TOTP(store *SecretStore, serialNumber string) TokenProvider {
+	return totp(store, serialNumber, time.Now)
+}
+
+func totp(store *SecretStore, serialNumber string, now func() time.Time) TokenProvider {
+	return func() (string, error) {
+		secret, err := store.Read(serialNumber)
+		if err != nil {
+			return "", err
+		}
+
+		return GenerateTOTP(secret, now())
+	}
+}
+
+// GenerateTOTP generates RFC 6238 token from base32 secret shown when a virtual MFA device is set up
+func GenerateTOTP(secret string, at time.Time) (string, error) {
+	key, err := decodeSecret(secret)
+	if err != nil {
+		return "", err
+	}
+
+	return generateTOTP(key, at), nil
+}
+
+func generateTOTP(key []byte, at time.Time) string {
+	counter := make([]byte, 8)
+	binary.BigEndian.PutUint64(counter, uint64(at.Unix()/totpPeriod))
+
+	mac := hmac.New(sha1.New, key)
+	_, _ = mac.Write(counter)
+	sum := mac.Sum(nil)
+
+	// dynamic truncation of RFC 4226
+	offset := sum[len(sum)-1] & 0x0f
+	code := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	modulo := uint32(1)
+	for i := 0; i < totpDigits; i++ {
+		modulo *= 10
+	}
+
+	return fmt.Sprintf("%0*d", totpDigits, code%modulo)
+}
+
+// secrets are often shown in groups separated by spaces and without padding
+func decodeSecret(secret string) ([]byte, error) {
+	normalized := strings.ToUpper(strings.TrimRight(strings.Join(strings.Fields(secret), ""), "="))
+	if normalized == "" {
+		return nil, fmt.Errorf("TOTP secret is empty")
+	}
+
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(normalized)
+	if err != nil {
+		return nil, fmt.Errorf("TOTP secret is not valid base32: %v", err)
+	}
+
+	return key, nil
+}
@@ -0,0 +1,43 @@
+package mfa
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateTOTP(t *testing.T) {
+	// test vectors of RFC 6238 for SHA1, truncated to 6 digits
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+	t.Run("generate tokens of RFC 6238 test vectors", func(t *testing.T) {
+		for unixTime, expected := range map[int64]string{
+			59:         "287082",
+			1111111109: "081804",
+			1111111111: "050471",
+			1234567890: "005924",
+			2000000000: "279037",
+		} {
+			token, err := GenerateTOTP(secret, time.Unix(unixTime, 0))
+
+			require.NoError(t, err)
+			require.Equal(t, expected, token)
+		}
+	})
+
+	t.Run("accept lower case secret in groups without padding", func(t *testing.T) {
+		token, err := GenerateTOTP("gezd gnbv gy3t qojq gezd gnbv gy3t qojq", time.Unix(59, 0))
+
+		require.NoError(t, err)
+		require.Equal(t, "287082", token)
+	})
+
+	t.Run("return error if secret is not base32", func(t *testing.T) {
+		_, err := GenerateTOTP("not-base32!", time.Unix(59, 0))
+
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "TOTP secret is not valid base32")
+	})
+}
@@ -0,0 +1,72 @@
+package process
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+)
+
+// ErrCommandNotFound is returned by Run when command doesn't exist or is not in PATH
+var ErrCommandNotFound = errors.New("command not found")
+
+var forwardedSignals = []os.Signal{
+	os.Interrupt,
+	syscall.SIGTERM,
+	syscall.SIGHUP,
+	syscall.SIGQUIT,
+}
+
+// Run runs command with given environment variables, attached to current stdin/stdout/stderr, and returns its exit code.
+// Signals received by aws-profile are forwarded to the command so that it can clean up before exiting
+func Run(name string, args []string, env []string) (int, error) {
+	cmd := exec.Command(name, args...) // #nosec
+	cmd.Env = env
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, forwardedSignals...)
+
+	if err := cmd.Start(); err != nil {
+		signal.Stop(signals)
+		if errors.Is(err, exec.ErrNotFound) || os.IsNotExist(err) {
+			return 0, fmt.Errorf("failed to start %s: %w", name, ErrCommandNotFound)
+		}
+
+		return 0, fmt.Errorf("failed to start %s: %v", name, err)
+	}
+
+	go func() {
+		for sig := range signals {
+			_ = cmd.Process.Signal(sig)
+		}
+	}()
+
+	err := cmd.Wait()
+	signal.Stop(signals)
+	close(signals)
+
+	return exitCode(err)
+}
+
+func exitCode(err error) (int, error) {
+	if err == nil {
+		return 0, nil
+	}
+
+	exitErr, ok := err.(*exec.ExitError)
+	if !ok {
+		return 0, err
+	}
+
+	// follow shell convention of 128 + signal number for commands killed by signal
+	if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return 128 + int(status.Signal()), nil
+	}
+
+	return exitErr.ExitCode(), nil
+}
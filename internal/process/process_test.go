@@ -0,0 +1,46 @@
+package process
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	t.Run("return zero exit code if command succeeds", func(t *testing.T) {
+		exitCode, err := Run("sh", []string{"-c", "exit 0"}, nil)
+
+		require.NoError(t, err)
+		require.Equal(t, 0, exitCode)
+	})
+
+	t.Run("return exit code of command", func(t *testing.T) {
+		exitCode, err := Run("sh", []string{"-c", "exit 3"}, nil)
+
+		require.NoError(t, err)
+		require.Equal(t, 3, exitCode)
+	})
+
+	t.Run("run command with given environment variables only", func(t *testing.T) {
+		exitCode, err := Run("sh", []string{"-c", `test "$AWS_ACCESS_KEY_ID" = "access-key-id" && test -z "$AWS_PROFILE"`}, []string{"AWS_ACCESS_KEY_ID=access-key-id"})
+
+		require.NoError(t, err)
+		require.Equal(t, 0, exitCode)
+	})
+
+	t.Run("return 128 plus signal number if command is killed by signal", func(t *testing.T) {
+		exitCode, err := Run("sh", []string{"-c", "kill -TERM $$"}, nil)
+
+		require.NoError(t, err)
+		require.Equal(t, 143, exitCode)
+	})
+
+	t.Run("return error if command does not exist", func(t *testing.T) {
+		_, err := Run("command-not-exists", nil, nil)
+
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to start command-not-exists")
+		require.True(t, errors.Is(err, ErrCommandNotFound))
+	})
+}
@@ -0,0 +1,54 @@
+package process
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"runtime"
+	"time"
+)
+
+// ShellCommand returns command executing given command line by shell, the same way AWS CLI runs credential_process
+func ShellCommand(ctx context.Context, command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.CommandContext(ctx, "cmd.exe", "/C", command) // #nosec
+	}
+
+	return exec.CommandContext(ctx, "sh", "-c", command) // #nosec
+}
+
+// RunShellCommand runs given command line by shell and returns what it prints to stdout, failing if it doesn't finish
+// within timeout. Name describes the command in errors, e.g. "credential process"
+func RunShellCommand(name string, command string, stdin io.Reader, stderr io.Writer, timeout time.Duration) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var stdout bytes.Buffer
+	cmd := ShellCommand(ctx, command)
+	cmd.Stdin = stdin
+	cmd.Stdout = &stdout
+	cmd.Stderr = stderr
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start %s [%s]: %v", name, command, err)
+	}
+
+	// children of the shell can keep stdout open after the shell is killed, don't wait for them when timed out
+	waitResult := make(chan error, 1)
+	go func() {
+		waitResult <- cmd.Wait()
+	}()
+
+	select {
+	case <-ctx.Done():
+		return nil, fmt.Errorf("%s [%s] timed out after %s", name, command, timeout)
+	case err := <-waitResult:
+		if err != nil {
+			return nil, fmt.Errorf("%s [%s] failed: %v", name, command, err)
+		}
+	}
+
+	return stdout.Bytes(), nil
+}
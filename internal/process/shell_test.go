@@ -0,0 +1,32 @@
+package process
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunShellCommand(t *testing.T) {
+	t.Run("return stdout of command", func(t *testing.T) {
+		stdout, err := RunShellCommand("test process", "echo output", nil, os.Stderr, time.Minute)
+
+		require.NoError(t, err)
+		require.Equal(t, "output\n", string(stdout))
+	})
+
+	t.Run("return error with name of command if command fails", func(t *testing.T) {
+		_, err := RunShellCommand("test process", "exit 3", nil, os.Stderr, time.Minute)
+
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "test process [exit 3] failed")
+	})
+
+	t.Run("return error if command does not finish within timeout", func(t *testing.T) {
+		_, err := RunShellCommand("test process", "sleep 1", nil, os.Stderr, 100*time.Millisecond)
+
+		require.Error(t, err)
+		require.Equal(t, "test process [sleep 1] timed out after 100ms", err.Error())
+	})
+}
@@ -0,0 +1,53 @@
+package server
+
+import (
+	"sync"
+	"time"
+
+	"github.com/hpcsc/aws-profile/internal/aws"
+)
+
+type CredentialsFn func() (aws.Credentials, error)
+
+// RefreshingCredentials keeps credentials in memory and gets new credentials when they are about to expire
+type RefreshingCredentials struct {
+	mutex          sync.Mutex
+	getCredentials CredentialsFn
+	refreshWindow  time.Duration
+	now            func() time.Time
+	current        *aws.Credentials
+}
+
+func NewRefreshingCredentials(getCredentials CredentialsFn, refreshWindow time.Duration) *RefreshingCredentials {
+	return &RefreshingCredentials{
+		getCredentials: getCredentials,
+		refreshWindow:  refreshWindow,
+		now:            time.Now,
+	}
+}
+
+// Get returns current credentials, credentials without expiration are never refreshed
+func (c *RefreshingCredentials) Get() (aws.Credentials, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.current != nil && !c.needsRefresh() {
+		return *c.current, nil
+	}
+
+	credentials, err := c.getCredentials()
+	if err != nil {
+		return aws.Credentials{}, err
+	}
+
+	c.current = &credentials
+	return credentials, nil
+}
+
+func (c *RefreshingCredentials) needsRefresh() bool {
+	if c.current.Expiration.IsZero() {
+		return false
+	}
+
+	return !c.current.Expiration.After(c.now().Add(c.refreshWindow))
+}
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/hpcsc/aws-profile/internal/aws"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRefreshingCredentials(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	newCredentialsFn := func(expirations ...time.Time) (CredentialsFn, *int) {
+		called := 0
+		return func() (aws.Credentials, error) {
+			expiration := expirations[called]
+			called++
+			return aws.Credentials{
+				Value:      credentials.Value{AccessKeyID: fmt.Sprintf("access-key-id-%d", called)},
+				Expiration: expiration,
+			}, nil
+		}, &called
+	}
+
+	newRefreshingCredentials := func(getCredentials CredentialsFn) *RefreshingCredentials {
+		refreshingCredentials := NewRefreshingCredentials(getCredentials, 5*time.Minute)
+		refreshingCredentials.now = func() time.Time {
+			return now
+		}
+		return refreshingCredentials
+	}
+
+	t.Run("reuse credentials that are not about to expire", func(t *testing.T) {
+		getCredentials, called := newCredentialsFn(now.Add(time.Hour))
+		refreshingCredentials := newRefreshingCredentials(getCredentials)
+
+		_, _ = refreshingCredentials.Get()
+		second, err := refreshingCredentials.Get()
+
+		require.NoError(t, err)
+		require.Equal(t, "access-key-id-1", second.AccessKeyID)
+		require.Equal(t, 1, *called)
+	})
+
+	t.Run("get new credentials if current credentials expire within refresh window", func(t *testing.T) {
+		getCredentials, called := newCredentialsFn(now.Add(4*time.Minute), now.Add(time.Hour))
+		refreshingCredentials := newRefreshingCredentials(getCredentials)
+
+		_, _ = refreshingCredentials.Get()
+		second, err := refreshingCredentials.Get()
+
+		require.NoError(t, err)
+		require.Equal(t, "access-key-id-2", second.AccessKeyID)
+		require.Equal(t, 2, *called)
+	})
+
+	t.Run("never refresh credentials without expiration", func(t *testing.T) {
+		getCredentials, called := newCredentialsFn(time.Time{})
+		refreshingCredentials := newRefreshingCredentials(getCredentials)
+
+		_, _ = refreshingCredentials.Get()
+		_, err := refreshingCredentials.Get()
+
+		require.NoError(t, err)
+		require.Equal(t, 1, *called)
+	})
+
+	t.Run("return error and retry on next call if failed to get credentials", func(t *testing.T) {
+		called := 0
+		refreshingCredentials := newRefreshingCredentials(func() (aws.Credentials, error) {
+			called++
+			if called == 1 {
+				return aws.Credentials{}, errors.New("AccessDenied")
+			}
+			return aws.Credentials{Value: credentials.Value{AccessKeyID: "access-key-id"}}, nil
+		})
+
+		_, err := refreshingCredentials.Get()
+		require.Error(t, err)
+
+		second, err := refreshingCredentials.Get()
+		require.NoError(t, err)
+		require.Equal(t, "access-key-id", second.AccessKeyID)
+	})
+}
@@ -0,0 +1,61 @@
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// ecsCredentials has the same format as the response of ECS container credentials endpoint
+type ecsCredentials struct {
+	AccessKeyId     string `json:"AccessKeyId"`
+	SecretAccessKey string `json:"SecretAccessKey"`
+	Token           string `json:"Token,omitempty"`
+	Expiration      string `json:"Expiration,omitempty"`
+}
+
+type ecsError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// NewECSHandler returns handler for AWS SDKs configured with AWS_CONTAINER_CREDENTIALS_FULL_URI and
+// AWS_CONTAINER_AUTHORIZATION_TOKEN. Requests without the same authorization token are rejected
+func NewECSHandler(authorizationToken string, credentials *RefreshingCredentials) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeECSResponse(w, http.StatusMethodNotAllowed, ecsError{Code: "MethodNotAllowed", Message: "only GET is supported"})
+			return
+		}
+
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte(authorizationToken)) != 1 {
+			writeECSResponse(w, http.StatusUnauthorized, ecsError{Code: "Unauthorized", Message: "invalid authorization token"})
+			return
+		}
+
+		current, err := credentials.Get()
+		if err != nil {
+			writeECSResponse(w, http.StatusInternalServerError, ecsError{Code: "CredentialsError", Message: err.Error()})
+			return
+		}
+
+		response := ecsCredentials{
+			AccessKeyId:     current.AccessKeyID,
+			SecretAccessKey: current.SecretAccessKey,
+			Token:           current.SessionToken,
+		}
+
+		if !current.Expiration.IsZero() {
+			response.Expiration = current.Expiration.UTC().Format(time.RFC3339)
+		}
+
+		writeECSResponse(w, http.StatusOK, response)
+	})
+}
+
+func writeECSResponse(w http.ResponseWriter, statusCode int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,93 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/endpointcreds"
+	"github.com/aws/aws-sdk-go/aws/defaults"
+	"github.com/hpcsc/aws-profile/internal/aws"
+	"github.com/stretchr/testify/require"
+)
+
+func stubCredentials(expiration time.Time) *RefreshingCredentials {
+	return NewRefreshingCredentials(func() (aws.Credentials, error) {
+		return aws.Credentials{
+			Value: credentials.Value{
+				AccessKeyID:     "access-key-id",
+				SecretAccessKey: "secret-access-key",
+				SessionToken:    "session-token",
+			},
+			Expiration: expiration,
+		}, nil
+	}, 0)
+}
+
+func newEndpointCredentialsProvider(url string, authorizationToken string) credentials.Provider {
+	return endpointcreds.NewProviderClient(*defaults.Config().WithMaxRetries(0), defaults.Handlers(), url, func(p *endpointcreds.Provider) {
+		p.AuthorizationToken = authorizationToken
+	})
+}
+
+func TestECSHandler(t *testing.T) {
+	expiration := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+
+	t.Run("return credentials readable by AWS SDK endpoint credentials provider", func(t *testing.T) {
+		server := httptest.NewServer(NewECSHandler("token", stubCredentials(expiration)))
+		defer server.Close()
+
+		provider := newEndpointCredentialsProvider(server.URL, "token")
+		value, err := provider.Retrieve()
+
+		require.NoError(t, err)
+		require.Equal(t, "access-key-id", value.AccessKeyID)
+		require.Equal(t, "secret-access-key", value.SecretAccessKey)
+		require.Equal(t, "session-token", value.SessionToken)
+		require.False(t, provider.IsExpired())
+	})
+
+	t.Run("reject request with invalid authorization token", func(t *testing.T) {
+		server := httptest.NewServer(NewECSHandler("token", stubCredentials(expiration)))
+		defer server.Close()
+
+		_, err := newEndpointCredentialsProvider(server.URL, "another-token").Retrieve()
+
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid authorization token")
+	})
+
+	t.Run("reject request without authorization token", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		NewECSHandler("token", stubCredentials(expiration)).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		require.Equal(t, http.StatusUnauthorized, recorder.Code)
+	})
+
+	t.Run("return error if failed to get credentials", func(t *testing.T) {
+		failingCredentials := NewRefreshingCredentials(func() (aws.Credentials, error) {
+			return aws.Credentials{}, errors.New("AccessDenied")
+		}, 0)
+
+		recorder := httptest.NewRecorder()
+		request := httptest.NewRequest(http.MethodGet, "/", nil)
+		request.Header.Set("Authorization", "token")
+		NewECSHandler("token", failingCredentials).ServeHTTP(recorder, request)
+
+		require.Equal(t, http.StatusInternalServerError, recorder.Code)
+		require.JSONEq(t, `{"code":"CredentialsError","message":"AccessDenied"}`, recorder.Body.String())
+	})
+
+	t.Run("omit expiration for credentials that never expire", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		request := httptest.NewRequest(http.MethodGet, "/", nil)
+		request.Header.Set("Authorization", "token")
+		NewECSHandler("token", stubCredentials(time.Time{})).ServeHTTP(recorder, request)
+
+		require.Equal(t, http.StatusOK, recorder.Code)
+		require.JSONEq(t, `{"AccessKeyId":"access-key-id","SecretAccessKey":"secret-access-key","Token":"session-token"}`, recorder.Body.String())
+	})
+}
@@ -0,0 +1,155 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	imdsTokenPath               = "/latest/api/token"
+	imdsSecurityCredentialsPath = "/latest/meta-data/iam/security-credentials/"
+	imdsTokenHeader             = "X-aws-ec2-metadata-token"
+	imdsTokenTTLHeader          = "X-aws-ec2-metadata-token-ttl-seconds"
+	imdsMaxTokenTTL             = 21600
+	// IMDS requires an expiration, credentials without expiration are reported as valid for this long
+	imdsStaticCredentialsTTL = time.Hour
+)
+
+// imdsCredentials has the same format as the response of EC2 instance metadata security credentials endpoint
+type imdsCredentials struct {
+	Code            string `json:"Code"`
+	LastUpdated     string `json:"LastUpdated"`
+	Type            string `json:"Type"`
+	AccessKeyId     string `json:"AccessKeyId"`
+	SecretAccessKey string `json:"SecretAccessKey"`
+	Token           string `json:"Token"`
+	Expiration      string `json:"Expiration"`
+}
+
+type imdsTokens struct {
+	mutex  sync.Mutex
+	tokens map[string]time.Time
+	now    func() time.Time
+}
+
+func (t *imdsTokens) issue(ttl time.Duration) (string, error) {
+	token, err := GenerateToken()
+	if err != nil {
+		return "", err
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	for existing, expiresAt := range t.tokens {
+		if !expiresAt.After(t.now()) {
+			delete(t.tokens, existing)
+		}
+	}
+
+	t.tokens[token] = t.now().Add(ttl)
+	return token, nil
+}
+
+func (t *imdsTokens) isValid(token string) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	expiresAt, ok := t.tokens[token]
+	return ok && expiresAt.After(t.now())
+}
+
+// NewIMDSHandler returns handler implementing IMDSv2 session token and security credentials endpoints for AWS SDKs
+// configured with AWS_EC2_METADATA_SERVICE_ENDPOINT. Credentials are returned as credentials of given role name
+func NewIMDSHandler(roleName string, credentials *RefreshingCredentials) http.Handler {
+	return newIMDSHandler(roleName, credentials, time.Now)
+}
+
+func newIMDSHandler(roleName string, credentials *RefreshingCredentials, now func() time.Time) http.Handler {
+	tokens := &imdsTokens{
+		tokens: map[string]time.Time{},
+		now:    now,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(imdsTokenPath, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			http.Error(w, "", http.StatusMethodNotAllowed)
+			return
+		}
+
+		// same as EC2, reject requests forwarded by proxies
+		if r.Header.Get("X-Forwarded-For") != "" {
+			http.Error(w, "", http.StatusForbidden)
+			return
+		}
+
+		ttl, err := strconv.Atoi(r.Header.Get(imdsTokenTTLHeader))
+		if err != nil || ttl < 1 || ttl > imdsMaxTokenTTL {
+			http.Error(w, "", http.StatusBadRequest)
+			return
+		}
+
+		token, err := tokens.issue(time.Duration(ttl) * time.Second)
+		if err != nil {
+			http.Error(w, "", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set(imdsTokenTTLHeader, strconv.Itoa(ttl))
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte(token))
+	})
+
+	mux.HandleFunc(imdsSecurityCredentialsPath, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "", http.StatusMethodNotAllowed)
+			return
+		}
+
+		if !tokens.isValid(r.Header.Get(imdsTokenHeader)) {
+			http.Error(w, "", http.StatusUnauthorized)
+			return
+		}
+
+		switch strings.TrimPrefix(r.URL.Path, imdsSecurityCredentialsPath) {
+		case "":
+			w.Header().Set("Content-Type", "text/plain")
+			_, _ = w.Write([]byte(roleName))
+		case roleName:
+			writeIMDSCredentials(w, credentials, now)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	return mux
+}
+
+func writeIMDSCredentials(w http.ResponseWriter, credentials *RefreshingCredentials, now func() time.Time) {
+	current, err := credentials.Get()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	expiration := current.Expiration
+	if expiration.IsZero() {
+		expiration = now().Add(imdsStaticCredentialsTTL)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(imdsCredentials{
+		Code:            "Success",
+		LastUpdated:     now().UTC().Format(time.RFC3339),
+		Type:            "AWS-HMAC",
+		AccessKeyId:     current.AccessKeyID,
+		SecretAccessKey: current.SecretAccessKey,
+		Token:           current.SessionToken,
+		Expiration:      expiration.UTC().Format(time.RFC3339),
+	})
+}
@@ -0,0 +1,141 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	awssdk "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials/ec2rolecreds"
+	"github.com/aws/aws-sdk-go/aws/ec2metadata"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/stretchr/testify/require"
+)
+
+func newEC2RoleCredentialsProvider(url string) *ec2rolecreds.EC2RoleProvider {
+	sess := session.Must(session.NewSession(awssdk.NewConfig().WithEndpoint(url).WithRegion("us-east-1").WithMaxRetries(0)))
+	return &ec2rolecreds.EC2RoleProvider{Client: ec2metadata.New(sess)}
+}
+
+func requestIMDSToken(t *testing.T, handler http.Handler, ttl string) string {
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPut, imdsTokenPath, nil)
+	request.Header.Set(imdsTokenTTLHeader, ttl)
+	handler.ServeHTTP(recorder, request)
+
+	require.Equal(t, http.StatusOK, recorder.Code)
+	return recorder.Body.String()
+}
+
+func getIMDSPath(handler http.Handler, path string, token string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, path, nil)
+	request.Header.Set(imdsTokenHeader, token)
+	handler.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestIMDSHandler(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	nowFn := func() time.Time {
+		return now
+	}
+
+	t.Run("return credentials readable by AWS SDK EC2 role credentials provider", func(t *testing.T) {
+		expiration := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+		server := httptest.NewServer(NewIMDSHandler("my-role", stubCredentials(expiration)))
+		defer server.Close()
+
+		// endpoint of EC2 metadata client of this SDK version includes "/latest"
+		provider := newEC2RoleCredentialsProvider(server.URL + "/latest")
+		value, err := provider.Retrieve()
+
+		require.NoError(t, err)
+		require.Equal(t, "access-key-id", value.AccessKeyID)
+		require.Equal(t, "secret-access-key", value.SecretAccessKey)
+		require.Equal(t, "session-token", value.SessionToken)
+		require.False(t, provider.IsExpired())
+	})
+
+	t.Run("list role name", func(t *testing.T) {
+		handler := newIMDSHandler("my-role", stubCredentials(time.Time{}), nowFn)
+		token := requestIMDSToken(t, handler, "60")
+
+		recorder := getIMDSPath(handler, imdsSecurityCredentialsPath, token)
+
+		require.Equal(t, http.StatusOK, recorder.Code)
+		require.Equal(t, "my-role", recorder.Body.String())
+	})
+
+	t.Run("return not found for unknown role name", func(t *testing.T) {
+		handler := newIMDSHandler("my-role", stubCredentials(time.Time{}), nowFn)
+		token := requestIMDSToken(t, handler, "60")
+
+		recorder := getIMDSPath(handler, imdsSecurityCredentialsPath+"another-role", token)
+
+		require.Equal(t, http.StatusNotFound, recorder.Code)
+	})
+
+	t.Run("report credentials without expiration as valid for an hour", func(t *testing.T) {
+		handler := newIMDSHandler("my-role", stubCredentials(time.Time{}), nowFn)
+		token := requestIMDSToken(t, handler, "60")
+
+		recorder := getIMDSPath(handler, imdsSecurityCredentialsPath+"my-role", token)
+
+		require.Equal(t, http.StatusOK, recorder.Code)
+		require.JSONEq(t, `{
+			"Code": "Success",
+			"LastUpdated": "2020-01-01T00:00:00Z",
+			"Type": "AWS-HMAC",
+			"AccessKeyId": "access-key-id",
+			"SecretAccessKey": "secret-access-key",
+			"Token": "session-token",
+			"Expiration": "2020-01-01T01:00:00Z"
+		}`, recorder.Body.String())
+	})
+
+	t.Run("reject request without session token", func(t *testing.T) {
+		handler := newIMDSHandler("my-role", stubCredentials(time.Time{}), nowFn)
+
+		recorder := getIMDSPath(handler, imdsSecurityCredentialsPath+"my-role", "")
+
+		require.Equal(t, http.StatusUnauthorized, recorder.Code)
+	})
+
+	t.Run("reject request with expired session token", func(t *testing.T) {
+		handler := newIMDSHandler("my-role", stubCredentials(time.Time{}), nowFn)
+		token := requestIMDSToken(t, handler, "60")
+
+		now = now.Add(time.Minute)
+		defer func() { now = now.Add(-time.Minute) }()
+		recorder := getIMDSPath(handler, imdsSecurityCredentialsPath+"my-role", token)
+
+		require.Equal(t, http.StatusUnauthorized, recorder.Code)
+	})
+
+	t.Run("reject session token request with invalid ttl", func(t *testing.T) {
+		handler := newIMDSHandler("my-role", stubCredentials(time.Time{}), nowFn)
+
+		for _, ttl := range []string{"", "0", "21601", "abc"} {
+			recorder := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodPut, imdsTokenPath, nil)
+			request.Header.Set(imdsTokenTTLHeader, ttl)
+			handler.ServeHTTP(recorder, request)
+
+			require.Equal(t, http.StatusBadRequest, recorder.Code, "ttl: %s", ttl)
+		}
+	})
+
+	t.Run("reject session token request forwarded by proxy", func(t *testing.T) {
+		handler := newIMDSHandler("my-role", stubCredentials(time.Time{}), nowFn)
+
+		recorder := httptest.NewRecorder()
+		request := httptest.NewRequest(http.MethodPut, imdsTokenPath, nil)
+		request.Header.Set(imdsTokenTTLHeader, "60")
+		request.Header.Set("X-Forwarded-For", "10.0.0.1")
+		handler.ServeHTTP(recorder, request)
+
+		require.Equal(t, http.StatusForbidden, recorder.Code)
+	})
+}
@@ -0,0 +1,70 @@
+package shell
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// Detect returns name of the shell running aws-profile, detected from parent process, then $SHELL, and defaults to
+// PowerShell on Windows and sh on other platforms
+func Detect() string {
+	return detect(parentProcessName(), os.Getenv("SHELL"), runtime.GOOS)
+}
+
+func detect(parentProcessName string, shellVariable string, goos string) string {
+	for _, candidate := range []string{parentProcessName, shellVariable} {
+		if name := normalizeShellName(candidate); name != "" {
+			if _, err := Get(name); err == nil {
+				return name
+			}
+		}
+	}
+
+	if goos == "windows" {
+		return "powershell"
+	}
+
+	return "sh"
+}
+
+// login shells are prefixed with "-", e.g. "-bash"
+func normalizeShellName(name string) string {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return ""
+	}
+
+	name = path.Base(strings.ReplaceAll(name, `\`, "/"))
+	name = strings.TrimPrefix(name, "-")
+	return strings.TrimSuffix(strings.ToLower(name), ".exe")
+}
+
+// parent process name is not detected on Windows
+func parentProcessName() string {
+	parentPid := os.Getppid()
+
+	switch runtime.GOOS {
+	case "windows":
+		return ""
+	case "linux":
+		content, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/comm", parentPid))
+		if err != nil {
+			return ""
+		}
+
+		return string(content)
+	default:
+		output, err := exec.Command("ps", "-o", "comm=", "-p", strconv.Itoa(parentPid)).Output() // #nosec
+		if err != nil {
+			return ""
+		}
+
+		return string(output)
+	}
+}
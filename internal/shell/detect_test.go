@@ -0,0 +1,33 @@
+package shell
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetect(t *testing.T) {
+	t.Run("detect shell from parent process name", func(t *testing.T) {
+		require.Equal(t, "fish", detect("fish\n", "/bin/bash", "linux"))
+	})
+
+	t.Run("detect login shell from parent process name", func(t *testing.T) {
+		require.Equal(t, "zsh", detect("-zsh", "", "darwin"))
+	})
+
+	t.Run("detect shell from windows executable path", func(t *testing.T) {
+		require.Equal(t, "cmd", detect(`C:\Windows\System32\cmd.exe`, "", "windows"))
+	})
+
+	t.Run("detect shell from SHELL environment variable if parent process is not a shell", func(t *testing.T) {
+		require.Equal(t, "tcsh", detect("sudo", "/usr/local/bin/tcsh", "linux"))
+	})
+
+	t.Run("default to sh on non-windows platforms", func(t *testing.T) {
+		require.Equal(t, "sh", detect("", "", "linux"))
+	})
+
+	t.Run("default to powershell on windows", func(t *testing.T) {
+		require.Equal(t, "powershell", detect("", "", "windows"))
+	})
+}
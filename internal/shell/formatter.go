@@ -0,0 +1,195 @@
+package shell
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type Variable struct {
+	Name  string
+	Value string
+}
+
+// Formatter formats commands to set and unset environment variables in a shell
+type Formatter interface {
+	Set(variables []Variable) string
+	Unset(names []string) string
+}
+
+var formatters = map[string]Formatter{
+	"sh":         posixFormatter{},
+	"powershell": powerShellFormatter{},
+	"fish":       fishFormatter{},
+	"nu":         nushellFormatter{},
+	"cmd":        cmdFormatter{},
+	"csh":        cshFormatter{},
+	"elvish":     elvishFormatter{},
+}
+
+// shells sharing syntax with one of supported shells
+var aliases = map[string]string{
+	"bash":    "sh",
+	"zsh":     "sh",
+	"ksh":     "sh",
+	"dash":    "sh",
+	"ash":     "sh",
+	"pwsh":    "powershell",
+	"nushell": "nu",
+	"tcsh":    "csh",
+}
+
+// Names returns names of all supported shells, including aliases
+func Names() []string {
+	var names []string
+	for name := range formatters {
+		names = append(names, name)
+	}
+
+	for alias := range aliases {
+		names = append(names, alias)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+func Get(name string) (Formatter, error) {
+	if alias, ok := aliases[name]; ok {
+		name = alias
+	}
+
+	formatter, ok := formatters[name]
+	if !ok {
+		return nil, fmt.Errorf("unsupported shell %s, supported shells: %s", name, strings.Join(Names(), ", "))
+	}
+
+	return formatter, nil
+}
+
+func formatEach(variables []Variable, format func(Variable) string) []string {
+	var formatted []string
+	for _, variable := range variables {
+		formatted = append(formatted, format(variable))
+	}
+
+	return formatted
+}
+
+func formatEachName(names []string, format func(string) string) []string {
+	var formatted []string
+	for _, name := range names {
+		formatted = append(formatted, format(name))
+	}
+
+	return formatted
+}
+
+type posixFormatter struct{}
+
+func (posixFormatter) Set(variables []Variable) string {
+	return "export " + strings.Join(formatEach(variables, func(variable Variable) string {
+		return variable.Name + "=" + quotePosix(variable.Value)
+	}), " ")
+}
+
+func (posixFormatter) Unset(names []string) string {
+	return "unset " + strings.Join(names, " ")
+}
+
+// single quoted string can't contain single quote, close the quoted string and add an escaped quote instead
+func quotePosix(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+type powerShellFormatter struct{}
+
+func (powerShellFormatter) Set(variables []Variable) string {
+	return strings.Join(formatEach(variables, func(variable Variable) string {
+		return fmt.Sprintf("$env:%s = '%s'", variable.Name, strings.ReplaceAll(variable.Value, "'", "''"))
+	}), "; ")
+}
+
+func (powerShellFormatter) Unset(names []string) string {
+	return "Remove-Item " + strings.Join(formatEachName(names, func(name string) string {
+		return `Env:\` + name
+	}), ", ")
+}
+
+type fishFormatter struct{}
+
+func (fishFormatter) Set(variables []Variable) string {
+	return strings.Join(formatEach(variables, func(variable Variable) string {
+		return fmt.Sprintf("set -gx %s %s", variable.Name, quoteWithBackslash(variable.Value, '\''))
+	}), "; ")
+}
+
+func (fishFormatter) Unset(names []string) string {
+	return strings.Join(formatEachName(names, func(name string) string {
+		return "set -e " + name
+	}), "; ")
+}
+
+type nushellFormatter struct{}
+
+func (nushellFormatter) Set(variables []Variable) string {
+	return strings.Join(formatEach(variables, func(variable Variable) string {
+		return fmt.Sprintf("$env.%s = %s", variable.Name, quoteWithBackslash(variable.Value, '"'))
+	}), "; ")
+}
+
+// ignore errors for variables that are not set
+func (nushellFormatter) Unset(names []string) string {
+	return "hide-env -i " + strings.Join(names, " ")
+}
+
+// quote value with given quote character, escaping backslash and the quote character with backslash
+func quoteWithBackslash(value string, quote rune) string {
+	escaped := strings.ReplaceAll(value, `\`, `\\`)
+	escaped = strings.ReplaceAll(escaped, string(quote), `\`+string(quote))
+	return string(quote) + escaped + string(quote)
+}
+
+type cmdFormatter struct{}
+
+// cmd takes everything between first and last quotes of set "name=value" as is, value doesn't need escaping
+func (cmdFormatter) Set(variables []Variable) string {
+	return strings.Join(formatEach(variables, func(variable Variable) string {
+		return fmt.Sprintf(`set "%s=%s"`, variable.Name, variable.Value)
+	}), " & ")
+}
+
+func (cmdFormatter) Unset(names []string) string {
+	return strings.Join(formatEachName(names, func(name string) string {
+		return fmt.Sprintf(`set "%s="`, name)
+	}), " & ")
+}
+
+type cshFormatter struct{}
+
+// csh performs history substitution of "!" even in single quoted string, it needs to be escaped outside of quotes
+func (cshFormatter) Set(variables []Variable) string {
+	return strings.Join(formatEach(variables, func(variable Variable) string {
+		return fmt.Sprintf("setenv %s %s", variable.Name, strings.ReplaceAll(quotePosix(variable.Value), "!", `'\!'`))
+	}), "; ")
+}
+
+func (cshFormatter) Unset(names []string) string {
+	return strings.Join(formatEachName(names, func(name string) string {
+		return "unsetenv " + name
+	}), "; ")
+}
+
+type elvishFormatter struct{}
+
+func (elvishFormatter) Set(variables []Variable) string {
+	return strings.Join(formatEach(variables, func(variable Variable) string {
+		return fmt.Sprintf("set-env %s '%s'", variable.Name, strings.ReplaceAll(variable.Value, "'", "''"))
+	}), "; ")
+}
+
+func (elvishFormatter) Unset(names []string) string {
+	return strings.Join(formatEachName(names, func(name string) string {
+		return "unset-env " + name
+	}), "; ")
+}
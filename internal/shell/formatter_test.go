@@ -0,0 +1,94 @@
+package shell
+
+import (
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatters(t *testing.T) {
+	variables := []Variable{
+		{Name: "AWS_ACCESS_KEY_ID", Value: "access-key-id"},
+		{Name: "AWS_SESSION_TOKEN", Value: `it's "quoted" \ and!`},
+	}
+	names := []string{"AWS_ACCESS_KEY_ID", "AWS_SESSION_TOKEN"}
+
+	testCases := []struct {
+		shell         string
+		expectedSet   string
+		expectedUnset string
+	}{
+		{
+			shell:         "sh",
+			expectedSet:   `export AWS_ACCESS_KEY_ID='access-key-id' AWS_SESSION_TOKEN='it'\''s "quoted" \ and!'`,
+			expectedUnset: "unset AWS_ACCESS_KEY_ID AWS_SESSION_TOKEN",
+		},
+		{
+			shell:         "powershell",
+			expectedSet:   `$env:AWS_ACCESS_KEY_ID = 'access-key-id'; $env:AWS_SESSION_TOKEN = 'it''s "quoted" \ and!'`,
+			expectedUnset: `Remove-Item Env:\AWS_ACCESS_KEY_ID, Env:\AWS_SESSION_TOKEN`,
+		},
+		{
+			shell:         "fish",
+			expectedSet:   `set -gx AWS_ACCESS_KEY_ID 'access-key-id'; set -gx AWS_SESSION_TOKEN 'it\'s "quoted" \\ and!'`,
+			expectedUnset: "set -e AWS_ACCESS_KEY_ID; set -e AWS_SESSION_TOKEN",
+		},
+		{
+			shell:         "nu",
+			expectedSet:   `$env.AWS_ACCESS_KEY_ID = "access-key-id"; $env.AWS_SESSION_TOKEN = "it's \"quoted\" \\ and!"`,
+			expectedUnset: "hide-env -i AWS_ACCESS_KEY_ID AWS_SESSION_TOKEN",
+		},
+		{
+			shell:         "cmd",
+			expectedSet:   `set "AWS_ACCESS_KEY_ID=access-key-id" & set "AWS_SESSION_TOKEN=it's "quoted" \ and!"`,
+			expectedUnset: `set "AWS_ACCESS_KEY_ID=" & set "AWS_SESSION_TOKEN="`,
+		},
+		{
+			shell:         "csh",
+			expectedSet:   `setenv AWS_ACCESS_KEY_ID 'access-key-id'; setenv AWS_SESSION_TOKEN 'it'\''s "quoted" \ and'\!''`,
+			expectedUnset: "unsetenv AWS_ACCESS_KEY_ID; unsetenv AWS_SESSION_TOKEN",
+		},
+		{
+			shell:         "elvish",
+			expectedSet:   `set-env AWS_ACCESS_KEY_ID 'access-key-id'; set-env AWS_SESSION_TOKEN 'it''s "quoted" \ and!'`,
+			expectedUnset: "unset-env AWS_ACCESS_KEY_ID; unset-env AWS_SESSION_TOKEN",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run("format commands for "+testCase.shell, func(t *testing.T) {
+			formatter, err := Get(testCase.shell)
+
+			require.NoError(t, err)
+			require.Equal(t, testCase.expectedSet, formatter.Set(variables))
+			require.Equal(t, testCase.expectedUnset, formatter.Unset(names))
+		})
+	}
+
+	t.Run("set values that are evaluated to the same values by sh", func(t *testing.T) {
+		if _, err := exec.LookPath("sh"); err != nil {
+			t.Skip("sh is not available")
+		}
+
+		formatter, _ := Get("sh")
+		output, err := exec.Command("sh", "-c", formatter.Set(variables)+`; printf '%s' "$AWS_SESSION_TOKEN"`).Output()
+
+		require.NoError(t, err)
+		require.Equal(t, variables[1].Value, string(output))
+	})
+
+	t.Run("return formatter of aliased shell", func(t *testing.T) {
+		formatter, err := Get("zsh")
+
+		require.NoError(t, err)
+		require.Equal(t, posixFormatter{}, formatter)
+	})
+
+	t.Run("return error for unsupported shell", func(t *testing.T) {
+		_, err := Get("rc")
+
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unsupported shell rc")
+	})
+}
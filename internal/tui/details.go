@@ -0,0 +1,51 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hpcsc/aws-profile/internal/awsconfig"
+)
+
+// profileDetails describes given profile in preview pane next to profile list
+func profileDetails(profile awsconfig.Profile, profiles awsconfig.Profiles) string {
+	lines := []string{
+		detailsLine("Role ARN", profile.RoleArn),
+		detailsLine("Account", profile.AccountId()),
+		detailsLine("Source chain", sourceChain(profile, profiles)),
+		detailsLine("MFA serial", profile.MFASerialNumber),
+		detailsLine("Region", profile.Region),
+		detailsLine("File", origin(profile)),
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func detailsLine(name string, value string) string {
+	if value == "" {
+		value = "-"
+	}
+
+	return fmt.Sprintf("%s: %s", name, value)
+}
+
+func sourceChain(profile awsconfig.Profile, profiles awsconfig.Profiles) string {
+	if profile.CredentialSource != "" {
+		return fmt.Sprintf("%s -> credential_source %s", profile.ProfileName, profile.CredentialSource)
+	}
+
+	names := profiles.SourceChainNames(&profile)
+	if len(names) < 2 {
+		return ""
+	}
+
+	return strings.Join(names, " -> ")
+}
+
+func origin(profile awsconfig.Profile) string {
+	if profile.OriginFile == "" {
+		return fmt.Sprintf("[%s]", profile.OriginSection)
+	}
+
+	return fmt.Sprintf("%s [%s]", profile.OriginFile, profile.OriginSection)
+}
@@ -0,0 +1,64 @@
+package tui
+
+import (
+	"testing"
+
+	"github.com/hpcsc/aws-profile/internal/awsconfig"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProfileDetails(t *testing.T) {
+	t.Run("describe assumed profile with its source chain and origin", func(t *testing.T) {
+		profiles := awsconfig.Profiles{
+			CredentialsProfiles: []awsconfig.Profile{
+				{ProfileName: "base"},
+			},
+			ConfigAssumedProfiles: []awsconfig.Profile{
+				{
+					ProfileName:     "profile admin",
+					RoleArn:         "arn:aws:iam::123456789012:role/admin",
+					SourceProfile:   "base",
+					MFASerialNumber: "arn:aws:iam::111111111111:mfa/user",
+					Region:          "ap-southeast-2",
+					OriginFile:      "/home/user/.aws/config",
+					OriginSection:   "profile admin",
+				},
+			},
+		}
+
+		details := profileDetails(profiles.ConfigAssumedProfiles[0], profiles)
+
+		require.Equal(t, `Role ARN: arn:aws:iam::123456789012:role/admin
+Account: 123456789012
+Source chain: profile admin -> base
+MFA serial: arn:aws:iam::111111111111:mfa/user
+Region: ap-southeast-2
+File: /home/user/.aws/config [profile admin]`, details)
+	})
+
+	t.Run("show placeholder for values profile does not have", func(t *testing.T) {
+		profile := awsconfig.Profile{
+			ProfileName:   "profile process",
+			OriginSection: "profile process",
+		}
+
+		details := profileDetails(profile, awsconfig.Profiles{})
+
+		require.Equal(t, `Role ARN: -
+Account: -
+Source chain: -
+MFA serial: -
+Region: -
+File: [profile process]`, details)
+	})
+
+	t.Run("end source chain with credential source", func(t *testing.T) {
+		profile := awsconfig.Profile{
+			ProfileName:      "profile ec2",
+			RoleArn:          "arn:aws:iam::123456789012:role/ec2",
+			CredentialSource: "Ec2InstanceMetadata",
+		}
+
+		require.Contains(t, profileDetails(profile, awsconfig.Profiles{}), "Source chain: profile ec2 -> credential_source Ec2InstanceMetadata")
+	})
+}
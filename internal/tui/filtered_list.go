@@ -0,0 +1,113 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+)
+
+// filteredList keeps state of a list narrowed by a search query, independent of how it's rendered
+type filteredList struct {
+	labels   []string
+	query    []rune
+	matches  []fuzzyMatch
+	selected int
+	// moved is set once selection is moved by user, until selected label no longer matches
+	moved bool
+}
+
+func newFilteredList(labels []string) *filteredList {
+	list := &filteredList{labels: labels}
+	list.matches = fuzzyFilter(labels, nil)
+	return list
+}
+
+func (list *filteredList) appendToQuery(r rune) {
+	list.setQuery(append(list.query, r))
+}
+
+func (list *filteredList) deleteFromQuery() {
+	if len(list.query) == 0 {
+		return
+	}
+
+	list.setQuery(list.query[:len(list.query)-1])
+}
+
+func (list *filteredList) clearQuery() {
+	list.setQuery(nil)
+}
+
+// select the best match of the new query, unless user moved selection to a label that still matches
+func (list *filteredList) setQuery(query []rune) {
+	selectedIndex, hasSelection := list.selectedIndex()
+
+	list.query = query
+	list.matches = fuzzyFilter(list.labels, query)
+	list.selected = 0
+
+	if !hasSelection || !list.moved {
+		return
+	}
+
+	for i, match := range list.matches {
+		if match.index == selectedIndex {
+			list.selected = i
+			return
+		}
+	}
+
+	list.moved = false
+}
+
+func (list *filteredList) moveUp() {
+	if list.selected > 0 {
+		list.selected--
+		list.moved = true
+	}
+}
+
+func (list *filteredList) moveDown() {
+	if list.selected < len(list.matches)-1 {
+		list.selected++
+		list.moved = true
+	}
+}
+
+// selectedIndex returns index of selected label in the original labels, false if no label matches query
+func (list *filteredList) selectedIndex() (int, bool) {
+	if len(list.matches) == 0 {
+		return -1, false
+	}
+
+	return list.matches[list.selected].index, true
+}
+
+// rows returns matching labels with matched characters styled using termui markup
+func (list *filteredList) rows(highlightStyle string) []string {
+	var rows []string
+
+	for _, match := range list.matches {
+		rows = append(rows, highlightPositions([]rune(list.labels[match.index]), match.positions, highlightStyle))
+	}
+
+	return rows
+}
+
+// characters used by termui markup are not highlighted to keep the markup valid
+func highlightPositions(label []rune, positions []int, style string) string {
+	matched := map[int]bool{}
+	for _, position := range positions {
+		matched[position] = true
+	}
+
+	var builder strings.Builder
+	for i, r := range label {
+		if matched[i] && !strings.ContainsRune("[]()", r) {
+			builder.WriteString(fmt.Sprintf("[%c](%s)", r, style))
+		} else {
+			builder.WriteRune(r)
+		}
+	}
+
+	return builder.String()
+}
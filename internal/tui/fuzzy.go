@@ -0,0 +1,161 @@
+package tui
+
+import (
+	"sort"
+	"unicode"
+)
+
+// scoring loosely follows fzf: every matched character scores, matches at word boundaries and consecutive matches
+// score more, and gaps between matched characters are penalized
+const (
+	scoreMatch          = 16
+	bonusBoundary       = 8
+	bonusCamelCase      = 7
+	bonusConsecutive    = 4
+	bonusFirstCharacter = 2
+	penaltyGapStart     = 3
+	penaltyGapExtension = 1
+)
+
+type fuzzyMatch struct {
+	index     int
+	score     int
+	positions []int
+}
+
+// fuzzyMatchText matches pattern as a subsequence of text and returns score and positions of matched runes in text.
+// Matching is case-insensitive unless pattern contains upper case characters
+func fuzzyMatchText(pattern []rune, text []rune) (int, []int, bool) {
+	if len(pattern) == 0 {
+		return 0, nil, true
+	}
+
+	caseSensitive := false
+	for _, r := range pattern {
+		if unicode.IsUpper(r) {
+			caseSensitive = true
+			break
+		}
+	}
+
+	equal := func(a rune, b rune) bool {
+		if caseSensitive {
+			return a == b
+		}
+		return unicode.ToLower(a) == unicode.ToLower(b)
+	}
+
+	// forward scan finds the earliest end of a match
+	patternIndex := 0
+	end := -1
+	for i, r := range text {
+		if equal(r, pattern[patternIndex]) {
+			patternIndex++
+			if patternIndex == len(pattern) {
+				end = i
+				break
+			}
+		}
+	}
+
+	if end < 0 {
+		return 0, nil, false
+	}
+
+	// backward scan from the end finds the shortest match ending there
+	positions := make([]int, len(pattern))
+	patternIndex = len(pattern) - 1
+	for i := end; i >= 0 && patternIndex >= 0; i-- {
+		if equal(text[i], pattern[patternIndex]) {
+			positions[patternIndex] = i
+			patternIndex--
+		}
+	}
+
+	return scorePositions(text, positions), positions, true
+}
+
+// consecutive characters get at least the bonus of the first character of their chunk,
+// so that a whole word matched from its boundary outranks scattered boundary matches
+func scorePositions(text []rune, positions []int) int {
+	score := 0
+	chunkBonus := 0
+
+	for i, position := range positions {
+		score += scoreMatch
+
+		bonus := characterBonus(text, position)
+		if i > 0 && position == positions[i-1]+1 {
+			bonus = maxInt(bonus, maxInt(chunkBonus, bonusConsecutive))
+		} else {
+			chunkBonus = bonus
+			if i > 0 {
+				gap := position - positions[i-1] - 1
+				score -= penaltyGapStart + (gap-1)*penaltyGapExtension
+			}
+		}
+
+		if i == 0 {
+			bonus *= bonusFirstCharacter
+		}
+		score += bonus
+	}
+
+	return score
+}
+
+func maxInt(a int, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func characterBonus(text []rune, position int) int {
+	if position == 0 {
+		return bonusBoundary
+	}
+
+	previous := text[position-1]
+	current := text[position]
+
+	if !unicode.IsLetter(previous) && !unicode.IsDigit(previous) {
+		return bonusBoundary
+	}
+
+	if unicode.IsLower(previous) && unicode.IsUpper(current) {
+		return bonusCamelCase
+	}
+
+	return 0
+}
+
+// fuzzyFilter returns labels matching pattern, best matches first, or all labels in their order if pattern is empty. Among labels with the same score, shorter labels
+// come first and labels with the same length keep their order
+func fuzzyFilter(labels []string, pattern []rune) []fuzzyMatch {
+	var matches []fuzzyMatch
+
+	for index, label := range labels {
+		if score, positions, ok := fuzzyMatchText(pattern, []rune(label)); ok {
+			matches = append(matches, fuzzyMatch{
+				index:     index,
+				score:     score,
+				positions: positions,
+			})
+		}
+	}
+
+	if len(pattern) == 0 {
+		return matches
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score > matches[j].score
+		}
+
+		return len(labels[matches[i].index]) < len(labels[matches[j].index])
+	})
+
+	return matches
+}
@@ -0,0 +1,150 @@
+package tui
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func matchedLabels(labels []string, matches []fuzzyMatch) []string {
+	var result []string
+	for _, match := range matches {
+		result = append(result, labels[match.index])
+	}
+	return result
+}
+
+func TestFuzzyMatchText(t *testing.T) {
+	t.Run("match characters in order with gaps", func(t *testing.T) {
+		_, positions, ok := fuzzyMatchText([]rune("pdad"), []rune("prod-admin"))
+
+		require.True(t, ok)
+		require.Equal(t, []int{0, 3, 5, 6}, positions)
+	})
+
+	t.Run("not match characters out of order", func(t *testing.T) {
+		_, _, ok := fuzzyMatchText([]rune("dp"), []rune("prod"))
+
+		require.False(t, ok)
+	})
+
+	t.Run("ignore case when pattern is lower case", func(t *testing.T) {
+		_, _, ok := fuzzyMatchText([]rune("prod"), []rune("PROD"))
+
+		require.True(t, ok)
+	})
+
+	t.Run("match case when pattern has upper case characters", func(t *testing.T) {
+		_, _, ok := fuzzyMatchText([]rune("Prod"), []rune("prod"))
+
+		require.False(t, ok)
+	})
+
+	t.Run("prefer shortest match ending at first complete match", func(t *testing.T) {
+		_, positions, ok := fuzzyMatchText([]rune("ab"), []rune("a-a-ab"))
+
+		require.True(t, ok)
+		require.Equal(t, []int{4, 5}, positions)
+	})
+}
+
+func TestFuzzyFilter(t *testing.T) {
+	t.Run("return all labels in original order when pattern is empty", func(t *testing.T) {
+		labels := []string{"b", "a", "c"}
+
+		require.Equal(t, labels, matchedLabels(labels, fuzzyFilter(labels, nil)))
+	})
+
+	t.Run("rank consecutive matches at word boundary first", func(t *testing.T) {
+		labels := []string{"profile staging-product", "profile prod", "profile p-r-o-d"}
+
+		require.Equal(t,
+			[]string{"profile prod", "profile staging-product", "profile p-r-o-d"},
+			matchedLabels(labels, fuzzyFilter(labels, []rune("prod"))))
+	})
+
+	t.Run("keep original order of labels with the same score", func(t *testing.T) {
+		labels := []string{"dev-2", "dev-1", "dev-3"}
+
+		require.Equal(t, labels, matchedLabels(labels, fuzzyFilter(labels, []rune("dev"))))
+	})
+}
+
+func TestFilteredList(t *testing.T) {
+	t.Run("narrow list when typing and widen it when deleting", func(t *testing.T) {
+		list := newFilteredList([]string{"dev", "prod", "staging"})
+
+		list.appendToQuery('p')
+		list.appendToQuery('r')
+		require.Len(t, list.matches, 1)
+
+		list.deleteFromQuery()
+		list.deleteFromQuery()
+		list.deleteFromQuery()
+		require.Len(t, list.matches, 3)
+	})
+
+	t.Run("select best match when label selected on open still matches but ranks lower", func(t *testing.T) {
+		list := newFilteredList([]string{"dev-prod-admin", "prod"})
+
+		list.appendToQuery('p')
+		list.appendToQuery('r')
+		list.appendToQuery('o')
+		list.appendToQuery('d')
+		index, ok := list.selectedIndex()
+
+		require.True(t, ok)
+		require.Equal(t, 0, list.selected)
+		require.Equal(t, 1, index)
+	})
+
+	t.Run("keep label selected by user when it still matches", func(t *testing.T) {
+		list := newFilteredList([]string{"dev", "prod-admin", "prod"})
+		list.moveDown()
+		list.moveDown()
+
+		list.appendToQuery('p')
+		index, ok := list.selectedIndex()
+
+		require.True(t, ok)
+		require.Equal(t, 2, index)
+	})
+
+	t.Run("select best match when selected label no longer matches", func(t *testing.T) {
+		list := newFilteredList([]string{"dev", "prod"})
+
+		list.appendToQuery('p')
+		index, ok := list.selectedIndex()
+
+		require.True(t, ok)
+		require.Equal(t, 1, index)
+	})
+
+	t.Run("have no selection when nothing matches", func(t *testing.T) {
+		list := newFilteredList([]string{"dev", "prod"})
+
+		list.appendToQuery('x')
+		_, ok := list.selectedIndex()
+
+		require.False(t, ok)
+	})
+
+	t.Run("not move selection beyond matches", func(t *testing.T) {
+		list := newFilteredList([]string{"dev", "prod"})
+
+		list.moveUp()
+		require.Equal(t, 0, list.selected)
+
+		list.moveDown()
+		list.moveDown()
+		require.Equal(t, 1, list.selected)
+	})
+
+	t.Run("highlight matched characters except characters used by termui markup", func(t *testing.T) {
+		list := newFilteredList([]string{"a[b]"})
+
+		list.setQuery([]rune("a]"))
+
+		require.Equal(t, []string{"[a](mod:bold)[b]"}, list.rows("mod:bold"))
+	})
+}
@@ -0,0 +1,17 @@
+package tui
+
+import "os"
+
+// IsInteractive returns true if both stdin and stdout are terminals, profile picker can't be used otherwise
+func IsInteractive() bool {
+	return isTerminal(os.Stdin) && isTerminal(os.Stdout)
+}
+
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	if err != nil {
+		return false
+	}
+
+	return info.Mode()&os.ModeCharDevice != 0
+}
@@ -5,32 +5,68 @@ import (
 	"github.com/hpcsc/aws-profile/internal/config"
 	"github.com/hpcsc/aws-profile/internal/utils"
 	"strings"
+	"unicode/utf8"
 
 	ui "github.com/gizak/termui/v3"
 	"github.com/gizak/termui/v3/widgets"
 	"github.com/hpcsc/aws-profile/internal/awsconfig"
 )
 
-func getDisplayableLabels(profiles []awsconfig.Profile) []string {
+// markers of favourite and recently used profiles, other profiles are indented to keep names aligned
+const (
+	favouriteMarker = "★ "
+	recentMarker    = "↺ "
+	otherMarker     = "  "
+)
+
+func getDisplayableLabels(profiles []awsconfig.Profile, preference awsconfig.Preference) []string {
 	var labels []string
 
 	for _, profile := range profiles {
-		labels = append(labels, profile.DisplayProfileName)
+		labels = append(labels, preferenceMarker(preference.Group(profile))+profile.DisplayProfileName)
 	}
 
 	return labels
 }
 
-func SelectProfileFromList(profiles awsconfig.Profiles, pattern string, config *config.Config) ([]byte, error) {
+func preferenceMarker(group awsconfig.PreferenceGroup) string {
+	switch group {
+	case awsconfig.FavouriteGroup:
+		return favouriteMarker
+	case awsconfig.RecentGroup:
+		return recentMarker
+	default:
+		return otherMarker
+	}
+}
+
+// NewProfileSelector returns profile picker listing favourites in config first, then profiles returned by
+// recentProfiles, then the remaining profiles alphabetically
+func NewProfileSelector(recentProfiles func() []string) func(awsconfig.Profiles, string, *config.Config) ([]byte, error) {
+	return func(profiles awsconfig.Profiles, pattern string, config *config.Config) ([]byte, error) {
+		preference := awsconfig.Preference{
+			Favourites: config.Favourites,
+			Recent:     recentProfiles(),
+		}
+
+		return selectProfileFromList(profiles, pattern, preference, config)
+	}
+}
+
+func selectProfileFromList(profiles awsconfig.Profiles, pattern string, preference awsconfig.Preference, config *config.Config) ([]byte, error) {
 	if err := ui.Init(); err != nil {
 		return nil, fmt.Errorf("failed to initialize termui: %v", err)
 	}
 	defer ui.Close()
 
-	filteredProfiles := profiles.Filter(pattern)
-	labels := getDisplayableLabels(filteredProfiles)
+	filteredProfiles := profiles.FilterByPreference(pattern, preference)
+	labels := getDisplayableLabels(filteredProfiles, preference)
 
-	selectedIndex, err := renderListSelection(labels, "Select an AWS profile", config)
+	details := func(index int) string {
+		return profileDetails(filteredProfiles[index], profiles)
+	}
+
+	selectedIndex, err := renderListSelection(labels, "Select an AWS profile", details, config)
 	if err != nil {
 		return nil, err
 	}
@@ -43,7 +79,7 @@ func SelectValueFromList(values []string, title string, config *config.Config) (
 	}
 	defer ui.Close()
 
-	selectedIndex, err := renderListSelection(values, title, config)
+	selectedIndex, err := renderListSelection(values, title, nil, config)
 	if err != nil {
 		return nil, err
 	}
@@ -71,39 +107,92 @@ func toTermUIColor(color string) ui.Color {
 	return ui.ColorGreen
 }
 
-func renderListSelection(labels []string, title string, config *config.Config) (int, error) {
+// matched characters are highlighted in rows that are not selected, selected row uses highlight color of config
+const matchedCharacterStyle = "fg:yellow,mod:bold"
+
+// details of highlighted label are shown next to the list if details function is given
+func renderListSelection(labels []string, title string, details func(int) string, config *config.Config) (int, error) {
+	filtered := newFilteredList(labels)
+
+	search := widgets.NewParagraph()
+	search.Title = "Search"
+
 	list := widgets.NewList()
 	list.Title = title
-	list.Rows = labels
 	list.SelectedRowStyle = ui.NewStyle(toTermUIColor(config.HighlightColor))
 	list.WrapText = true
 
 	grid := ui.NewGrid()
-	termWidth, termHeight := ui.TerminalDimensions()
-	grid.SetRect(0, 0, termWidth, termHeight)
+	resize := func() {
+		termWidth, termHeight := ui.TerminalDimensions()
+		grid.SetRect(0, 0, termWidth, termHeight)
+	}
+	resize()
+
+	preview := widgets.NewParagraph()
+	preview.Title = "Details"
+
+	listRow := ui.NewRow(1.0/3,
+		ui.NewCol(1.0, list),
+	)
+	if details != nil {
+		listRow = ui.NewRow(1.0/3,
+			ui.NewCol(1.0/2, list),
+			ui.NewCol(1.0/2, preview),
+		)
+	}
 
 	grid.Set(
-		ui.NewRow(1.0/3,
-			ui.NewCol(1.0, list),
+		ui.NewRow(1.0/8,
+			ui.NewCol(1.0, search),
 		),
+		listRow,
 	)
 
-	ui.Render(grid)
+	render := func() {
+		search.Text = string(filtered.query)
+		list.Rows = filtered.rows(matchedCharacterStyle)
+		list.SelectedRow = filtered.selected
+
+		preview.Text = ""
+		if selectedIndex, ok := filtered.selectedIndex(); ok && details != nil {
+			preview.Text = details(selectedIndex)
+		}
+
+		ui.Render(grid)
+	}
+	render()
 
 	uiEvents := ui.PollEvents()
 	for {
 		e := <-uiEvents
 		switch e.ID {
-		case "q", "<C-c>":
+		case "<Escape>", "<C-c>":
 			return -1, utils.NewCancelledError()
-		case "j", "<Down>":
-			list.ScrollDown()
-		case "k", "<Up>":
-			list.ScrollUp()
+		case "<Down>", "<C-j>", "<C-n>":
+			filtered.moveDown()
+		case "<Up>", "<C-k>", "<C-p>":
+			filtered.moveUp()
+		case "<Backspace>", "<C-<Backspace>>":
+			filtered.deleteFromQuery()
+		case "<C-u>":
+			filtered.clearQuery()
+		case "<Space>":
+			filtered.appendToQuery(' ')
+		case "<Resize>":
+			resize()
+			ui.Clear()
 		case "<Enter>":
-			return list.SelectedRow, nil
+			if selectedIndex, ok := filtered.selectedIndex(); ok {
+				return selectedIndex, nil
+			}
+		default:
+			if e.Type == ui.KeyboardEvent && utf8.RuneCountInString(e.ID) == 1 {
+				r, _ := utf8.DecodeRuneInString(e.ID)
+				filtered.appendToQuery(r)
+			}
 		}
 
-		ui.Render(grid)
+		render()
 	}
 }